@@ -9,7 +9,7 @@ for i in glob.glob("block_*.json"):
         tx_fetch_time_ms = data["tx_fetch_time_ms"]
         transaction_count = data["transaction_count"]
         total_evm_instructions = data.get("total_evm_instructions", data.get("total_instructions", 0))
-        estimated_transpiled_instructions = data.get("estimated_transpiled_instructions", 0)
+        executed_instructions = data.get("executed_instructions", data.get("estimated_transpiled_instructions", 0))
         transpile_time_ms = data["transpile_time_ms"]
         assembly_time_ms = data["assembly_time_ms"]
         proof_time_ms = data["proof_time_ms"]
@@ -17,7 +17,7 @@ for i in glob.glob("block_*.json"):
         proof_keygen_time_ms = data["proof_keygen_time_ms"]
         proof_setup_time_ms = data["proof_setup_time_ms"]
         proof_prove_time_ms = data["proof_prove_time_ms"]
-        proof_read_time_ms = data["proof_read_time_ms"]
+        cycle_count_time_ms = data.get("cycle_count_time_ms", 0)
         total_time_ms = data["total_time_ms"]
         print(f"File: {i}")
         print(f"block_number: {block_number}")
@@ -25,14 +25,14 @@ for i in glob.glob("block_*.json"):
         print(f"tx_fetch_time_ms: {tx_fetch_time_ms}")
         print(f"transaction_count: {transaction_count}")
         print(f"total_evm_instructions: {total_evm_instructions}")
-        print(f"estimated_transpiled_instructions: {estimated_transpiled_instructions}")
+        print(f"executed_instructions: {executed_instructions}")
         print(f"transpile_time_ms: {transpile_time_ms}")
         print(f"assembly_time_ms: {assembly_time_ms}")
+        print(f"cycle_count_time_ms: {cycle_count_time_ms}")
         print(f"proof_time_ms: {proof_time_ms}")
         print(f"  proof_build_time_ms: {proof_build_time_ms}")
         print(f"  proof_keygen_time_ms: {proof_keygen_time_ms}")
         print(f"  proof_setup_time_ms: {proof_setup_time_ms}")
         print(f"  proof_prove_time_ms: {proof_prove_time_ms}")
-        print(f"  proof_read_time_ms: {proof_read_time_ms}")
         print(f"total_time_ms: {total_time_ms}")
         print("===")
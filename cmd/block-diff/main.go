@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"erigon-transpiler-risc-v/erigonapi"
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+	"erigon-transpiler-risc-v/transpiler"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cmd/rpcdaemon/cli"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/rpc/ethapi"
+	"github.com/erigontech/erigon/rpc/jsonrpc"
+	"github.com/erigontech/erigon/turbo/debug"
+	"github.com/holiman/uint256"
+	"github.com/spf13/cobra"
+)
+
+// Number of stack entries around the diverging slot that are printed
+const stackContext = 4
+
+func main() {
+	cmd, cfg := cli.RootCommand()
+	rootCtx, rootCancel := common.RootContext()
+
+	var blockNumber string
+	var maxTxs int
+	var keepGoing bool
+	cmd.Flags().StringVar(&blockNumber, "block-number", "", "Block number to check (required)")
+	cmd.Flags().IntVar(&maxTxs, "max-txs", 0, "Limit to first N transactions (0 = all transactions)")
+	cmd.Flags().BoolVar(&keepGoing, "keep-going", false, "Check the remaining transactions after the first divergence")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if blockNumber == "" {
+			return fmt.Errorf("block-number is required")
+		}
+		blockNum, err := strconv.ParseUint(blockNumber, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid block number: %v", err)
+		}
+
+		ctx := cmd.Context()
+		logger := debug.SetupCobra(cmd, "rpcdaemon")
+		logger.Enabled(ctx, log.LvlCrit)
+		db, backend, txPool, mining, stateCache, blockReader, engine, ff, bridgeReader, heimdallReader, err := cli.RemoteServices(ctx, cfg, logger, rootCancel)
+		if err != nil {
+			logger.Error("Could not connect to DB", "err", err)
+			return nil
+		}
+		defer db.Close()
+		defer engine.Close()
+		if bridgeReader != nil {
+			defer bridgeReader.Close()
+		}
+		if heimdallReader != nil {
+			defer heimdallReader.Close()
+		}
+
+		apiList := jsonrpc.APIList(db, backend, txPool, mining, ff, stateCache, blockReader, cfg, engine, logger, bridgeReader, heimdallReader)
+		debugAPI := erigonapi.FindDebug(apiList)
+		ethApi := erigonapi.FindEth(apiList)
+
+		blockData, err := ethApi.GetBlockByNumber(ctx, rpc.BlockNumber(blockNum), true)
+		if err != nil {
+			return fmt.Errorf("failed to get block: %v", err)
+		}
+		if blockData == nil {
+			return fmt.Errorf("block not found")
+		}
+		txs, ok := blockData["transactions"].([]interface{})
+		if !ok {
+			return fmt.Errorf("transactions field is not an array")
+		}
+
+		runner, err := prover.NewRunner()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Checking block %d with %d transactions\n", blockNum, len(txs))
+		diverged := 0
+		for i, txInterface := range txs {
+			if maxTxs > 0 && i >= maxTxs {
+				break
+			}
+			tx, ok := txInterface.(*ethapi.RPCTransaction)
+			if !ok {
+				fmt.Printf("Skipping invalid transaction %d (type: %T)\n", i+1, txInterface)
+				continue
+			}
+
+			matched, err := diffTransaction(ctx, debugAPI, runner, i, tx.Hash)
+			if err != nil {
+				return fmt.Errorf("transaction %d (%s): %v", i+1, tx.Hash.String(), err)
+			}
+			if !matched {
+				diverged++
+				if !keepGoing {
+					break
+				}
+			}
+		}
+
+		if diverged > 0 {
+			return fmt.Errorf("%d transaction(s) diverged", diverged)
+		}
+		fmt.Printf("All transactions match\n")
+		return nil
+	}
+
+	if err := cmd.ExecuteContext(rootCtx); err != nil {
+		fmt.Printf("ExecuteContext: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// diffTransaction transpiles a single transaction with snapshots and compares the EVM and RISC-V stacks.
+func diffTransaction(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, runner prover.Runner, txIndex int, txHash common.Hash) (bool, error) {
+	instructions, state, err := erigonapi.TraceTransaction(ctx, debugAPI, txHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to trace: %v", err)
+	}
+
+	// Host-optimized opcodes are enabled, the dummy zeros would diverge on the first ADD
+	txTranspiler := transpiler.NewTranspilerWithConfig(transpiler.TranspilerConfig{
+		DisableDebugMappings: true,
+	})
+	txTranspiler.EnableSnapshots()
+	evmSnapshot, err := txTranspiler.ProcessExecution(instructions, state)
+	if err != nil {
+		return false, fmt.Errorf("failed to transpile: %v", err)
+	}
+
+	bytecode, err := txTranspiler.ToAssembly().ToBytecode()
+	if err != nil {
+		fmt.Printf("Transaction %d (%s): failed to assemble: %v\n", txIndex+1, txHash.String(), err)
+		return false, nil
+	}
+
+	result, err := runner.Execute(bytecode)
+	if err != nil {
+		fmt.Printf("Transaction %d (%s): RISC-V execution failed: %v\n", txIndex+1, txHash.String(), err)
+		return false, nil
+	}
+
+	divergence := transpiler.FirstStackDivergence(evmSnapshot.Snapshots, *result.StackSnapshots)
+	if divergence == nil {
+		fmt.Printf("Transaction %d (%s): %d snapshots match\n", txIndex+1, txHash.String(), len(evmSnapshot.Snapshots))
+		return true, nil
+	}
+
+	printDivergence(txIndex, txHash, instructions, divergence)
+	return false, nil
+}
+
+func printDivergence(txIndex int, txHash common.Hash, instructions []*tracer.EvmInstructionMetadata, divergence *transpiler.StackDivergence) {
+	fmt.Printf("Transaction %d (%s) diverged\n", txIndex+1, txHash.String())
+	if divergence.Snapshot < len(instructions) {
+		instruction := instructions[divergence.Snapshot]
+		fmt.Printf("  after EVM instruction %d: %s (%s pc %d)\n", divergence.Snapshot, instructionName(instruction), instruction.Address.Hex(), instruction.Pc)
+	}
+	if divergence.Slot < 0 {
+		fmt.Printf("  snapshot %d is missing (EVM has %d entries, RISC-V has %d)\n",
+			divergence.Snapshot, len(divergence.Expected), len(divergence.Actual))
+		return
+	}
+	fmt.Printf("  stack slot %d from the top (EVM depth %d, RISC-V depth %d)\n",
+		divergence.Slot, len(divergence.Expected), len(divergence.Actual))
+	for slot := max(0, divergence.Slot-stackContext); slot <= divergence.Slot+stackContext; slot++ {
+		marker := " "
+		if slot == divergence.Slot {
+			marker = ">"
+		}
+		fmt.Printf("  %s [%d] evm=%s riscv=%s\n", marker, slot, stackSlot(divergence.Expected, slot), stackSlot(divergence.Actual, slot))
+	}
+}
+
+func stackSlot(stack []uint256.Int, slot int) string {
+	if slot >= len(stack) {
+		return "-"
+	}
+	return stack[len(stack)-1-slot].Hex()
+}
+
+func instructionName(instruction *tracer.EvmInstructionMetadata) string {
+	if instruction.IsStackRestore {
+		return "STACK_RESTORE"
+	}
+	return instruction.Opcode.String()
+}
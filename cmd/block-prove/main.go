@@ -1,25 +1,24 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"erigon-transpiler-risc-v/erigonapi"
 	"erigon-transpiler-risc-v/prover"
 	"erigon-transpiler-risc-v/tracer"
 	"erigon-transpiler-risc-v/transpiler"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/erigontech/erigon-lib/common"
-	"github.com/erigontech/erigon-lib/jsonstream"
 	"github.com/erigontech/erigon-lib/log/v3"
 	"github.com/erigontech/erigon/cmd/rpcdaemon/cli"
-	"github.com/erigontech/erigon/eth/tracers"
-	"github.com/erigontech/erigon/eth/tracers/config"
 	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/rpc/ethapi"
 	"github.com/erigontech/erigon/rpc/jsonrpc"
@@ -33,6 +32,14 @@ type ProofResult struct {
 	InstructionCount int    `json:"instruction_count"`
 	AppVK            string `json:"app_vk"`
 	Proof            string `json:"proof"`
+	// Only set with --per-tx, where every transaction is its own program
+	ExecutedInstructions uint64                  `json:"executed_instructions,omitempty"`
+	TranspileTimeMs      int64                   `json:"transpile_time_ms,omitempty"`
+	CycleCountTimeMs     int64                   `json:"cycle_count_time_ms,omitempty"`
+	ProofTimeMs          int64                   `json:"proof_time_ms,omitempty"`
+	Cycles               *prover.CycleReport     `json:"cycles,omitempty"`
+	Soundness            *prover.SoundnessReport `json:"soundness,omitempty"`
+	Error                string                  `json:"error,omitempty"`
 }
 
 func main() {
@@ -46,6 +53,15 @@ func main() {
 	var skipProof bool
 	var maxTxs int
 	var useStarkProof bool
+	var backendName string
+	var dumpTrace string
+	var witnessFile string
+	var blockFile string
+	var chainID uint64
+	var rpcURL string
+	var perTx bool
+	var workers int
+	var aggregate bool
 	cmd.Flags().StringVar(&blockNumber, "block-number", "", "Block number to trace all transactions (required)")
 	cmd.Flags().BoolVar(&debugAssembly, "debug-assembly", false, "Write transpiled assembly to disk for debugging")
 	cmd.Flags().StringVar(&assemblyFile, "assembly-file", "transpiled_block.s", "Assembly output file path (used with --debug-assembly)")
@@ -53,13 +69,56 @@ func main() {
 	cmd.Flags().BoolVar(&skipProof, "skip-proof", false, "Skip ZK proof generation to save memory")
 	cmd.Flags().IntVar(&maxTxs, "max-txs", 0, "Limit to first N transactions (0 = all transactions, useful for binary search debugging)")
 	cmd.Flags().BoolVar(&useStarkProof, "stark-proof", false, "Use STARK proof instead of app proof")
+	cmd.Flags().StringVar(&backendName, "backend", "openvm", "zkVM backend to prove with ("+strings.Join(prover.BackendNames(), ", ")+")")
+	cmd.Flags().StringVar(&dumpTrace, "dump-trace", "", "Write the EVM traces of the block to this file for trace-replay (JSON when it ends in .json)")
+	cmd.Flags().StringVar(&witnessFile, "witness", "", "debug_executionWitness file to execute the block on instead of the database (stateless mode)")
+	cmd.Flags().StringVar(&blockFile, "block-file", "", "eth_getBlockByNumber file with full transactions (required with --witness)")
+	cmd.Flags().Uint64Var(&chainID, "chain-id", 1, "Chain ID of the block, mainnet or sepolia (used with --witness)")
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Trace with debug_traceTransaction on this http(s):// or ws(s):// JSON-RPC node instead of the Erigon database")
+	cmd.Flags().BoolVar(&perTx, "per-tx", false, "Transpile and prove every transaction as its own program instead of the block as one")
+	cmd.Flags().IntVar(&workers, "workers", 2, "Transactions proven concurrently (used with --per-tx)")
+	cmd.Flags().BoolVar(&aggregate, "aggregate", false, "Aggregate the transaction STARK proofs into one block proof (needs --per-tx --stark-proof and OpenVM)")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		if blockNumber == "" {
+		if blockNumber == "" && witnessFile == "" {
 			return fmt.Errorf("block-number is required")
 		}
+		zkBackend, err := prover.NewBackend(backendName)
+		if err != nil {
+			return err
+		}
+		if workers < 1 {
+			return fmt.Errorf("workers must be at least 1")
+		}
+		if aggregate && (!perTx || !useStarkProof || skipProof || zkBackend.Name() != prover.NewOpenVMBackend().Name()) {
+			return fmt.Errorf("--aggregate needs --per-tx and --stark-proof on the openvm backend")
+		}
+		opts := blockOptions{
+			debugAssembly: debugAssembly,
+			assemblyFile:  assemblyFile,
+			debugMode:     debugMode,
+			skipProof:     skipProof,
+			maxTxs:        maxTxs,
+			useStarkProof: useStarkProof,
+			zkBackend:     zkBackend,
+			dumpTrace:     dumpTrace,
+			perTx:         perTx,
+			workers:       workers,
+			aggregate:     aggregate,
+		}
 
 		ctx := cmd.Context()
+		if witnessFile != "" {
+			return processWitnessBlock(ctx, witnessFile, blockFile, chainID, opts)
+		}
+		if rpcURL != "" {
+			blockNum, err := strconv.ParseUint(blockNumber, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid block number: %v", err)
+			}
+			return processRPCBlock(ctx, rpcURL, blockNum, opts)
+		}
+
 		logger := debug.SetupCobra(cmd, "rpcdaemon")
 		logger.Enabled(ctx, log.LvlCrit)
 		db, backend, txPool, mining, stateCache, blockReader, engine, ff, bridgeReader, heimdallReader, err := cli.RemoteServices(ctx, cfg, logger, rootCancel)
@@ -77,8 +136,8 @@ func main() {
 		}
 
 		apiList := jsonrpc.APIList(db, backend, txPool, mining, ff, stateCache, blockReader, cfg, engine, logger, bridgeReader, heimdallReader)
-		debugAPI := findDebug(apiList)
-		ethApi := findEth(apiList)
+		debugAPI := erigonapi.FindDebug(apiList)
+		ethApi := erigonapi.FindEth(apiList)
 
 		blockNum, err := strconv.ParseUint(blockNumber, 10, 64)
 		if err != nil {
@@ -109,7 +168,7 @@ func main() {
 
 		fmt.Printf("Tracing block %d with %d transactions\n", blockNum, len(txs))
 
-		return processBlockAsUnit(ctx, debugAPI, blockNum, txs, debugAssembly, assemblyFile, debugMode, skipProof, maxTxs, blockFetchTime, useStarkProof)
+		return processBlockAsUnit(ctx, debugAPI, blockNum, txs, blockFetchTime, opts)
 	}
 
 	if err := cmd.ExecuteContext(rootCtx); err != nil {
@@ -118,66 +177,31 @@ func main() {
 	}
 }
 
-func findEth(apiList []rpc.API) jsonrpc.EthAPI {
-	for _, api := range apiList {
-		if api.Namespace == "eth" {
-			if ethAPI, ok := api.Service.(jsonrpc.EthAPI); ok {
-				return ethAPI
-			}
-		}
-	}
-	return nil
-}
-
-func findDebug(apiList []rpc.API) *jsonrpc.DebugAPIImpl {
-	for _, api := range apiList {
-		if api.Namespace == "debug" {
-			if ethAPI, ok := api.Service.(*jsonrpc.DebugAPIImpl); ok {
-				return ethAPI
-			}
-		}
-	}
-	return nil
+// TraceResult is the trace of one transaction of the block, Error is set when it could not be traced.
+type TraceResult struct {
+	Index        int
+	TxIndex      int
+	TxHash       common.Hash
+	Instructions []*tracer.EvmInstructionMetadata
+	State        *tracer.EvmExecutionState
+	Error        error
 }
 
-func traceTransaction(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, txHash common.Hash) ([]*tracer.EvmInstructionMetadata, *tracer.EvmExecutionState, error) {
-	var tracerResult *tracer.StateTracer
-
-	customTracer := tracer.NewTracerHooks(
-		func(newTracer *tracer.StateTracer) (*prover.ResultsFile, error) {
-			tracerResult = newTracer
-			return &prover.ResultsFile{}, nil
-		},
-	)
-	tracers.RegisterLookup(false, customTracer)
-
-	var buf bytes.Buffer
-	stream := jsonstream.New(&buf)
-
-	tracerName := "Mine"
-	timeout := "10m"
-	err := debugAPI.TraceTransaction(
-		ctx,
-		txHash,
-		&config.TraceConfig{
-			Tracer:  &tracerName,
-			Timeout: &timeout,
-		},
-		stream,
-	)
-
-	if err != nil {
-		return nil, nil, err
-	}
-
-	if tracerResult == nil {
-		return nil, nil, fmt.Errorf("no tracer result received")
-	}
-
-	return tracerResult.GetInstructions(), tracerResult.GetExecutionState(), nil
+type blockOptions struct {
+	debugAssembly bool
+	assemblyFile  string
+	debugMode     bool
+	skipProof     bool
+	maxTxs        int
+	useStarkProof bool
+	zkBackend     prover.Backend
+	dumpTrace     string
+	perTx         bool
+	workers       int
+	aggregate     bool
 }
 
-func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blockNum uint64, txs []interface{}, debugAssembly bool, assemblyFile string, debugMode bool, skipProof bool, maxTxs int, blockFetchTime time.Duration, useStarkProof bool) error {
+func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blockNum uint64, txs []interface{}, blockFetchTime time.Duration, opts blockOptions) error {
 	fmt.Printf("Processing block %d with %d transactions using parallel tracing...\n", blockNum, len(txs))
 
 	type TraceJob struct {
@@ -187,19 +211,10 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 		TxObject *ethapi.RPCTransaction
 	}
 
-	type TraceResult struct {
-		Index        int
-		TxIndex      int
-		TxHash       common.Hash
-		Instructions []*tracer.EvmInstructionMetadata
-		State        *tracer.EvmExecutionState
-		Error        error
-	}
-
 	var jobs []TraceJob
 	for i, txInterface := range txs {
-		if maxTxs > 0 && i >= maxTxs {
-			fmt.Printf("Limiting to first %d transactions for debugging\n", maxTxs)
+		if opts.maxTxs > 0 && i >= opts.maxTxs {
+			fmt.Printf("Limiting to first %d transactions for debugging\n", opts.maxTxs)
 			break
 		}
 
@@ -235,7 +250,7 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 
 			fmt.Printf("Tracing transaction %d/%d: %s\n", j.TxIndex+1, len(txs), j.TxHash.String())
 
-			instructions, state, err := traceTransaction(ctx, debugAPI, j.TxHash)
+			instructions, state, err := erigonapi.TraceTransaction(ctx, debugAPI, j.TxHash)
 
 			results[j.Index] = TraceResult{
 				Index:        j.Index,
@@ -260,9 +275,108 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 	txFetchTime := time.Since(txFetchStart)
 	fmt.Printf("All transactions traced successfully in %v\n", txFetchTime)
 
+	return proveTracedBlock(ctx, blockNum, len(txs), results, blockFetchTime, txFetchTime, opts)
+}
+
+// processWitnessBlock re-executes the block on the state of its execution witness, without a database or node.
+func processWitnessBlock(ctx context.Context, witnessFile string, blockFile string, chainID uint64, opts blockOptions) error {
+	if blockFile == "" {
+		return fmt.Errorf("--block-file is required with --witness")
+	}
+
+	chainConfig, err := tracer.ChainConfig(chainID)
+	if err != nil {
+		return err
+	}
+
+	loadStart := time.Now()
+	block, err := tracer.LoadWitnessBlock(blockFile)
+	if err != nil {
+		return fmt.Errorf("failed to load block: %v", err)
+	}
+	witness, err := tracer.LoadExecutionWitness(witnessFile)
+	if err != nil {
+		return fmt.Errorf("failed to load witness: %v", err)
+	}
+	blockFetchTime := time.Since(loadStart)
+	blockNum := block.Header.Number.Uint64()
+	fmt.Printf("Loaded block %d with %d transactions and a witness with %d trie nodes in %v\n",
+		blockNum, len(block.Transactions), len(witness.Nodes), blockFetchTime)
+
+	traceStart := time.Now()
+	traced, err := tracer.TraceWitnessBlock(block, witness, chainConfig, opts.maxTxs)
+	if err != nil {
+		return fmt.Errorf("failed to execute block: %v", err)
+	}
+	txFetchTime := time.Since(traceStart)
+	fmt.Printf("Executed and traced %d transactions in %v\n", len(traced), txFetchTime)
+
+	results := make([]TraceResult, len(traced))
+	for i, transaction := range traced {
+		results[i] = TraceResult{
+			Index:        i,
+			TxIndex:      i,
+			TxHash:       block.Transactions[i].Hash(),
+			Instructions: transaction.Instructions,
+			State:        transaction.State,
+		}
+	}
+	return proveTracedBlock(ctx, blockNum, len(block.Transactions), results, blockFetchTime, txFetchTime, opts)
+}
+
+// processRPCBlock traces the transactions of the block on a JSON-RPC node, no Erigon database is needed.
+func processRPCBlock(ctx context.Context, rpcURL string, blockNum uint64, opts blockOptions) error {
+	client, err := tracer.DialRPC(ctx, rpcURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	rpcTracer := tracer.NewRPCTracer(client)
+
+	blockFetchStart := time.Now()
+	txHashes, err := rpcTracer.BlockTransactions(ctx, blockNum)
+	if err != nil {
+		return fmt.Errorf("failed to get block: %v", err)
+	}
+	blockFetchTime := time.Since(blockFetchStart)
+	fmt.Printf("Block %d with %d transactions fetched in %v\n", blockNum, len(txHashes), blockFetchTime)
+
+	txFetchStart := time.Now()
+	var results []TraceResult
+	for i, txHash := range txHashes {
+		if opts.maxTxs > 0 && i >= opts.maxTxs {
+			fmt.Printf("Limiting to first %d transactions for debugging\n", opts.maxTxs)
+			break
+		}
+		fmt.Printf("Tracing transaction %d/%d: %s\n", i+1, len(txHashes), txHash.String())
+		instructions, state, err := rpcTracer.TraceTransaction(ctx, txHash)
+		if err != nil {
+			fmt.Printf("Failed to trace transaction %d: %v\n", i+1, err)
+		}
+		results = append(results, TraceResult{
+			Index:        len(results),
+			TxIndex:      i,
+			TxHash:       txHash,
+			Instructions: instructions,
+			State:        state,
+			Error:        err,
+		})
+	}
+	txFetchTime := time.Since(txFetchStart)
+	fmt.Printf("All transactions traced in %v\n", txFetchTime)
+
+	return proveTracedBlock(ctx, blockNum, len(txHashes), results, blockFetchTime, txFetchTime, opts)
+}
+
+// proveTracedBlock transpiles the traced transactions into one program, proves it and writes block_<number>.json.
+func proveTracedBlock(ctx context.Context, blockNum uint64, txCount int, results []TraceResult, blockFetchTime, txFetchTime time.Duration, opts blockOptions) error {
+	if opts.perTx {
+		return proveTransactions(ctx, blockNum, txCount, results, blockFetchTime, txFetchTime, opts)
+	}
 	fmt.Printf("Processing all %d traced transactions...\n", len(results))
 	blockTranspiler := transpiler.NewTranspiler()
 	var allTxResults []ProofResult
+	var traced []tracer.TracedTransaction
 
 	transpileStart := time.Now()
 
@@ -273,7 +387,7 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 		}
 
 		fmt.Printf("Transpiling transaction %d/%d with %d instructions\n",
-			result.TxIndex+1, len(txs), len(result.Instructions))
+			result.TxIndex+1, txCount, len(result.Instructions))
 
 		_, err := blockTranspiler.ProcessExecution(result.Instructions, result.State)
 		if err != nil {
@@ -283,6 +397,7 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 		if i < len(results)-1 {
 			blockTranspiler.AddTransactionBoundary()
 		}
+		traced = append(traced, tracer.TracedTransaction{Instructions: result.Instructions, State: result.State})
 
 		allTxResults = append(allTxResults, ProofResult{
 			TransactionHash:  result.TxHash.String(),
@@ -294,32 +409,50 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 	transpileTime := time.Since(transpileStart)
 	fmt.Printf("Transpilation completed in %v\n", transpileTime)
 
+	if opts.dumpTrace != "" {
+		if err := tracer.WriteTraceFile(opts.dumpTrace, traced); err != nil {
+			return fmt.Errorf("failed to write trace to %s: %v", opts.dumpTrace, err)
+		}
+		fmt.Printf("Block trace written to: %s\n", opts.dumpTrace)
+	}
+	soundness := blockTranspiler.SoundnessReport()
+	fmt.Printf("Constrained opcodes: %.2f%%\n", soundness.ConstrainedPercentage)
+
 	fmt.Printf("Generating assembly for block...\n")
 	assemblyStart := time.Now()
-	assembly := blockTranspiler.ToAssembly()
-	content, err := assembly.ToToolChainCompatibleAssembly()
+	zkVm, err := prover.NewZkProverFromAssembly(blockTranspiler.ToAssembly(), opts.zkBackend)
 	assemblyTime := time.Since(assemblyStart)
 	fmt.Printf("Assembly generation completed in %v\n", assemblyTime)
 
 	if err != nil {
 		return fmt.Errorf("failed to generate assembly for block: %v", err)
 	}
+	content := zkVm.Content()
 
-	if debugAssembly {
-		err := os.WriteFile(assemblyFile, []byte(content), 0644)
+	if opts.debugAssembly {
+		err := os.WriteFile(opts.assemblyFile, []byte(content), 0644)
 		if err != nil {
-			fmt.Printf("Error writing assembly file %s: %v\n", assemblyFile, err)
+			fmt.Printf("Error writing assembly file %s: %v\n", opts.assemblyFile, err)
 		} else {
-			fmt.Printf("Block assembly written to: %s\n", assemblyFile)
+			fmt.Printf("Block assembly written to: %s\n", opts.assemblyFile)
 		}
 	}
 
+	fmt.Printf("Counting executed instructions...\n")
+	cycleCountStart := time.Now()
+	cycles, err := zkVm.CountCycles()
+	if err != nil {
+		return fmt.Errorf("failed to count executed instructions for block: %v", err)
+	}
+	cycleCountTime := time.Since(cycleCountStart)
+	fmt.Printf("Executed %d instructions, counted in %v\n", cycles.Total, cycleCountTime)
+
 	var output prover.ProofGeneration
 	var proveTime time.Duration
-	if skipProof {
+	if opts.skipProof {
 		fmt.Printf("Skipping ZK proof generation (--skip-proof enabled)\n")
 
-		if debugMode {
+		if opts.debugMode {
 			debugFile := fmt.Sprintf("debug_mappings_block_%d.json", blockNum)
 			if saveErr := blockTranspiler.SaveDebugMappings(debugFile); saveErr != nil {
 				fmt.Printf("Failed to save debug mappings: %v\n", saveErr)
@@ -328,11 +461,10 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 			}
 		}
 	} else {
-		fmt.Printf("Starting ZK proof generation for combined block...\n")
+		fmt.Printf("Starting ZK proof generation for combined block on %s...\n", opts.zkBackend.Name())
 		proveStart := time.Now()
-		zkVm := prover.NewZkProver(content)
 		var err error
-		if useStarkProof {
+		if opts.useStarkProof {
 			fmt.Printf("Using STARK proof...\n")
 			output, err = zkVm.StarkProve(ctx)
 		} else {
@@ -350,7 +482,7 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 				fmt.Printf("Debug mappings saved to: %s\n", debugFile)
 			}
 
-			if !debugMode {
+			if !opts.debugMode {
 				fmt.Printf("Re-run with --debug-mode for detailed transpilation analysis\n")
 			}
 
@@ -358,52 +490,57 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 		}
 
 		fmt.Printf("ZK proof generation completed in %v\n", proveTime)
-		fmt.Printf("  - Build: %v\n", time.Duration(output.Timing.BuildTimeMs)*time.Millisecond)
-		fmt.Printf("  - Keygen: %v\n", time.Duration(output.Timing.KeygenTimeMs)*time.Millisecond)
+		fmt.Printf("  - Build: %v%s\n", time.Duration(output.Timing.BuildTimeMs)*time.Millisecond, cachedSuffix(output.Timing.BuildCached))
+		fmt.Printf("  - Keygen: %v%s\n", time.Duration(output.Timing.KeygenTimeMs)*time.Millisecond, cachedSuffix(output.Timing.KeygenCached))
 		fmt.Printf("  - Setup total: %v\n", time.Duration(output.Timing.SetupTimeMs)*time.Millisecond)
 		fmt.Printf("  - Prove command: %v\n", time.Duration(output.Timing.ProveTimeMs)*time.Millisecond)
-		fmt.Printf("  - Read proof files: %v\n", time.Duration(output.Timing.ReadTimeMs)*time.Millisecond)
 	}
 
 	blockResult := struct {
-		BlockNumber                     uint64        `json:"block_number"`
-		TransactionCount                int           `json:"transaction_count"`
-		Transactions                    []ProofResult `json:"transactions"`
-		TotalEvmInstructions            int           `json:"total_evm_instructions"`
-		EstimatedTranspiledInstructions int64         `json:"estimated_transpiled_instructions"`
-		BlockFetchTimeMs                int64         `json:"block_fetch_time_ms"`
-		TxFetchTimeMs                   int64         `json:"tx_fetch_time_ms"`
-		TranspileTimeMs                 int64         `json:"transpile_time_ms"`
-		AssemblyTimeMs                  int64         `json:"assembly_time_ms"`
-		ProofTimeMs                     int64         `json:"proof_time_ms"`
-		ProofBuildTimeMs                int64         `json:"proof_build_time_ms"`
-		ProofKeygenTimeMs               int64         `json:"proof_keygen_time_ms"`
-		ProofSetupTimeMs                int64         `json:"proof_setup_time_ms"`
-		ProofProveTimeMs                int64         `json:"proof_prove_time_ms"`
-		ProofReadTimeMs                 int64         `json:"proof_read_time_ms"`
-		TotalTimeMs                     int64         `json:"total_time_ms"`
-		AppVK                           string        `json:"app_vk"`
-		Proof                           string        `json:"proof"`
-		Timestamp                       string        `json:"timestamp"`
+		BlockNumber          uint64                  `json:"block_number"`
+		Backend              string                  `json:"backend"`
+		TransactionCount     int                     `json:"transaction_count"`
+		Transactions         []ProofResult           `json:"transactions"`
+		TotalEvmInstructions int                     `json:"total_evm_instructions"`
+		ExecutedInstructions uint64                  `json:"executed_instructions"`
+		Cycles               *prover.CycleReport     `json:"cycles"`
+		Soundness            *prover.SoundnessReport `json:"soundness"`
+		BlockFetchTimeMs     int64                   `json:"block_fetch_time_ms"`
+		TxFetchTimeMs        int64                   `json:"tx_fetch_time_ms"`
+		TranspileTimeMs      int64                   `json:"transpile_time_ms"`
+		AssemblyTimeMs       int64                   `json:"assembly_time_ms"`
+		ProofTimeMs          int64                   `json:"proof_time_ms"`
+		ProofBuildTimeMs     int64                   `json:"proof_build_time_ms"`
+		ProofKeygenTimeMs    int64                   `json:"proof_keygen_time_ms"`
+		ProofSetupTimeMs     int64                   `json:"proof_setup_time_ms"`
+		ProofProveTimeMs     int64                   `json:"proof_prove_time_ms"`
+		CycleCountTimeMs     int64                   `json:"cycle_count_time_ms"`
+		TotalTimeMs          int64                   `json:"total_time_ms"`
+		AppVK                string                  `json:"app_vk"`
+		Proof                string                  `json:"proof"`
+		Timestamp            string                  `json:"timestamp"`
 	}{
-		BlockNumber:                     blockNum,
-		TransactionCount:                len(allTxResults),
-		Transactions:                    allTxResults,
-		EstimatedTranspiledInstructions: output.EstimatedInstructions,
-		AppVK:                           hex.EncodeToString(output.AppVK),
-		Proof:                           hex.EncodeToString(output.Proof),
-		BlockFetchTimeMs:                blockFetchTime.Milliseconds(),
-		TxFetchTimeMs:                   txFetchTime.Milliseconds(),
-		TranspileTimeMs:                 transpileTime.Milliseconds(),
-		AssemblyTimeMs:                  assemblyTime.Milliseconds(),
-		ProofTimeMs:                     proveTime.Milliseconds(),
-		ProofBuildTimeMs:                output.Timing.BuildTimeMs,
-		ProofKeygenTimeMs:               output.Timing.KeygenTimeMs,
-		ProofSetupTimeMs:                output.Timing.SetupTimeMs,
-		ProofProveTimeMs:                output.Timing.ProveTimeMs,
-		ProofReadTimeMs:                 output.Timing.ReadTimeMs,
-		TotalTimeMs:                     (blockFetchTime + txFetchTime + transpileTime + assemblyTime + proveTime).Milliseconds(),
-		Timestamp:                       time.Now().UTC().Format(time.RFC3339),
+		BlockNumber:          blockNum,
+		Backend:              opts.zkBackend.Name(),
+		TransactionCount:     len(allTxResults),
+		Transactions:         allTxResults,
+		ExecutedInstructions: cycles.Total,
+		Cycles:               cycles,
+		Soundness:            soundness,
+		AppVK:                hex.EncodeToString(output.AppVK),
+		Proof:                hex.EncodeToString(output.Proof),
+		BlockFetchTimeMs:     blockFetchTime.Milliseconds(),
+		TxFetchTimeMs:        txFetchTime.Milliseconds(),
+		TranspileTimeMs:      transpileTime.Milliseconds(),
+		AssemblyTimeMs:       assemblyTime.Milliseconds(),
+		ProofTimeMs:          proveTime.Milliseconds(),
+		ProofBuildTimeMs:     output.Timing.BuildTimeMs,
+		ProofKeygenTimeMs:    output.Timing.KeygenTimeMs,
+		ProofSetupTimeMs:     output.Timing.SetupTimeMs,
+		ProofProveTimeMs:     output.Timing.ProveTimeMs,
+		CycleCountTimeMs:     cycleCountTime.Milliseconds(),
+		TotalTimeMs:          (blockFetchTime + txFetchTime + transpileTime + assemblyTime + cycleCountTime + proveTime).Milliseconds(),
+		Timestamp:            time.Now().UTC().Format(time.RFC3339),
 	}
 
 	for _, txResult := range allTxResults {
@@ -428,3 +565,239 @@ func processBlockAsUnit(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, blo
 
 	return nil
 }
+
+func cachedSuffix(cached bool) string {
+	if cached {
+		return " (cached)"
+	}
+	return ""
+}
+
+// proveTransactions transpiles every traced transaction into its own program and proves them with opts.workers
+// concurrent provers. A failed transaction is reported in its result instead of failing the block.
+func proveTransactions(ctx context.Context, blockNum uint64, txCount int, results []TraceResult, blockFetchTime, txFetchTime time.Duration, opts blockOptions) error {
+	fmt.Printf("Proving %d traced transactions separately (max %d concurrent)...\n", len(results), opts.workers)
+
+	if opts.dumpTrace != "" {
+		var traced []tracer.TracedTransaction
+		for _, result := range results {
+			if result.Error == nil {
+				traced = append(traced, tracer.TracedTransaction{Instructions: result.Instructions, State: result.State})
+			}
+		}
+		if err := tracer.WriteTraceFile(opts.dumpTrace, traced); err != nil {
+			return fmt.Errorf("failed to write trace to %s: %v", opts.dumpTrace, err)
+		}
+		fmt.Printf("Block trace written to: %s\n", opts.dumpTrace)
+	}
+
+	semaphore := make(chan struct{}, opts.workers)
+	txResults := make([]ProofResult, len(results))
+	var wg sync.WaitGroup
+
+	proveStart := time.Now()
+	for i, result := range results {
+		wg.Add(1)
+		go func(i int, result TraceResult) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			txResults[i] = proveTransaction(ctx, blockNum, txCount, result, opts)
+			if txResults[i].Error != "" {
+				fmt.Printf("Transaction %d/%d failed: %s\n", result.TxIndex+1, txCount, txResults[i].Error)
+			} else {
+				fmt.Printf("Completed transaction %d/%d with %d executed instructions\n",
+					result.TxIndex+1, txCount, txResults[i].ExecutedInstructions)
+			}
+		}(i, result)
+	}
+	wg.Wait()
+	proveTime := time.Since(proveStart)
+
+	var aggregated prover.AggregateProof
+	var aggregateErr error
+	if opts.aggregate {
+		aggregated, aggregateErr = aggregateTransactions(ctx, txResults)
+		if aggregateErr != nil {
+			fmt.Printf("Aggregation failed: %v\n", aggregateErr)
+		} else {
+			fmt.Printf("Aggregated %d transaction proofs in %v\n", len(aggregated.Transactions), time.Duration(aggregated.TimeMs)*time.Millisecond)
+		}
+	}
+
+	blockResult := struct {
+		BlockNumber          uint64        `json:"block_number"`
+		Backend              string        `json:"backend"`
+		PerTransaction       bool          `json:"per_transaction"`
+		Workers              int           `json:"workers"`
+		TransactionCount     int           `json:"transaction_count"`
+		FailedCount          int           `json:"failed_count"`
+		Transactions         []ProofResult `json:"transactions"`
+		TotalEvmInstructions int           `json:"total_evm_instructions"`
+		ExecutedInstructions uint64        `json:"executed_instructions"`
+		BlockFetchTimeMs     int64         `json:"block_fetch_time_ms"`
+		TxFetchTimeMs        int64         `json:"tx_fetch_time_ms"`
+		ProofTimeMs          int64         `json:"proof_time_ms"`
+		TotalTimeMs          int64         `json:"total_time_ms"`
+		Timestamp            string        `json:"timestamp"`
+		// Only set with --aggregate
+		AggregateProof        string                         `json:"aggregate_proof,omitempty"`
+		AggregateVK           string                         `json:"aggregate_vk,omitempty"`
+		AggregateExeCommit    string                         `json:"aggregate_exe_commit,omitempty"`
+		AggregateVmCommit     string                         `json:"aggregate_vm_commit,omitempty"`
+		AggregatePublicValues string                         `json:"aggregate_public_values,omitempty"`
+		AggregateTransactions []prover.AggregatedTransaction `json:"aggregate_transactions,omitempty"`
+		AggregateTimeMs       int64                          `json:"aggregate_time_ms,omitempty"`
+		AggregateError        string                         `json:"aggregate_error,omitempty"`
+	}{
+		BlockNumber:      blockNum,
+		Backend:          opts.zkBackend.Name(),
+		PerTransaction:   true,
+		Workers:          opts.workers,
+		TransactionCount: len(txResults),
+		Transactions:     txResults,
+		BlockFetchTimeMs: blockFetchTime.Milliseconds(),
+		TxFetchTimeMs:    txFetchTime.Milliseconds(),
+		ProofTimeMs:      proveTime.Milliseconds(),
+		TotalTimeMs:      (blockFetchTime + txFetchTime + proveTime).Milliseconds() + aggregated.TimeMs,
+		Timestamp:        time.Now().UTC().Format(time.RFC3339),
+
+		AggregateTransactions: aggregated.Transactions,
+		AggregateTimeMs:       aggregated.TimeMs,
+	}
+	if len(aggregated.Proof) > 0 {
+		blockResult.AggregateProof = hex.EncodeToString(aggregated.Proof)
+		blockResult.AggregateVK = hex.EncodeToString(aggregated.VerifyingKey)
+		blockResult.AggregateExeCommit = aggregated.AppExeCommit
+		blockResult.AggregateVmCommit = aggregated.AppVmCommit
+		blockResult.AggregatePublicValues = hex.EncodeToString(aggregated.PublicValues)
+	}
+	if aggregateErr != nil {
+		blockResult.AggregateError = aggregateErr.Error()
+	}
+	for _, txResult := range txResults {
+		if txResult.Error != "" {
+			blockResult.FailedCount++
+		}
+		blockResult.TotalEvmInstructions += txResult.InstructionCount
+		blockResult.ExecutedInstructions += txResult.ExecutedInstructions
+	}
+
+	jsonData, err := json.MarshalIndent(blockResult, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON for block: %v", err)
+	}
+
+	outputFile := fmt.Sprintf("block_%d.json", blockNum)
+	err = os.WriteFile(outputFile, jsonData, 0644)
+	if err != nil {
+		fmt.Printf("Error writing to file %s: %v\n", outputFile, err)
+	} else {
+		fmt.Printf("Block %d results written to: %s\n", blockNum, outputFile)
+	}
+
+	fmt.Printf("Proved %d of %d transactions in %v\n",
+		len(txResults)-blockResult.FailedCount, len(txResults), proveTime)
+
+	if aggregateErr != nil {
+		return fmt.Errorf("failed to aggregate block %d: %v", blockNum, aggregateErr)
+	}
+	return nil
+}
+
+// aggregateTransactions folds the STARK proofs of the transactions into one, which needs all of them to be proven.
+func aggregateTransactions(ctx context.Context, txResults []ProofResult) (prover.AggregateProof, error) {
+	proofs := make([]prover.TransactionProof, 0, len(txResults))
+	for _, txResult := range txResults {
+		if txResult.Error != "" {
+			return prover.AggregateProof{}, fmt.Errorf("transaction %d is not proven", txResult.TransactionIndex)
+		}
+		proof, err := hex.DecodeString(txResult.Proof)
+		if err != nil {
+			return prover.AggregateProof{}, err
+		}
+		proofs = append(proofs, prover.TransactionProof{TxHash: txResult.TransactionHash, Proof: proof})
+	}
+
+	fmt.Printf("Aggregating %d transaction proofs...\n", len(proofs))
+	return prover.AggregateProofs(ctx, proofs)
+}
+
+// proveTransaction transpiles and proves one transaction, the error of any step is kept in the result.
+func proveTransaction(ctx context.Context, blockNum uint64, txCount int, result TraceResult, opts blockOptions) ProofResult {
+	txResult := ProofResult{
+		TransactionHash:  result.TxHash.String(),
+		TransactionIndex: result.TxIndex + 1,
+		InstructionCount: len(result.Instructions),
+	}
+	if result.Error != nil {
+		txResult.Error = fmt.Sprintf("failed to trace: %v", result.Error)
+		return txResult
+	}
+
+	fmt.Printf("Transpiling transaction %d/%d with %d instructions\n",
+		result.TxIndex+1, txCount, len(result.Instructions))
+	transpileStart := time.Now()
+	txTranspiler := transpiler.NewTranspiler()
+	if _, err := txTranspiler.ProcessExecution(result.Instructions, result.State); err != nil {
+		txResult.Error = fmt.Sprintf("failed to transpile: %v", err)
+		return txResult
+	}
+	txResult.Soundness = txTranspiler.SoundnessReport()
+	zkVm, err := prover.NewZkProverFromAssembly(txTranspiler.ToAssembly(), opts.zkBackend)
+	txResult.TranspileTimeMs = time.Since(transpileStart).Milliseconds()
+	if err != nil {
+		txResult.Error = fmt.Sprintf("failed to generate assembly: %v", err)
+		return txResult
+	}
+	content := zkVm.Content()
+
+	if opts.debugAssembly {
+		extension := filepath.Ext(opts.assemblyFile)
+		assemblyFile := fmt.Sprintf("%s_tx%d%s", strings.TrimSuffix(opts.assemblyFile, extension), result.TxIndex+1, extension)
+		if err := os.WriteFile(assemblyFile, []byte(content), 0644); err != nil {
+			fmt.Printf("Error writing assembly file %s: %v\n", assemblyFile, err)
+		}
+	}
+
+	cycleCountStart := time.Now()
+	cycles, err := zkVm.CountCycles()
+	txResult.CycleCountTimeMs = time.Since(cycleCountStart).Milliseconds()
+	if err != nil {
+		txResult.Error = fmt.Sprintf("failed to count executed instructions: %v", err)
+		return txResult
+	}
+	txResult.ExecutedInstructions = cycles.Total
+	txResult.Cycles = cycles
+
+	debugFile := fmt.Sprintf("debug_mappings_block_%d_tx%d.json", blockNum, result.TxIndex+1)
+	if opts.skipProof {
+		if opts.debugMode {
+			if saveErr := txTranspiler.SaveDebugMappings(debugFile); saveErr != nil {
+				fmt.Printf("Failed to save debug mappings: %v\n", saveErr)
+			}
+		}
+		return txResult
+	}
+
+	proveStart := time.Now()
+	var output prover.ProofGeneration
+	if opts.useStarkProof {
+		output, err = zkVm.StarkProve(ctx)
+	} else {
+		output, err = zkVm.Prove(ctx)
+	}
+	txResult.ProofTimeMs = time.Since(proveStart).Milliseconds()
+	if err != nil {
+		txResult.Error = fmt.Sprintf("failed to prove: %v", err)
+		if saveErr := txTranspiler.SaveDebugMappings(debugFile); saveErr == nil {
+			fmt.Printf("Debug mappings of transaction %d saved to: %s\n", result.TxIndex+1, debugFile)
+		}
+		return txResult
+	}
+	txResult.AppVK = hex.EncodeToString(output.AppVK)
+	txResult.Proof = hex.EncodeToString(output.Proof)
+	return txResult
+}
@@ -3,23 +3,38 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"erigon-transpiler-risc-v/prover"
 	"erigon-transpiler-risc-v/transpiler"
+
+	"github.com/alexflint/go-arg"
 )
 
+var args struct {
+	Mappings    string `arg:"positional,required" help:"Debug mappings JSON written by tx-prove or block-prove"`
+	Emulator    bool   `arg:"-e,--emulator" help:"Bisect on the emulator against the EVM stacks, then confirm the candidate with the prover"`
+	SkipConfirm bool   `arg:"--skip-confirm" help:"With --emulator, don't confirm the candidate with the prover"`
+	Minimize    bool   `arg:"-m,--minimize" help:"Shrink the failing prefix to a minimal set of opcodes that still fails to prove"`
+	Workers     int    `arg:"-w,--workers" default:"1" help:"Number of prefixes checked concurrently in each search round"`
+	MemoryGB    int    `arg:"--memory-budget" help:"Total memory in GB for the workers, 0 = unlimited"`
+	WorkerGB    int    `arg:"--worker-memory" default:"16" help:"Peak memory in GB of a single proof, used with --memory-budget"`
+}
+
+// Maximum time to prove a single prefix
+const proveTimeout = 10 * time.Minute
+
+// Reports whether the opcodes 0..endIndex work, nil when they do
+type checkFunc func(ctx context.Context, mappings []transpiler.EvmToRiscVMapping, endIndex int) error
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Printf("Usage: %s <debug_mappings.json>\n", os.Args[0])
-		fmt.Printf("This tool will binary search to find the problematic EVM opcode using existing debug mappings\n")
-		os.Exit(1)
-	}
-	filename := os.Args[1]
+	arg.MustParse(&args)
 
-	data, err := os.ReadFile(filename)
+	data, err := os.ReadFile(args.Mappings)
 	if err != nil {
 		fmt.Printf("Error reading file: %v\n", err)
 		os.Exit(1)
@@ -33,47 +48,47 @@ func main() {
 	}
 
 	fmt.Printf("Total EVM opcodes: %d\n", len(mappings))
-	fmt.Printf("Starting binary search to find problematic assembly...\n\n")
 
-	left := 0
-	right := len(mappings) - 1
-	lastWorkingIndex := -1
+	check := checkWithProver
+	if args.Emulator {
+		if !transpiler.HasEvmStacks(mappings) {
+			fmt.Printf("Warning: the mappings have no EVM stacks, only emulator crashes will be detected\n")
+		}
+		check = checkWithEmulator
+	}
 
-	content := ""
+	workers := args.Workers
+	if args.MemoryGB > 0 && args.WorkerGB > 0 {
+		workers = min(workers, args.MemoryGB/args.WorkerGB)
+	}
+	workers = max(workers, 1)
 
-	for left <= right {
-		mid := (left + right) / 2
-		fmt.Printf("Testing range 0-%d (%d EVM opcodes)...", mid, mid+1)
+	var lastWorkingIndex int
+	if workers > 1 {
+		fmt.Printf("Starting %d-ary search with %d workers to find problematic assembly...\n\n", workers+1, workers)
+		lastWorkingIndex = parallelSearch(mappings, check, workers)
+	} else {
+		fmt.Printf("Starting binary search to find problematic assembly...\n\n")
+		lastWorkingIndex = bisect(mappings, check)
+	}
 
-		content, err = buildAssemblyUpTo(mappings, mid)
-		if err != nil {
-			fmt.Printf(" FAILED at assembly generation: %v\n", err)
-			right = mid - 1
+	problemIndex := lastWorkingIndex + 1
+	if problemIndex < len(mappings) && args.Emulator && !args.SkipConfirm {
+		fmt.Printf("\nConfirming opcode %d (%s) with the prover...", problemIndex, mappings[problemIndex].EvmOpcode)
+		if err := checkWithProver(context.Background(), mappings, problemIndex); err != nil {
+			fmt.Printf(" FAILED at proving: %v\n", err)
 		} else {
-			start := time.Now()
-			zkVm := prover.NewZkProver(content)
-
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-			defer cancel()
-
-			_, err := zkVm.Prove(ctx)
-
-			end := time.Now()
-			duration := end.Sub(start)
-			fmt.Printf(" Proving took %s...", duration.String())
-			if err != nil {
-				fmt.Printf(" FAILED at proving: %v\n", err)
-				right = mid - 1
-			} else {
-				fmt.Printf(" SUCCESS\n")
-				lastWorkingIndex = mid
-				left = mid + 1
-			}
+			fmt.Printf(" SUCCESS\n")
+			fmt.Printf("The prover accepts the diverging stack, run without --emulator to find the opcode that breaks proving\n")
 		}
 	}
 
+	endIndex := min(problemIndex, len(mappings)-1)
+	content, err := transpiler.MappingsAssembly(mappings[:endIndex+1]).ToToolChainCompatibleAssembly()
 	assemblyFile := "debug_transpiler_assembly.s"
-	err = os.WriteFile(assemblyFile, []byte(content), 0644)
+	if err == nil {
+		err = os.WriteFile(assemblyFile, []byte(content), 0644)
+	}
 	if err != nil {
 		fmt.Printf("Warning: Failed to write assembly to %s: %v\n", assemblyFile, err)
 	} else {
@@ -86,34 +101,211 @@ func main() {
 	} else if lastWorkingIndex == len(mappings)-1 {
 		fmt.Printf("\n✅ All EVM opcodes compile successfully!\n")
 	} else {
-		problemIndex := lastWorkingIndex + 1
 		fmt.Printf("\n🎯 Found problematic EVM opcode!\n")
-		// fmt.Printf("Last working index: %d (%s) [depth: %d]\n", lastWorkingIndex, mappings[lastWorkingIndex].EvmOpcode, mappings[lastWorkingIndex].CallDepth)
 		fmt.Printf("Problematic EVM opcode at index %d: %s [depth: %d]\n", problemIndex, mappings[problemIndex].EvmOpcode, mappings[problemIndex].CallDepth)
+		if source := mappings[problemIndex].Source; source != nil {
+			fmt.Printf("Traced from %s, RISC-V instruction %d\n", source, mappings[problemIndex].RiscVStart)
+		}
+
+		if args.Minimize {
+			minimize(mappings[:problemIndex+1])
+		}
 	}
 }
 
-func buildAssemblyUpTo(mappings []transpiler.EvmToRiscVMapping, endIndex int) (string, error) {
-	var allInstructions []prover.Instruction
-	dataVarMap := make(map[string]prover.DataVariable)
+// minimize writes the smallest set of mappings from the failing prefix that still fails to prove.
+func minimize(failing []transpiler.EvmToRiscVMapping) {
+	fmt.Printf("\nMinimizing %d EVM opcodes...\n", len(failing))
+	checks := 0
+	minimized := transpiler.MinimizeMappings(failing, func(subset []transpiler.EvmToRiscVMapping) bool {
+		checks++
+		// Removing a mapping can drop a label the others jump to, such subsets are not valid programs
+		if _, err := transpiler.MappingsAssembly(subset).ToBytecode(); errors.Is(err, prover.ErrUndefinedSymbol) {
+			fmt.Printf("Check %d: %d EVM opcodes... skipped, %v\n", checks, len(subset), err)
+			return false
+		}
+		fmt.Printf("Check %d: %d EVM opcodes...", checks, len(subset))
+		// Like the bisection, any failure counts, assembly errors included
+		if err := checkWithProver(context.Background(), subset, len(subset)-1); err != nil {
+			fmt.Printf(" still fails\n")
+			return true
+		}
+		fmt.Printf(" passes\n")
+		return false
+	})
+
+	fmt.Printf("Minimal reproducer: %d EVM opcodes after %d checks\n", len(minimized), checks)
+	for _, mapping := range minimized {
+		if mapping.Source != nil {
+			fmt.Printf("  %s [depth: %d] %s\n", mapping.EvmOpcode, mapping.CallDepth, mapping.Source)
+		} else {
+			fmt.Printf("  %s [depth: %d]\n", mapping.EvmOpcode, mapping.CallDepth)
+		}
+	}
 
-	for i := 0; i <= endIndex && i < len(mappings); i++ {
-		allInstructions = append(allInstructions, mappings[i].RiscVInstructions...)
+	mappingsFile := "debug_transpiler_minimized.json"
+	data, err := json.MarshalIndent(minimized, "", "  ")
+	if err == nil {
+		err = os.WriteFile(mappingsFile, data, 0644)
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to write mappings to %s: %v\n", mappingsFile, err)
+	} else {
+		fmt.Printf("Minimized mappings written to: %s\n", mappingsFile)
+	}
 
-		for _, dataVar := range mappings[i].DataVariables {
-			dataVarMap[dataVar.Name] = dataVar
+	assemblyFile := "debug_transpiler_minimized.s"
+	content, err := transpiler.MappingsAssembly(minimized).ToToolChainCompatibleAssembly()
+	if err == nil {
+		err = os.WriteFile(assemblyFile, []byte(content), 0644)
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to write assembly to %s: %v\n", assemblyFile, err)
+	} else {
+		fmt.Printf("Minimized assembly written to: %s\n", assemblyFile)
+	}
+}
+
+// bisect returns the index of the last opcode for which the prefix passes the check, -1 when the first opcode fails.
+func bisect(mappings []transpiler.EvmToRiscVMapping, check checkFunc) int {
+	left := 0
+	right := len(mappings) - 1
+	lastWorkingIndex := -1
+
+	for left <= right {
+		mid := (left + right) / 2
+		fmt.Printf("Testing range 0-%d (%d EVM opcodes)...", mid, mid+1)
+
+		start := time.Now()
+		err := check(context.Background(), mappings, mid)
+		fmt.Printf(" took %s...", time.Since(start).String())
+		if err != nil {
+			fmt.Printf(" FAILED: %v\n", err)
+			right = mid - 1
+		} else {
+			fmt.Printf(" SUCCESS\n")
+			lastWorkingIndex = mid
+			left = mid + 1
+		}
+	}
+	return lastWorkingIndex
+}
+
+// parallelSearch is bisect with several prefixes checked concurrently in each round.
+// Every proof runs in its own temporary workspace, the checks that can no longer change the result are cancelled.
+func parallelSearch(mappings []transpiler.EvmToRiscVMapping, check checkFunc, workers int) int {
+	left := 0
+	right := len(mappings) - 1
+	lastWorkingIndex := -1
+
+	for round := 1; left <= right; round++ {
+		candidates := searchCandidates(left, right, workers)
+		fmt.Printf("Round %d: testing %d prefixes in range 0-%d..0-%d\n", round, len(candidates), left, right)
+
+		passed := checkConcurrently(mappings, check, candidates)
+		// The prefixes are checked in order, stop at the first failure
+		newRight := right
+		for i, candidate := range candidates {
+			if passed[i] == nil {
+				continue
+			}
+			if *passed[i] {
+				lastWorkingIndex = candidate
+				left = candidate + 1
+			} else {
+				newRight = candidate - 1
+				break
+			}
+		}
+		right = newRight
+		fmt.Printf("Round %d: narrowed to range 0-%d..0-%d\n\n", round, left, right)
+	}
+	return lastWorkingIndex
+}
+
+// searchCandidates splits left..right into evenly spaced prefixes, one per worker.
+func searchCandidates(left, right, workers int) []int {
+	size := right - left + 1
+	if size <= workers {
+		candidates := make([]int, 0, size)
+		for i := left; i <= right; i++ {
+			candidates = append(candidates, i)
 		}
+		return candidates
 	}
+	candidates := make([]int, 0, workers)
+	for i := 1; i <= workers; i++ {
+		candidates = append(candidates, left+i*size/(workers+1))
+	}
+	return candidates
+}
+
+// checkConcurrently returns whether each candidate passed, nil when its check was cancelled.
+// A failure makes the longer prefixes fail too and a success makes the shorter ones pass, so those checks are cancelled.
+func checkConcurrently(mappings []transpiler.EvmToRiscVMapping, check checkFunc, candidates []int) []*bool {
+	results := make([]*bool, len(candidates))
+	contexts := make([]context.Context, len(candidates))
+	cancels := make([]context.CancelFunc, len(candidates))
+	for i := range candidates {
+		contexts[i], cancels[i] = context.WithCancel(context.Background())
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, candidate := range candidates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := check(contexts[i], mappings, candidate)
 
-	var allDataVars []prover.DataVariable
-	for _, dataVar := range dataVarMap {
-		allDataVars = append(allDataVars, dataVar)
+			mu.Lock()
+			defer mu.Unlock()
+			if contexts[i].Err() != nil {
+				fmt.Printf("  0-%d: cancelled after %s\n", candidate, time.Since(start).String())
+				return
+			}
+			passed := err == nil
+			results[i] = &passed
+			if passed {
+				fmt.Printf("  0-%d: SUCCESS after %s\n", candidate, time.Since(start).String())
+				for j := 0; j < i; j++ {
+					cancels[j]()
+				}
+			} else {
+				fmt.Printf("  0-%d: FAILED after %s: %v\n", candidate, time.Since(start).String(), err)
+				for j := i + 1; j < len(candidates); j++ {
+					cancels[j]()
+				}
+			}
+		}()
 	}
+	wg.Wait()
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return results
+}
 
-	assembly := &prover.AssemblyFile{
-		Instructions: allInstructions,
-		DataSection:  allDataVars,
+func checkWithProver(ctx context.Context, mappings []transpiler.EvmToRiscVMapping, endIndex int) error {
+	content, err := transpiler.MappingsAssembly(mappings[:endIndex+1]).ToToolChainCompatibleAssembly()
+	if err != nil {
+		return fmt.Errorf("%w: %v", transpiler.ErrMappingsAssembly, err)
 	}
 
-	return assembly.ToToolChainCompatibleAssembly()
+	zkVm := prover.NewZkProver(content)
+	ctx, cancel := context.WithTimeout(ctx, proveTimeout)
+	defer cancel()
+
+	_, err = zkVm.Prove(ctx)
+	return err
+}
+
+// checkWithEmulator runs the prefix on the emulator and compares its snapshots to the EVM stacks in the mappings.
+func checkWithEmulator(_ context.Context, mappings []transpiler.EvmToRiscVMapping, endIndex int) error {
+	runner, err := prover.NewRunner()
+	if err != nil {
+		return err
+	}
+	return transpiler.CheckMappings(mappings[:endIndex+1], runner)
 }
@@ -3,34 +3,68 @@ package main
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
 	"erigon-transpiler-risc-v/transpiler"
 
 	"github.com/alexflint/go-arg"
+	libcommon "github.com/erigontech/erigon-lib/common"
 	"github.com/holiman/uint256"
 )
 
 var args struct {
-	Bytecode   string `arg:"-b,--bytecode,required" help:"Contract bytecode (hex string, with or without 0x prefix)"`
+	Bytecode   string `arg:"-b,--bytecode" help:"Contract bytecode (hex string, with or without 0x prefix)"`
+	Solidity   string `arg:"-s,--solidity" help:"Solidity contract to compile instead of --bytecode, as file:contract (e.g. contracts/Counter.sol:Counter)"`
 	Calldata   string `arg:"-c,--calldata" help:"Call data (hex string, with or without 0x prefix)"`
 	OutputFile string `arg:"-o,--output" default:"test.proof" help:"Output file path"`
+	Coverage   string `arg:"--coverage" help:"With --solidity, write the executed opcodes per Solidity line as JSON to this file"`
+	State      string `arg:"--state" help:"Pre-state of the accounts, a prestateTracer result or a debug_executionWitness JSON file"`
+	To         string `arg:"--to" help:"Call this account of --state instead of deploying --bytecode or --solidity"`
+	Gas        uint64 `arg:"--gas" default:"100000" help:"Gas limit of the call"`
 }
 
 func main() {
 	arg.MustParse(&args)
 
-	bytecodeHex := strings.TrimPrefix(args.Bytecode, "0x")
+	if args.To != "" {
+		if args.Bytecode != "" || args.Solidity != "" || args.State == "" {
+			fmt.Fprintf(os.Stderr, "--to calls the code in --state and can't be used with --bytecode or --solidity\n")
+			os.Exit(1)
+		}
+	} else if (args.Bytecode == "") == (args.Solidity == "") {
+		fmt.Fprintf(os.Stderr, "Exactly one of --bytecode and --solidity is required\n")
+		os.Exit(1)
+	}
 	calldataHex := strings.TrimPrefix(args.Calldata, "0x")
 
-	bytecode, err := hex.DecodeString(bytecodeHex)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error decoding bytecode: %v\n", err)
-		os.Exit(1)
+	var bytecode []byte
+	var contract *prover.SolidityContract
+	var err error
+	if args.Solidity != "" {
+		file, contractName, found := strings.Cut(args.Solidity, ":")
+		if !found {
+			fmt.Fprintf(os.Stderr, "Expected --solidity as file:contract\n")
+			os.Exit(1)
+		}
+		contract, err = prover.CompileSolidityFile(file, contractName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error compiling %s: %v\n", args.Solidity, err)
+			os.Exit(1)
+		}
+		bytecode = contract.Bytecode
+	} else if args.Bytecode != "" {
+		bytecode, err = hex.DecodeString(strings.TrimPrefix(args.Bytecode, "0x"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error decoding bytecode: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	var calldata []byte
@@ -42,16 +76,50 @@ func main() {
 		}
 	}
 
-	fmt.Fprintf(os.Stderr, "Running transpilation...\n")
-	assembly, _, err := transpiler.NewTestRunnerWithConfig(bytecode, transpiler.TestConfig{
+	config := transpiler.TestConfig{
 		CallValue: uint256.NewInt(0),
 		CallData:  calldata,
-	}).Execute()
+		Contract:  contract,
+		GasLimit:  args.Gas,
+	}
+	if args.State != "" {
+		config.StateReader, err = tracer.LoadStateFile(args.State)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading state from %s: %v\n", args.State, err)
+			os.Exit(1)
+		}
+	}
+	if args.To != "" {
+		if !libcommon.IsHexAddress(args.To) {
+			fmt.Fprintf(os.Stderr, "Invalid --to address %q\n", args.To)
+			os.Exit(1)
+		}
+		to := libcommon.HexToAddress(args.To)
+		config.Address = &to
+	}
+
+	fmt.Fprintf(os.Stderr, "Running transpilation...\n")
+	assembly, _, err := transpiler.NewTestRunnerWithConfig(bytecode, config).Execute()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error during transpilation: %v\n", err)
 		os.Exit(1)
 	}
 
+	if contract != nil {
+		coverage := assembly.SolidityCoverage()
+		printCoverage(coverage)
+		if args.Coverage != "" {
+			data, err := json.MarshalIndent(coverage, "", "  ")
+			if err == nil {
+				err = os.WriteFile(args.Coverage, data, 0644)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing coverage to %s: %v\n", args.Coverage, err)
+				os.Exit(1)
+			}
+		}
+	}
+
 	fmt.Fprintf(os.Stderr, "Converting to toolchain-compatible assembly...\n")
 	content, err := assembly.ToToolChainCompatibleAssembly()
 	if err != nil {
@@ -81,3 +149,24 @@ func main() {
 	verifyCmd := fmt.Sprintf("cargo openvm verify app --app-vk %s --proof %s", appVkFile, proofFile)
 	fmt.Println(verifyCmd)
 }
+
+func printCoverage(coverage map[string]map[int]int) {
+	files := make([]string, 0, len(coverage))
+	for file := range coverage {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		lines := make([]int, 0, len(coverage[file]))
+		for line := range coverage[file] {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		fmt.Fprintf(os.Stderr, "Executed opcodes per line of %s:\n", file)
+		for _, line := range lines {
+			fmt.Fprintf(os.Stderr, "  %5d: %d\n", line, coverage[file][line])
+		}
+	}
+}
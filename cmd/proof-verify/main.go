@@ -15,8 +15,8 @@ func main() {
 
 	cmd := &cobra.Command{
 		Use:   "proof-verify",
-		Short: "Verify OpenVM proofs from results file",
-		Long:  "Verify OpenVM app proofs using cargo openvm verify app command with data from results.json",
+		Short: "Verify zkVM proofs from results file",
+		Long:  "Verify app proofs with data from results.json, using the backend recorded in the file (defaults to OpenVM)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 			defer cancel()
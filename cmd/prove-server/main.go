@@ -0,0 +1,680 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+	"erigon-transpiler-risc-v/transpiler"
+
+	"github.com/alexflint/go-arg"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/holiman/uint256"
+)
+
+var args struct {
+	Listen  string `arg:"--listen" default:"localhost:8080" help:"Address the REST API listens on"`
+	DataDir string `arg:"--data-dir" default:"prove-server-data" help:"Directory with the jobs, their logs and artifacts"`
+	Workers int    `arg:"--workers" default:"1" help:"Jobs run concurrently"`
+	RPCURL  string `arg:"--rpc-url" help:"http(s):// or ws(s):// JSON-RPC node to trace transaction and block jobs on"`
+	Backend string `arg:"--backend" default:"openvm" help:"Default zkVM backend of the jobs (openvm, sp1, risc0)"`
+}
+
+const (
+	JobTypeBytecode    = "bytecode"
+	JobTypeTransaction = "transaction"
+	JobTypeBlock       = "block"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// JobRequest is the body of POST /jobs, the fields used depend on the type.
+type JobRequest struct {
+	Type        string `json:"type"`
+	Bytecode    string `json:"bytecode,omitempty"`
+	Calldata    string `json:"calldata,omitempty"`
+	TxHash      string `json:"tx_hash,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	// Optional, the server's --backend by default
+	Backend   string `json:"backend,omitempty"`
+	SkipProof bool   `json:"skip_proof,omitempty"`
+}
+
+type Job struct {
+	ID                   string     `json:"id"`
+	Request              JobRequest `json:"request"`
+	Status               JobStatus  `json:"status"`
+	Error                string     `json:"error,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	StartedAt            *time.Time `json:"started_at,omitempty"`
+	FinishedAt           *time.Time `json:"finished_at,omitempty"`
+	ExecutedInstructions uint64     `json:"executed_instructions,omitempty"`
+	Artifacts            []string   `json:"artifacts,omitempty"`
+}
+
+func main() {
+	arg.MustParse(&args)
+	if args.Workers < 1 {
+		fmt.Fprintf(os.Stderr, "--workers must be at least 1\n")
+		os.Exit(1)
+	}
+	if _, err := prover.NewBackend(args.Backend); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	store, err := openJobStore(args.DataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", args.DataDir, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	for range args.Workers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			runWorker(ctx, store)
+		}()
+	}
+
+	server := &http.Server{Addr: args.Listen, Handler: newHandler(store)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Proving service listening on %s with %d workers, jobs in %s\n", args.Listen, args.Workers, args.DataDir)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Running jobs are interrupted and run again on the next start
+	workers.Wait()
+}
+
+// =============================================================================
+// JOB STORE
+// =============================================================================
+
+// jobStore keeps every job in <dir>/jobs/<id>/job.json next to its log and artifacts. New jobs are written in
+// <dir>/new and renamed into jobs, so a job directory always has its job.json.
+type jobStore struct {
+	dir     string
+	lock    sync.Mutex
+	wake    *sync.Cond
+	jobs    map[string]*Job
+	queue   []string
+	cancels map[string]context.CancelFunc
+}
+
+func openJobStore(dir string) (*jobStore, error) {
+	store := &jobStore{
+		dir:     dir,
+		jobs:    make(map[string]*Job),
+		cancels: make(map[string]context.CancelFunc),
+	}
+	store.wake = sync.NewCond(&store.lock)
+
+	// Jobs that weren't renamed into jobs were never accepted
+	if err := os.RemoveAll(filepath.Join(dir, "new")); err != nil {
+		return nil, err
+	}
+	for _, subdir := range []string{"jobs", "new"} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "jobs"))
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(store.jobDir(entry.Name()), "job.json"))
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("Skipping %s, it has no job.json", store.jobDir(entry.Name()))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("invalid job %s: %w", entry.Name(), err)
+		}
+		// A job that was running when the server stopped starts over
+		if job.Status == JobRunning {
+			job.Status = JobQueued
+			job.StartedAt = nil
+			if err := store.save(&job); err != nil {
+				return nil, err
+			}
+		}
+		store.jobs[job.ID] = &job
+		if job.Status == JobQueued {
+			store.queue = append(store.queue, job.ID)
+		}
+	}
+	sort.Slice(store.queue, func(i, j int) bool {
+		return store.jobs[store.queue[i]].CreatedAt.Before(store.jobs[store.queue[j]].CreatedAt)
+	})
+	return store, nil
+}
+
+func (s *jobStore) jobDir(id string) string {
+	return filepath.Join(s.dir, "jobs", id)
+}
+
+// save writes the job with a rename, so job.json is never partially written. The lock has to be held.
+func (s *jobStore) save(job *Job) error {
+	return writeJob(s.jobDir(job.ID), job)
+}
+
+func writeJob(dir string, job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "job.json")
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *jobStore) add(request JobRequest) (Job, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Job{}, err
+	}
+	job := &Job{
+		ID:        time.Now().UTC().Format("20060102-150405-") + hex.EncodeToString(id),
+		Request:   request,
+		Status:    JobQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+	staging, err := os.MkdirTemp(filepath.Join(s.dir, "new"), job.ID+"-")
+	if err != nil {
+		return Job{}, err
+	}
+	if err := writeJob(staging, job); err != nil {
+		os.RemoveAll(staging)
+		return Job{}, err
+	}
+	if err := os.Rename(staging, s.jobDir(job.ID)); err != nil {
+		os.RemoveAll(staging)
+		return Job{}, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.jobs[job.ID] = job
+	s.queue = append(s.queue, job.ID)
+	s.wake.Signal()
+	return *job, nil
+}
+
+// next waits for a queued job and marks it running, nil once ctx is done.
+func (s *jobStore) next(ctx context.Context) (*Job, context.Context) {
+	stopWaiting := context.AfterFunc(ctx, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.wake.Broadcast()
+	})
+	defer stopWaiting()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for len(s.queue) == 0 && ctx.Err() == nil {
+		s.wake.Wait()
+	}
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+
+	job := s.jobs[s.queue[0]]
+	s.queue = s.queue[1:]
+	now := time.Now().UTC()
+	job.Status = JobRunning
+	job.StartedAt = &now
+	if err := s.save(job); err != nil {
+		log.Printf("Failed to save job %s: %v", job.ID, err)
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	s.cancels[job.ID] = cancel
+	snapshot := *job
+	return &snapshot, jobCtx
+}
+
+func (s *jobStore) finish(id string, result jobResult, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	job := s.jobs[id]
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+		delete(s.cancels, id)
+	}
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	job.ExecutedInstructions = result.executedInstructions
+	job.Artifacts = result.artifacts
+	switch {
+	case job.Status == JobCanceled:
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = JobSucceeded
+	}
+	if err := s.save(job); err != nil {
+		log.Printf("Failed to save job %s: %v", job.ID, err)
+	}
+}
+
+// cancel removes a queued job from the queue or stops a running one.
+func (s *jobStore) cancel(id string) (Job, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, os.ErrNotExist
+	}
+	switch job.Status {
+	case JobQueued:
+		s.queue = slices.DeleteFunc(s.queue, func(queued string) bool { return queued == id })
+		now := time.Now().UTC()
+		job.FinishedAt = &now
+	case JobRunning:
+		s.cancels[id]()
+	default:
+		return *job, fmt.Errorf("job %s is already %s", id, job.Status)
+	}
+	job.Status = JobCanceled
+	return *job, s.save(job)
+}
+
+func (s *jobStore) get(id string) (Job, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func (s *jobStore) list() []Job {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// =============================================================================
+// REST API
+// =============================================================================
+
+func newHandler(store *jobStore) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+		var request JobRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid job request: %w", err))
+			return
+		}
+		if err := validateRequest(request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		job, err := store.add(request)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, job)
+	})
+
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, store.list())
+	})
+
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		job, ok := store.get(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no job %s", r.PathValue("id")))
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	})
+
+	mux.HandleFunc("DELETE /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		job, err := store.cancel(r.PathValue("id"))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			writeError(w, http.StatusNotFound, fmt.Errorf("no job %s", r.PathValue("id")))
+		case err != nil:
+			writeError(w, http.StatusConflict, err)
+		default:
+			writeJSON(w, http.StatusOK, job)
+		}
+	})
+
+	mux.HandleFunc("GET /jobs/{id}/log", func(w http.ResponseWriter, r *http.Request) {
+		job, ok := store.get(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no job %s", r.PathValue("id")))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		file, err := os.Open(filepath.Join(store.jobDir(job.ID), jobLogFile))
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		defer file.Close()
+		io.Copy(w, file)
+	})
+
+	mux.HandleFunc("GET /jobs/{id}/artifacts/{name}", func(w http.ResponseWriter, r *http.Request) {
+		job, ok := store.get(r.PathValue("id"))
+		if !ok || !slices.Contains(job.Artifacts, r.PathValue("name")) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no artifact %s of job %s", r.PathValue("name"), r.PathValue("id")))
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(store.jobDir(job.ID), r.PathValue("name")))
+	})
+
+	return mux
+}
+
+func validateRequest(request JobRequest) error {
+	if request.Backend != "" {
+		if _, err := prover.NewBackend(request.Backend); err != nil {
+			return err
+		}
+	}
+	switch request.Type {
+	case JobTypeBytecode:
+		if request.Bytecode == "" {
+			return fmt.Errorf("bytecode job without bytecode")
+		}
+		if _, err := hex.DecodeString(strings.TrimPrefix(request.Bytecode, "0x")); err != nil {
+			return fmt.Errorf("invalid bytecode: %w", err)
+		}
+		if _, err := hex.DecodeString(strings.TrimPrefix(request.Calldata, "0x")); err != nil {
+			return fmt.Errorf("invalid calldata: %w", err)
+		}
+	case JobTypeTransaction, JobTypeBlock:
+		if args.RPCURL == "" {
+			return fmt.Errorf("%s jobs need the server to be started with --rpc-url", request.Type)
+		}
+		if request.Type == JobTypeTransaction && len(strings.TrimPrefix(request.TxHash, "0x")) != 64 {
+			return fmt.Errorf("invalid tx_hash %q", request.TxHash)
+		}
+	default:
+		return fmt.Errorf("unknown job type %q, expected %s, %s or %s", request.Type, JobTypeBytecode, JobTypeTransaction, JobTypeBlock)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// =============================================================================
+// JOB EXECUTION
+// =============================================================================
+
+const (
+	jobLogFile       = "log.txt"
+	jobAssemblyFile  = "assembly.s"
+	jobCyclesFile    = "cycles.json"
+	jobResultsFile   = "results.json"
+	jobTraceFile     = "trace.bin"
+	jobProverLogFile = "prover.log"
+)
+
+type jobResult struct {
+	executedInstructions uint64
+	artifacts            []string
+}
+
+func runWorker(ctx context.Context, store *jobStore) {
+	for {
+		job, jobCtx := store.next(ctx)
+		if job == nil {
+			return
+		}
+		result, err := runJob(jobCtx, store.jobDir(job.ID), job.Request)
+		// Interrupted by the shutdown, the job stays running and is queued again on restart
+		if ctx.Err() != nil {
+			return
+		}
+		store.finish(job.ID, result, err)
+	}
+}
+
+func runJob(ctx context.Context, dir string, request JobRequest) (jobResult, error) {
+	logFile, err := os.OpenFile(filepath.Join(dir, jobLogFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return jobResult{}, err
+	}
+	defer logFile.Close()
+	logger := log.New(logFile, "", log.LstdFlags)
+	result := jobResult{artifacts: []string{jobLogFile}}
+
+	err = func() error {
+		backendName := request.Backend
+		if backendName == "" {
+			backendName = args.Backend
+		}
+		zkBackend, err := prover.NewBackend(backendName)
+		if err != nil {
+			return err
+		}
+
+		var traced []tracer.TracedTransaction
+		switch request.Type {
+		case JobTypeBytecode:
+			traced, err = traceBytecode(request, logger)
+		default:
+			traced, err = traceTransactions(ctx, request, logger)
+		}
+		if err != nil {
+			return err
+		}
+		assembly, soundness, err := transpileTraces(dir, traced, logger, &result)
+		if err != nil {
+			return err
+		}
+		return proveAssembly(ctx, dir, assembly, soundness, zkBackend, request.SkipProof, logger, &result)
+	}()
+	if err != nil {
+		logger.Printf("Job failed: %v", err)
+	} else {
+		logger.Printf("Job succeeded")
+	}
+	return result, err
+}
+
+// traceBytecode calls the bytecode deployed on an empty state, like evm-prove.
+func traceBytecode(request JobRequest, logger *log.Logger) ([]tracer.TracedTransaction, error) {
+	bytecode, err := hex.DecodeString(strings.TrimPrefix(request.Bytecode, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	calldata, err := hex.DecodeString(strings.TrimPrefix(request.Calldata, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Tracing %d bytes of bytecode with %d bytes of calldata", len(bytecode), len(calldata))
+
+	contractAddr := libcommon.HexToAddress(transpiler.CONTRACT_ADDRESS)
+	simpleTracer := tracer.NewSimpleTracer()
+	if err := simpleTracer.DeployContract(contractAddr, bytecode, uint256.NewInt(1000)); err != nil {
+		return nil, err
+	}
+	instructions, state, _, err := simpleTracer.ExecuteContract(contractAddr, calldata, 100000, uint256.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	return []tracer.TracedTransaction{{Instructions: instructions, State: state}}, nil
+}
+
+// traceTransactions traces the transaction or the transactions of the block on the JSON-RPC node.
+func traceTransactions(ctx context.Context, request JobRequest, logger *log.Logger) ([]tracer.TracedTransaction, error) {
+	client, err := tracer.DialRPC(ctx, args.RPCURL)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	rpcTracer := tracer.NewRPCTracer(client)
+
+	var txHashes []libcommon.Hash
+	if request.Type == JobTypeTransaction {
+		txHashes = []libcommon.Hash{libcommon.HexToHash(request.TxHash)}
+	} else {
+		txHashes, err = rpcTracer.BlockTransactions(ctx, request.BlockNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block %d: %w", request.BlockNumber, err)
+		}
+		logger.Printf("Block %d has %d transactions", request.BlockNumber, len(txHashes))
+	}
+
+	traced := make([]tracer.TracedTransaction, 0, len(txHashes))
+	for i, txHash := range txHashes {
+		instructions, state, err := rpcTracer.TraceTransaction(ctx, txHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to trace %s: %w", txHash.Hex(), err)
+		}
+		logger.Printf("Traced transaction %d/%d %s with %d instructions", i+1, len(txHashes), txHash.Hex(), len(instructions))
+		traced = append(traced, tracer.TracedTransaction{Instructions: instructions, State: state})
+	}
+	return traced, nil
+}
+
+// transpileTraces transpiles the traced transactions into one program, like block-prove.
+func transpileTraces(dir string, traced []tracer.TracedTransaction, logger *log.Logger, result *jobResult) (*prover.AssemblyFile, *prover.SoundnessReport, error) {
+	jobTranspiler := transpiler.NewTranspiler()
+	for i, transaction := range traced {
+		if _, err := jobTranspiler.ProcessExecution(transaction.Instructions, transaction.State); err != nil {
+			return nil, nil, fmt.Errorf("failed to transpile transaction %d: %w", i+1, err)
+		}
+		if i < len(traced)-1 {
+			jobTranspiler.AddTransactionBoundary()
+		}
+	}
+
+	if err := tracer.WriteTraceFile(filepath.Join(dir, jobTraceFile), traced); err != nil {
+		return nil, nil, fmt.Errorf("failed to write the trace: %w", err)
+	}
+	result.artifacts = append(result.artifacts, jobTraceFile)
+
+	soundness := jobTranspiler.SoundnessReport()
+	logger.Printf("Constrained opcodes: %.2f%%", soundness.ConstrainedPercentage)
+	return jobTranspiler.ToAssembly(), soundness, nil
+}
+
+func proveAssembly(ctx context.Context, dir string, assembly *prover.AssemblyFile, soundness *prover.SoundnessReport, zkBackend prover.Backend, skipProof bool, logger *log.Logger, result *jobResult) error {
+	zkVm, err := prover.NewZkProverFromAssembly(assembly, zkBackend)
+	if err != nil {
+		return fmt.Errorf("failed to generate assembly: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, jobAssemblyFile), []byte(zkVm.Content()), 0644); err != nil {
+		return err
+	}
+	result.artifacts = append(result.artifacts, jobAssemblyFile)
+
+	cycles, err := zkVm.CountCycles()
+	if err != nil {
+		return fmt.Errorf("failed to count executed instructions: %w", err)
+	}
+	if err := writeArtifact(dir, jobCyclesFile, cycles, result); err != nil {
+		return err
+	}
+	result.executedInstructions = cycles.Total
+	logger.Printf("Executed %d instructions", cycles.Total)
+
+	results := prover.ResultsFile{
+		AppVK:     "skipped",
+		Proof:     "skipped",
+		Backend:   zkBackend.Name(),
+		Soundness: soundness,
+	}
+	if !skipProof {
+		logger.Printf("Proving on %s", zkBackend.Name())
+		proveStart := time.Now()
+		output, err := zkVm.Prove(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to prove: %w", err)
+		}
+		logger.Printf("Proved in %v (build %dms cached=%t, keygen %dms cached=%t, prove %dms)", time.Since(proveStart),
+			output.Timing.BuildTimeMs, output.Timing.BuildCached, output.Timing.KeygenTimeMs, output.Timing.KeygenCached,
+			output.Timing.ProveTimeMs)
+		if err := os.WriteFile(filepath.Join(dir, jobProverLogFile), []byte(output.Stdout), 0644); err == nil {
+			result.artifacts = append(result.artifacts, jobProverLogFile)
+		}
+		results.AppVK = hex.EncodeToString(output.AppVK)
+		results.Proof = hex.EncodeToString(output.Proof)
+	}
+	return writeArtifact(dir, jobResultsFile, results, result)
+}
+
+func writeArtifact(dir string, name string, value interface{}, result *jobResult) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return err
+	}
+	result.artifacts = append(result.artifacts, name)
+	return nil
+}
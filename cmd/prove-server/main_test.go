@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var bytecodeRequest = JobRequest{Type: JobTypeBytecode, Bytecode: "6001600201", SkipProof: true}
+
+func TestJobStorePersistence(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openJobStore(dir)
+	require.NoError(t, err)
+	job, err := store.add(bytecodeRequest)
+	require.NoError(t, err)
+
+	reopened, err := openJobStore(dir)
+	require.NoError(t, err)
+	loaded, ok := reopened.get(job.ID)
+	require.True(t, ok)
+	assert.Equal(t, JobQueued, loaded.Status)
+	assert.Equal(t, bytecodeRequest, loaded.Request)
+	assert.Equal(t, []string{job.ID}, reopened.queue)
+}
+
+func TestJobStoreRequeuesRunningJobs(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openJobStore(dir)
+	require.NoError(t, err)
+	first, err := store.add(bytecodeRequest)
+	require.NoError(t, err)
+	second, err := store.add(bytecodeRequest)
+	require.NoError(t, err)
+
+	running, _ := store.next(context.Background())
+	require.NotNil(t, running)
+	assert.Equal(t, first.ID, running.ID)
+	assert.Equal(t, JobRunning, running.Status)
+
+	// The server stopped while the first job was running
+	reopened, err := openJobStore(dir)
+	require.NoError(t, err)
+	requeued, ok := reopened.get(first.ID)
+	require.True(t, ok)
+	assert.Equal(t, JobQueued, requeued.Status)
+	assert.Nil(t, requeued.StartedAt)
+	assert.Equal(t, []string{first.ID, second.ID}, reopened.queue)
+}
+
+func TestJobStoreSkipsIncompleteJobs(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openJobStore(dir)
+	require.NoError(t, err)
+	job, err := store.add(bytecodeRequest)
+	require.NoError(t, err)
+
+	// A job directory without job.json and a job that was never renamed into jobs
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "jobs", "incomplete"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "new", "unaccepted"), 0755))
+
+	reopened, err := openJobStore(dir)
+	require.NoError(t, err)
+	jobs := reopened.list()
+	require.Len(t, jobs, 1)
+	assert.Equal(t, job.ID, jobs[0].ID)
+	assert.NoDirExists(t, filepath.Join(dir, "new", "unaccepted"))
+}
+
+func TestJobStoreCancel(t *testing.T) {
+	store, err := openJobStore(t.TempDir())
+	require.NoError(t, err)
+	running, err := store.add(bytecodeRequest)
+	require.NoError(t, err)
+	queued, err := store.add(bytecodeRequest)
+	require.NoError(t, err)
+	_, jobCtx := store.next(context.Background())
+	require.NotNil(t, jobCtx)
+
+	canceled, err := store.cancel(queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, JobCanceled, canceled.Status)
+	assert.NotNil(t, canceled.FinishedAt)
+	assert.Empty(t, store.queue)
+
+	// The running job is stopped through its context and stays canceled when it finishes
+	_, err = store.cancel(running.ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, jobCtx.Err(), context.Canceled)
+	store.finish(running.ID, jobResult{}, jobCtx.Err())
+	finished, _ := store.get(running.ID)
+	assert.Equal(t, JobCanceled, finished.Status)
+	assert.NotNil(t, finished.FinishedAt)
+
+	_, err = store.cancel(running.ID)
+	assert.ErrorContains(t, err, "already canceled")
+	_, err = store.cancel("missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Nothing is left to run
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	next, _ := store.next(ctx)
+	assert.Nil(t, next)
+}
+
+func TestHandler(t *testing.T) {
+	store, err := openJobStore(t.TempDir())
+	require.NoError(t, err)
+	server := httptest.NewServer(newHandler(store))
+	defer server.Close()
+
+	request := func(method string, path string, body string) (int, []byte) {
+		httpRequest, err := http.NewRequest(method, server.URL+path, bytes.NewReader([]byte(body)))
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(httpRequest)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		data, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, data
+	}
+
+	status, body := request(http.MethodPost, "/jobs", `{"type": "bytecode", "bytecode": "0x6001600201", "skip_proof": true}`)
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var job Job
+	require.NoError(t, json.Unmarshal(body, &job))
+	assert.Equal(t, JobQueued, job.Status)
+
+	for _, invalid := range []string{
+		`{"type": "bytecode"`,
+		`{"type": "bytecode", "bytecode": "zz"}`,
+		`{"type": "bytecode", "bytecode": "00", "backend": "missing"}`,
+		`{"type": "transaction", "tx_hash": "0x01"}`,
+		`{"type": "unknown"}`,
+	} {
+		status, body = request(http.MethodPost, "/jobs", invalid)
+		assert.Equal(t, http.StatusBadRequest, status, invalid)
+		assert.Contains(t, string(body), `"error"`)
+	}
+
+	status, body = request(http.MethodGet, "/jobs", "")
+	assert.Equal(t, http.StatusOK, status)
+	var jobs []Job
+	require.NoError(t, json.Unmarshal(body, &jobs))
+	require.Len(t, jobs, 1)
+	assert.Equal(t, job.ID, jobs[0].ID)
+
+	status, _ = request(http.MethodGet, "/jobs/"+job.ID, "")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = request(http.MethodGet, "/jobs/missing", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// Only the artifacts listed in the job are served
+	running, _ := store.next(context.Background())
+	require.NoError(t, os.WriteFile(filepath.Join(store.jobDir(job.ID), jobLogFile), []byte("Job succeeded\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(store.jobDir(job.ID), jobCyclesFile), []byte("{}"), 0644))
+	status, _ = request(http.MethodGet, "/jobs/"+job.ID+"/artifacts/"+jobCyclesFile, "")
+	assert.Equal(t, http.StatusNotFound, status)
+	store.finish(running.ID, jobResult{artifacts: []string{jobLogFile, jobCyclesFile}}, nil)
+
+	status, body = request(http.MethodGet, "/jobs/"+job.ID+"/artifacts/"+jobCyclesFile, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "{}", string(body))
+	status, body = request(http.MethodGet, "/jobs/"+job.ID+"/log", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Job succeeded\n", string(body))
+	status, _ = request(http.MethodGet, "/jobs/"+job.ID+"/artifacts/job.json", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// A finished job can't be canceled
+	status, _ = request(http.MethodDelete, "/jobs/"+job.ID, "")
+	assert.Equal(t, http.StatusConflict, status)
+	status, _ = request(http.MethodDelete, "/jobs/missing", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, body = request(http.MethodPost, "/jobs", `{"type": "bytecode", "bytecode": "00"}`)
+	require.Equal(t, http.StatusCreated, status)
+	require.NoError(t, json.Unmarshal(body, &job))
+	status, body = request(http.MethodDelete, "/jobs/"+job.ID, "")
+	assert.Equal(t, http.StatusOK, status)
+	require.NoError(t, json.Unmarshal(body, &job))
+	assert.Equal(t, JobCanceled, job.Status)
+}
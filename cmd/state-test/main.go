@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"erigon-transpiler-risc-v/statetest"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+)
+
+var args struct {
+	Paths   []string `arg:"positional,required" help:"GeneralStateTests JSON files or directories"`
+	Fork    string   `arg:"-f,--fork" help:"Only run the post entries of this fork (e.g. Cancun)"`
+	Output  string   `arg:"-o,--output" help:"Write the results as JSON to this file"`
+	Verbose bool     `arg:"-v,--verbose" help:"Print the passing and skipped entries too"`
+}
+
+type summary struct {
+	Pass            int                `json:"pass"`
+	Fail            int                `json:"fail"`
+	Skip            int                `json:"skip"`
+	PassPercentage  float64            `json:"pass_percentage"`
+	Results         []statetest.Result `json:"results"`
+	FilesWithErrors map[string]string  `json:"files_with_errors,omitempty"`
+}
+
+func main() {
+	arg.MustParse(&args)
+
+	files, err := fixtureFiles(args.Paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing fixtures: %v\n", err)
+		os.Exit(1)
+	}
+
+	result := summary{FilesWithErrors: make(map[string]string)}
+	for _, file := range files {
+		fixtures, err := statetest.Load(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", file, err)
+			result.FilesWithErrors[file] = err.Error()
+			continue
+		}
+
+		names := make([]string, 0, len(fixtures))
+		for name := range fixtures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			for _, testResult := range fixtures[name].Run(name, args.Fork) {
+				switch testResult.Status {
+				case statetest.Pass:
+					result.Pass++
+				case statetest.Fail:
+					result.Fail++
+				case statetest.Skip:
+					result.Skip++
+				}
+				if args.Verbose || testResult.Status == statetest.Fail {
+					fmt.Printf("%-4s %s %s[%d] %s\n", testResult.Status, testResult.Fork, testResult.Name, testResult.Index, testResult.Reason)
+				}
+				result.Results = append(result.Results, testResult)
+			}
+		}
+	}
+
+	// Skipped entries are not executed, so they don't count towards the conformance
+	if executed := result.Pass + result.Fail; executed > 0 {
+		result.PassPercentage = 100 * float64(result.Pass) / float64(executed)
+	}
+	fmt.Printf("Passed %d, failed %d, skipped %d (%.2f%% of the executed entries passed)\n",
+		result.Pass, result.Fail, result.Skip, result.PassPercentage)
+
+	if args.Output != "" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding results: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(args.Output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", args.Output, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Results written to: %s\n", args.Output)
+	}
+
+	if result.Fail > 0 {
+		os.Exit(1)
+	}
+}
+
+func fixtureFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(file, ".json") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+	"erigon-transpiler-risc-v/transpiler"
+
+	"github.com/alexflint/go-arg"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/holiman/uint256"
+)
+
+var args struct {
+	Bytecode string `arg:"-b,--bytecode" help:"Contract bytecode (hex string, with or without 0x prefix)"`
+	Calldata string `arg:"-c,--calldata" help:"Call data (hex string, with or without 0x prefix)"`
+	Value    uint64 `arg:"-v,--value" help:"Call value in wei"`
+	Trace    string `arg:"-t,--trace" help:"Trace file written with --dump-trace, instead of --bytecode"`
+	Tx       int    `arg:"--tx" help:"Index of the transaction in the trace file"`
+}
+
+const helpText = `Commands:
+  s, step [n]          execute the next n EVM opcodes (default 1)
+  c, continue          run until a breakpoint or the end
+  b, break op <OPCODE> break after an opcode (e.g. b op SSTORE)
+  b, break pc <PC>     break after the opcode at a contract pc (decimal or 0x hex)
+  b, break diverge     break when the EVM and RISC-V stacks differ
+  bl                   list the breakpoints
+  d, delete            remove all breakpoints
+  p, print             show the stacks after the current opcode
+  r, regs              show the RISC-V registers
+  h, help              show this help
+  q, quit              exit`
+
+type breakpoints struct {
+	opcodes map[string]bool
+	pcs     map[uint64]bool
+	diverge bool
+}
+
+type debugger struct {
+	instructions []*tracer.EvmInstructionMetadata
+	stepper      *prover.Stepper
+	// Index of the EVM opcode each EBREAK of the program follows, opcodes without a snapshot emit none
+	breaks []int
+	// Number of EBREAKs the emulator reached
+	reached int
+	// Index of the last executed EVM opcode, -1 before the first step
+	current     int
+	riscvStack  []uint256.Int
+	finished    bool
+	breakpoints breakpoints
+}
+
+func main() {
+	parser := arg.MustParse(&args)
+	if (args.Bytecode == "") == (args.Trace == "") {
+		parser.Fail("exactly one of --bytecode and --trace is required")
+	}
+
+	var instructions []*tracer.EvmInstructionMetadata
+	var state *tracer.EvmExecutionState
+	var err error
+	if args.Trace != "" {
+		instructions, state, err = readTrace(args.Trace, args.Tx)
+	} else {
+		instructions, state, err = traceBytecode(args.Bytecode, args.Calldata, uint256.NewInt(args.Value))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error tracing: %v\n", err)
+		os.Exit(1)
+	}
+
+	dbg, err := newDebugger(instructions, state)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error transpiling: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Traced %d EVM opcodes, type h for help\n", len(instructions))
+	dbg.repl(os.Stdin)
+}
+
+func decodeHex(value string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(value, "0x"))
+}
+
+// readTrace loads one transaction of a trace file written by tx-prove or block-prove.
+func readTrace(path string, txIndex int) ([]*tracer.EvmInstructionMetadata, *tracer.EvmExecutionState, error) {
+	transactions, err := tracer.ReadTraceFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if txIndex < 0 || txIndex >= len(transactions) {
+		return nil, nil, fmt.Errorf("transaction %d is out of range, %s has %d transactions", txIndex, path, len(transactions))
+	}
+	transaction := transactions[txIndex]
+	if len(transaction.Instructions) == 0 {
+		return nil, nil, fmt.Errorf("transaction %d executed no opcodes", txIndex)
+	}
+	return transaction.Instructions, transaction.State, nil
+}
+
+func traceBytecode(bytecodeHex, calldataHex string, value *uint256.Int) ([]*tracer.EvmInstructionMetadata, *tracer.EvmExecutionState, error) {
+	bytecode, err := decodeHex(bytecodeHex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid bytecode: %w", err)
+	}
+	calldata, err := decodeHex(calldataHex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid calldata: %w", err)
+	}
+
+	contractAddr := libcommon.HexToAddress(transpiler.CONTRACT_ADDRESS)
+	simpleTracer := tracer.NewSimpleTracer()
+	if err := simpleTracer.DeployContract(contractAddr, bytecode, uint256.NewInt(1000)); err != nil {
+		return nil, nil, err
+	}
+	// Reverts and exceptional halts are traced up to where they happen
+	instructions, state, _, _ := simpleTracer.ExecuteContract(contractAddr, calldata, 100000, value)
+	if len(instructions) == 0 {
+		return nil, nil, fmt.Errorf("no opcodes were executed")
+	}
+	return instructions, state, nil
+}
+
+func newDebugger(instructions []*tracer.EvmInstructionMetadata, state *tracer.EvmExecutionState) (*debugger, error) {
+	evmTranspiler := transpiler.NewTestTranspiler()
+	var breaks []int
+	for i := range instructions {
+		var resultStack *[]uint256.Int
+		if i+1 < len(instructions) {
+			resultStack = &instructions[i+1].StackSnapshot
+		}
+		mapped := len(evmTranspiler.GetDebugMappings())
+		if err := evmTranspiler.AddInstructionWithResult(instructions[i], state, resultStack); err != nil {
+			return nil, err
+		}
+		// A stop at depth 0 ends the transaction without an EBREAK, count the ones the opcode emitted
+		for _, mapping := range evmTranspiler.GetDebugMappings()[mapped:] {
+			for _, instruction := range mapping.RiscVInstructions {
+				if instruction.Name == prover.InstructionEBREAK {
+					breaks = append(breaks, i)
+				}
+			}
+		}
+	}
+	bytecode, err := evmTranspiler.ToAssembly().ToBytecode()
+	if err != nil {
+		return nil, err
+	}
+	stepper, err := prover.NewStepper(bytecode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &debugger{
+		instructions: instructions,
+		stepper:      stepper,
+		breaks:       breaks,
+		current:      -1,
+		breakpoints: breakpoints{
+			opcodes: make(map[string]bool),
+			pcs:     make(map[uint64]bool),
+		},
+	}, nil
+}
+
+func (d *debugger) repl(input io.Reader) {
+	scanner := bufio.NewScanner(input)
+	for {
+		fmt.Print("(step-debug) ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "s", "step":
+			count := 1
+			if len(fields) > 1 {
+				n, err := strconv.Atoi(fields[1])
+				if err != nil || n < 1 {
+					fmt.Printf("Invalid step count %q\n", fields[1])
+					continue
+				}
+				count = n
+			}
+			for i := 0; i < count; i++ {
+				if !d.step() {
+					break
+				}
+			}
+			d.print()
+		case "c", "continue":
+			for d.step() {
+				if d.hitBreakpoint() {
+					break
+				}
+			}
+			d.print()
+		case "b", "break":
+			d.addBreakpoint(fields[1:])
+		case "bl":
+			d.listBreakpoints()
+		case "d", "delete":
+			d.breakpoints = breakpoints{opcodes: make(map[string]bool), pcs: make(map[uint64]bool)}
+			fmt.Printf("Deleted all breakpoints\n")
+		case "p", "print":
+			d.print()
+		case "r", "regs":
+			d.printRegisters()
+		case "h", "help":
+			fmt.Println(helpText)
+		case "q", "quit":
+			return
+		default:
+			fmt.Printf("Unknown command %q, type h for help\n", fields[0])
+		}
+	}
+}
+
+// step executes the next EVM opcode on the emulator, false when the execution ended.
+func (d *debugger) step() bool {
+	if d.finished {
+		return false
+	}
+	// The last opcode of the trace has no snapshot
+	if d.reached >= len(d.breaks) || d.breaks[d.reached] >= len(d.instructions)-1 {
+		d.current = len(d.instructions) - 1
+		d.finished = true
+		return false
+	}
+
+	stack, err := d.stepper.Next()
+	if err != nil {
+		d.finished = true
+		if !errors.Is(err, io.EOF) {
+			fmt.Printf("RISC-V execution failed after EVM opcode %d: %v\n", d.current, err)
+		} else {
+			fmt.Printf("RISC-V execution ended after EVM opcode %d\n", d.current)
+		}
+		return false
+	}
+	d.current = d.breaks[d.reached]
+	d.reached++
+	d.riscvStack = stack
+	return true
+}
+
+func (d *debugger) hitBreakpoint() bool {
+	instruction := d.instructions[d.current]
+	if d.breakpoints.opcodes[instructionName(instruction)] {
+		fmt.Printf("Breakpoint: opcode %s\n", instructionName(instruction))
+		return true
+	}
+	if d.breakpoints.pcs[instruction.Pc] {
+		fmt.Printf("Breakpoint: pc %d\n", instruction.Pc)
+		return true
+	}
+	if d.breakpoints.diverge {
+		if divergence := transpiler.FirstStackDivergence([][]uint256.Int{d.evmStack()}, [][]uint256.Int{d.riscvStack}); divergence != nil {
+			fmt.Printf("Breakpoint: stacks diverge at slot %d\n", divergence.Slot)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *debugger) addBreakpoint(fields []string) {
+	switch {
+	case len(fields) == 2 && fields[0] == "op":
+		opcode := strings.ToUpper(fields[1])
+		d.breakpoints.opcodes[opcode] = true
+		fmt.Printf("Breakpoint after opcode %s\n", opcode)
+	case len(fields) == 2 && fields[0] == "pc":
+		pc, err := strconv.ParseUint(fields[1], 0, 64)
+		if err != nil {
+			fmt.Printf("Invalid pc %q\n", fields[1])
+			return
+		}
+		d.breakpoints.pcs[pc] = true
+		fmt.Printf("Breakpoint after pc %d\n", pc)
+	case len(fields) == 1 && fields[0] == "diverge":
+		d.breakpoints.diverge = true
+		fmt.Printf("Breakpoint on stack divergence\n")
+	default:
+		fmt.Printf("Usage: b op <OPCODE> | b pc <PC> | b diverge\n")
+	}
+}
+
+func (d *debugger) listBreakpoints() {
+	for opcode := range d.breakpoints.opcodes {
+		fmt.Printf("  op %s\n", opcode)
+	}
+	for pc := range d.breakpoints.pcs {
+		fmt.Printf("  pc %d\n", pc)
+	}
+	if d.breakpoints.diverge {
+		fmt.Printf("  diverge\n")
+	}
+}
+
+// evmStack is the EVM stack after the current opcode, the stack before the next one.
+func (d *debugger) evmStack() []uint256.Int {
+	if d.current+1 < len(d.instructions) {
+		return d.instructions[d.current+1].StackSnapshot
+	}
+	return nil
+}
+
+func (d *debugger) print() {
+	if d.current < 0 {
+		fmt.Printf("Not started, next opcode: %s\n", describe(d.instructions[0]))
+		return
+	}
+	fmt.Printf("[%d/%d] %s\n", d.current, len(d.instructions)-1, describe(d.instructions[d.current]))
+	if d.finished && d.current == len(d.instructions)-1 {
+		fmt.Printf("End of the trace\n")
+		return
+	}
+
+	evmStack := d.evmStack()
+	divergence := transpiler.FirstStackDivergence([][]uint256.Int{evmStack}, [][]uint256.Int{d.riscvStack})
+	fmt.Printf("  %-4s %-68s %s\n", "slot", "evm", "riscv")
+	for slot := 0; slot < max(len(evmStack), len(d.riscvStack)); slot++ {
+		marker := " "
+		if divergence != nil && slot == divergence.Slot {
+			marker = ">"
+		}
+		fmt.Printf("%s %-4d %-68s %s\n", marker, slot, stackSlot(evmStack, slot), stackSlot(d.riscvStack, slot))
+	}
+	if divergence != nil {
+		fmt.Printf("Stacks diverge at slot %d\n", divergence.Slot)
+	}
+}
+
+func (d *debugger) printRegisters() {
+	emu := d.stepper.Emulator()
+	fmt.Printf("  pc=0x%08x sp=0x%08x s1=0x%08x s2=0x%08x s3=0x%08x s4=0x%08x\n",
+		emu.PC, emu.Registers[prover.RegSP], emu.Registers[prover.RegS1], emu.Registers[prover.RegS2],
+		emu.Registers[prover.RegS3], emu.Registers[prover.RegS4])
+	fmt.Printf("  executed %d RISC-V instructions\n", emu.Steps)
+}
+
+func describe(instruction *tracer.EvmInstructionMetadata) string {
+	description := fmt.Sprintf("%s at %s pc %d", instructionName(instruction), instruction.Address.Hex(), instruction.Pc)
+	if len(instruction.Arguments) > 0 {
+		description += " 0x" + hex.EncodeToString(instruction.Arguments)
+	}
+	return description
+}
+
+func instructionName(instruction *tracer.EvmInstructionMetadata) string {
+	if instruction.IsStackRestore {
+		return "STACK_RESTORE"
+	}
+	return instruction.Opcode.String()
+}
+
+func stackSlot(stack []uint256.Int, slot int) string {
+	if slot >= len(stack) {
+		return "-"
+	}
+	return stack[len(stack)-1-slot].Hex()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+	"erigon-transpiler-risc-v/transpiler"
+
+	"github.com/alexflint/go-arg"
+)
+
+var args struct {
+	TraceFile     string `arg:"positional,required" help:"Trace file written by tx-prove or block-prove with --dump-trace"`
+	Output        string `arg:"-o,--output" default:"results.json" help:"Results file with the proof"`
+	AssemblyFile  string `arg:"--assembly-file" help:"Write the transpiled assembly to this file"`
+	DebugMappings string `arg:"--debug-mappings" help:"Write the debug mappings to this file, for debug-transpiler"`
+	SkipProof     bool   `arg:"--skip-proof" help:"Only transpile and count the executed instructions"`
+	Backend       string `arg:"--backend" default:"openvm" help:"zkVM backend to prove with (openvm, sp1, risc0)"`
+}
+
+func main() {
+	arg.MustParse(&args)
+
+	zkBackend, err := prover.NewBackend(args.Backend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	transactions, err := tracer.ReadTraceFile(args.TraceFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", args.TraceFile, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Replaying %d transactions from %s\n", len(transactions), args.TraceFile)
+
+	evmTranspiler := transpiler.NewTranspiler()
+	for i, transaction := range transactions {
+		fmt.Printf("Transpiling transaction %d/%d with %d instructions\n", i+1, len(transactions), len(transaction.Instructions))
+		if _, err := evmTranspiler.ProcessExecution(transaction.Instructions, transaction.State); err != nil {
+			fmt.Fprintf(os.Stderr, "Error transpiling transaction %d: %v\n", i+1, err)
+			os.Exit(1)
+		}
+		if i < len(transactions)-1 {
+			evmTranspiler.AddTransactionBoundary()
+		}
+	}
+	soundness := evmTranspiler.SoundnessReport()
+	fmt.Printf("Constrained opcodes: %.2f%%\n", soundness.ConstrainedPercentage)
+
+	if args.DebugMappings != "" {
+		if err := evmTranspiler.SaveDebugMappings(args.DebugMappings); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing debug mappings to %s: %v\n", args.DebugMappings, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Debug mappings written to: %s\n", args.DebugMappings)
+	}
+
+	assembly := evmTranspiler.ToAssembly()
+	if args.AssemblyFile != "" {
+		content, err := assembly.ToBackendAssembly(zkBackend)
+		if err == nil {
+			err = os.WriteFile(args.AssemblyFile, []byte(content), 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing assembly to %s: %v\n", args.AssemblyFile, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Transpiled assembly written to: %s\n", args.AssemblyFile)
+	}
+
+	if args.SkipProof {
+		cycles, err := assembly.CountCycles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error counting executed instructions: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Executed %d instructions, skipping proof generation\n", cycles.Total)
+		return
+	}
+
+	zkVm, err := prover.NewZkProverFromAssembly(assembly, zkBackend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error preparing prover: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	output, err := zkVm.Prove(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating proof: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Executed %d instructions\n", output.Cycles.Total)
+
+	results, err := json.MarshalIndent(prover.ResultsFile{
+		AppVK:     hex.EncodeToString(output.AppVK),
+		Proof:     hex.EncodeToString(output.Proof),
+		Backend:   zkBackend.Name(),
+		Soundness: soundness,
+	}, "", "  ")
+	if err == nil {
+		err = os.WriteFile(args.Output, results, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing results to %s: %v\n", args.Output, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Results written to: %s\n", args.Output)
+}
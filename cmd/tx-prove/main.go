@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
+	"erigon-transpiler-risc-v/erigonapi"
 	"erigon-transpiler-risc-v/prover"
 	"erigon-transpiler-risc-v/tracer"
 	"erigon-transpiler-risc-v/transpiler"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/erigontech/erigon-lib/common"
@@ -18,7 +21,6 @@ import (
 	"github.com/erigontech/erigon/cmd/rpcdaemon/cli"
 	"github.com/erigontech/erigon/eth/tracers"
 	"github.com/erigontech/erigon/eth/tracers/config"
-	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/rpc/jsonrpc"
 	"github.com/erigontech/erigon/turbo/debug"
 	"github.com/spf13/cobra"
@@ -34,15 +36,37 @@ func main() {
 	var debugMode bool
 	var skipProving bool
 	var assemblyFile string
+	var backendName string
+	var dumpTrace string
+	var rpcURL string
 	cmd.Flags().StringVar(&txHash, "tx-hash", "0x04d3d48f42983eb155be1ff4b66d5c5af8ed1cedecac055083a00f6e863603d2", "Transaction hash to trace (required)")
 	cmd.Flags().StringVar(&outputFile, "output", "", "Output file path (optional, defaults to stdout)")
 	cmd.Flags().BoolVar(&debugAssembly, "debug-assembly", false, "Write transpiled assembly to disk for debugging")
 	cmd.Flags().BoolVar(&debugMode, "debug-mode", false, "Enable debug transpiler with detailed mappings")
 	cmd.Flags().StringVar(&assemblyFile, "assembly-file", "transpiled.s", "Assembly output file path (used with --debug-assembly)")
 	cmd.Flags().BoolVar(&skipProving, "skip-proving", false, "Skip proof generation")
+	cmd.Flags().StringVar(&backendName, "backend", "openvm", "zkVM backend to prove with ("+strings.Join(prover.BackendNames(), ", ")+")")
+	cmd.Flags().StringVar(&dumpTrace, "dump-trace", "", "Write the EVM trace to this file for trace-replay (JSON when it ends in .json)")
+	cmd.Flags().StringVar(&rpcURL, "rpc-url", "", "Trace with debug_traceTransaction on this http(s):// or ws(s):// JSON-RPC node instead of the Erigon database")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
+		zkBackend, err := prover.NewBackend(backendName)
+		if err != nil {
+			return err
+		}
+		opts := proveOptions{
+			debugAssembly: debugAssembly,
+			debugMode:     debugMode,
+			skipProving:   skipProving,
+			assemblyFile:  assemblyFile,
+			dumpTrace:     dumpTrace,
+			zkBackend:     zkBackend,
+		}
+		if rpcURL != "" {
+			return proveFromRPC(ctx, rpcURL, libcommon.HexToHash(txHash), outputFile, opts)
+		}
+
 		logger := debug.SetupCobra(cmd, "rpcdaemon")
 		logger.Enabled(ctx, log.LvlCrit)
 		db, backend, txPool, mining, stateCache, blockReader, engine, ff, bridgeReader, heimdallReader, err := cli.RemoteServices(ctx, cfg, logger, rootCancel)
@@ -62,64 +86,14 @@ func main() {
 		apiList := jsonrpc.APIList(db, backend, txPool, mining, ff, stateCache, blockReader, cfg, engine, logger, bridgeReader, heimdallReader)
 		var buf bytes.Buffer
 		stream := jsonstream.New(&buf)
-		debugAPI := findDebug(apiList)
+		debugAPI := erigonapi.FindDebug(apiList)
 
 		ranTracer := false
 		customTracer := tracer.NewTracerHooks(
 			func(newTracer *tracer.StateTracer) (*prover.ResultsFile, error) {
 				ranTracer = true
 				fmt.Println("hello")
-				transpiler := transpiler.NewTranspiler()
-				instructions := newTracer.GetInstructions()
-				executionState := newTracer.GetExecutionState()
-				_, err := transpiler.ProcessExecution(instructions, executionState)
-				if err != nil {
-					return nil, err
-				}
-				assembly := transpiler.ToAssembly()
-				content, err := assembly.ToToolChainCompatibleAssembly()
-				if err != nil {
-					return nil, err
-				}
-
-				if debugMode {
-					debugFile := "debug_mappings.json"
-					err = transpiler.SaveDebugMappings(debugFile)
-					if err != nil {
-						fmt.Printf("Warning: Failed to write debug mappings to %s: %v\n", debugFile, err)
-					} else {
-						fmt.Printf("Debug mappings written to: %s\n", debugFile)
-					}
-				}
-
-				if debugAssembly {
-					err := os.WriteFile(assemblyFile, []byte(content), 0644)
-					if err != nil {
-						fmt.Printf("Warning: Failed to write assembly to %s: %v\n", assemblyFile, err)
-					} else {
-						fmt.Printf("Transpiled assembly written to: %s\n", assemblyFile)
-					}
-				}
-				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-				defer cancel()
-
-				if skipProving {
-					fmt.Println("Skipping proving as per --skip-proving flag.")
-					return &prover.ResultsFile{
-						AppVK: "skipped",
-						Proof: "skipped",
-					}, nil
-				}
-				zkVm := prover.NewZkProver(content)
-				output, err := zkVm.Prove(ctx)
-				if err != nil {
-					return nil, err
-				}
-
-				return &prover.ResultsFile{
-					AppVK: hex.EncodeToString(output.AppVK),
-					Proof: hex.EncodeToString(output.Proof),
-				}, nil
+				return proveTrace(newTracer.GetInstructions(), newTracer.GetExecutionState(), opts)
 			},
 		)
 
@@ -145,17 +119,8 @@ func main() {
 			os.Exit(1)
 		}
 
-		// Output results to file or stdout
-		results := buf.String()
-		if outputFile != "" {
-			err := os.WriteFile(outputFile, []byte(results), 0644)
-			if err != nil {
-				fmt.Printf("Error writing to file %s: %v\n", outputFile, err)
-				return err
-			}
-			fmt.Printf("Results written to: %s\n", outputFile)
-		} else {
-			fmt.Println("Results: ", results)
+		if err := writeResults(outputFile, buf.String()); err != nil {
+			return err
 		}
 		os.Exit(0)
 
@@ -168,13 +133,124 @@ func main() {
 	}
 }
 
-func findDebug(apiList []rpc.API) *jsonrpc.DebugAPIImpl {
-	for _, api := range apiList {
-		if api.Namespace == "debug" {
-			if ethAPI, ok := api.Service.(*jsonrpc.DebugAPIImpl); ok {
-				return ethAPI
-			}
+// writeResults outputs the results to the file, or to stdout when no file is given.
+func writeResults(outputFile string, results string) error {
+	if outputFile != "" {
+		err := os.WriteFile(outputFile, []byte(results), 0644)
+		if err != nil {
+			fmt.Printf("Error writing to file %s: %v\n", outputFile, err)
+			return err
 		}
+		fmt.Printf("Results written to: %s\n", outputFile)
+	} else {
+		fmt.Println("Results: ", results)
 	}
 	return nil
 }
+
+// proveFromRPC traces the transaction on a JSON-RPC node, no Erigon database is needed.
+func proveFromRPC(ctx context.Context, rpcURL string, txHash libcommon.Hash, outputFile string, opts proveOptions) error {
+	client, err := tracer.DialRPC(ctx, rpcURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	instructions, executionState, err := tracer.NewRPCTracer(client).TraceTransaction(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to trace %s: %w", txHash.Hex(), err)
+	}
+	if len(instructions) == 0 {
+		return fmt.Errorf("transaction %s executed no opcodes", txHash.Hex())
+	}
+	fmt.Printf("Traced %d instructions from %s\n", len(instructions), rpcURL)
+
+	results, err := proveTrace(instructions, executionState, opts)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return writeResults(outputFile, string(data))
+}
+
+type proveOptions struct {
+	debugAssembly bool
+	debugMode     bool
+	skipProving   bool
+	assemblyFile  string
+	dumpTrace     string
+	zkBackend     prover.Backend
+}
+
+// proveTrace transpiles and proves the trace of a transaction.
+func proveTrace(instructions []*tracer.EvmInstructionMetadata, executionState *tracer.EvmExecutionState, opts proveOptions) (*prover.ResultsFile, error) {
+	transpiler := transpiler.NewTranspiler()
+	if opts.dumpTrace != "" {
+		err := tracer.WriteTraceFile(opts.dumpTrace, []tracer.TracedTransaction{{Instructions: instructions, State: executionState}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write trace to %s: %w", opts.dumpTrace, err)
+		}
+		fmt.Printf("Trace written to: %s\n", opts.dumpTrace)
+	}
+	_, err := transpiler.ProcessExecution(instructions, executionState)
+	if err != nil {
+		return nil, err
+	}
+	assembly := transpiler.ToAssembly()
+	content, err := assembly.ToBackendAssembly(opts.zkBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.debugMode {
+		debugFile := "debug_mappings.json"
+		err = transpiler.SaveDebugMappings(debugFile)
+		if err != nil {
+			fmt.Printf("Warning: Failed to write debug mappings to %s: %v\n", debugFile, err)
+		} else {
+			fmt.Printf("Debug mappings written to: %s\n", debugFile)
+		}
+	}
+
+	if opts.debugAssembly {
+		err := os.WriteFile(opts.assemblyFile, []byte(content), 0644)
+		if err != nil {
+			fmt.Printf("Warning: Failed to write assembly to %s: %v\n", opts.assemblyFile, err)
+		} else {
+			fmt.Printf("Transpiled assembly written to: %s\n", opts.assemblyFile)
+		}
+	}
+	soundness := transpiler.SoundnessReport()
+	fmt.Printf("Constrained opcodes: %.2f%%\n", soundness.ConstrainedPercentage)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if opts.skipProving {
+		fmt.Println("Skipping proving as per --skip-proving flag.")
+		return &prover.ResultsFile{
+			AppVK:     "skipped",
+			Proof:     "skipped",
+			Soundness: soundness,
+		}, nil
+	}
+	zkVm, err := prover.NewZkProverFromAssembly(assembly, opts.zkBackend)
+	if err != nil {
+		return nil, err
+	}
+	output, err := zkVm.Prove(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Executed %d instructions\n", output.Cycles.Total)
+
+	return &prover.ResultsFile{
+		AppVK:     hex.EncodeToString(output.AppVK),
+		Proof:     hex.EncodeToString(output.Proof),
+		Backend:   opts.zkBackend.Name(),
+		Soundness: soundness,
+	}, nil
+}
@@ -0,0 +1,76 @@
+// Package erigonapi traces transactions through the RPC APIs of an Erigon database.
+package erigonapi
+
+import (
+	"bytes"
+	"context"
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/jsonstream"
+	"github.com/erigontech/erigon/eth/tracers"
+	"github.com/erigontech/erigon/eth/tracers/config"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/rpc/jsonrpc"
+)
+
+func FindEth(apiList []rpc.API) jsonrpc.EthAPI {
+	for _, api := range apiList {
+		if api.Namespace == "eth" {
+			if ethAPI, ok := api.Service.(jsonrpc.EthAPI); ok {
+				return ethAPI
+			}
+		}
+	}
+	return nil
+}
+
+func FindDebug(apiList []rpc.API) *jsonrpc.DebugAPIImpl {
+	for _, api := range apiList {
+		if api.Namespace == "debug" {
+			if debugAPI, ok := api.Service.(*jsonrpc.DebugAPIImpl); ok {
+				return debugAPI
+			}
+		}
+	}
+	return nil
+}
+
+// TraceTransaction replays the transaction with the state tracer and returns its instructions and execution state.
+func TraceTransaction(ctx context.Context, debugAPI *jsonrpc.DebugAPIImpl, txHash common.Hash) ([]*tracer.EvmInstructionMetadata, *tracer.EvmExecutionState, error) {
+	var tracerResult *tracer.StateTracer
+
+	customTracer := tracer.NewTracerHooks(
+		func(newTracer *tracer.StateTracer) (*prover.ResultsFile, error) {
+			tracerResult = newTracer
+			return &prover.ResultsFile{}, nil
+		},
+	)
+	tracers.RegisterLookup(false, customTracer)
+
+	var buf bytes.Buffer
+	stream := jsonstream.New(&buf)
+
+	tracerName := "Mine"
+	timeout := "10m"
+	err := debugAPI.TraceTransaction(
+		ctx,
+		txHash,
+		&config.TraceConfig{
+			Tracer:  &tracerName,
+			Timeout: &timeout,
+		},
+		stream,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tracerResult == nil {
+		return nil, nil, fmt.Errorf("no tracer result received")
+	}
+
+	return tracerResult.GetInstructions(), tracerResult.GetExecutionState(), nil
+}
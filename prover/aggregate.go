@@ -0,0 +1,162 @@
+package prover
+
+import (
+	"context"
+	"embed"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//go:embed openvm_aggregate/*
+var aggregationToolchain embed.FS
+
+const (
+	aggregateHostManifest = "host/Cargo.toml"
+	aggregateInputFile    = "proofs.json"
+	aggregateProofFile    = "aggregate.stark.proof"
+	aggregatePublicValues = "public_values.bin"
+	aggregateCommitFile   = "aggregate_commit.json"
+	// Bytes of the aggregate public values per transaction: hash, app exe commit, app vm commit, public values
+	aggregateBytesPerTransaction = 128
+)
+
+// TransactionProof is the OpenVM STARK proof of one transaction, as written by `cargo openvm prove stark`.
+type TransactionProof struct {
+	TxHash string
+	Proof  []byte
+}
+
+// AggregatedTransaction is the entry of a transaction in the aggregate public values.
+// The hash is not bound by the proof, it labels the program committed to by AppExeCommit.
+type AggregatedTransaction struct {
+	TxHash       string `json:"tx_hash"`
+	AppExeCommit string `json:"app_exe_commit"`
+	AppVmCommit  string `json:"app_vm_commit"`
+	PublicValues string `json:"public_values"`
+}
+
+type AggregateProof struct {
+	// STARK proof of the aggregation program, verified with the aggregation vk like any OpenVM STARK proof
+	Proof        []byte
+	VerifyingKey []byte
+	// The vk accepts the proof of any program, a verifier has to pin these commits of the aggregation program
+	AppExeCommit string
+	AppVmCommit  string
+	PublicValues []byte
+	Transactions []AggregatedTransaction
+	Stdout       string
+	TimeMs       int64
+}
+
+// AggregateProofs folds the transaction STARK proofs into one block STARK. The aggregation program verifies each
+// proof with OpenVM's recursive verifier and reveals the transaction hashes, app exe commits and public values.
+// The proofs have to be made with the aggregation keys of `cargo openvm setup`.
+func AggregateProofs(ctx context.Context, proofs []TransactionProof) (AggregateProof, error) {
+	if len(proofs) == 0 {
+		return AggregateProof{}, fmt.Errorf("no transaction proofs to aggregate")
+	}
+	start := time.Now()
+
+	type inputEntry struct {
+		TxHash string `json:"tx_hash"`
+		Proof  string `json:"proof"`
+	}
+	input := make([]inputEntry, len(proofs))
+	for i, proof := range proofs {
+		input[i] = inputEntry{TxHash: proof.TxHash, Proof: fmt.Sprintf("tx_%d.stark.proof", i)}
+	}
+	inputData, err := json.Marshal(input)
+	if err != nil {
+		return AggregateProof{}, err
+	}
+
+	workSpace, err := setupWorkspace(aggregationToolchain, "openvm_aggregate", aggregateInputFile, inputData)
+	if err != nil {
+		return AggregateProof{}, NewZkProverError("failed to setup workspace", err)
+	}
+	defer os.RemoveAll(filepath.Dir(workSpace))
+	for i, proof := range proofs {
+		if err := os.WriteFile(filepath.Join(workSpace, input[i].Proof), proof.Proof, 0644); err != nil {
+			return AggregateProof{}, NewZkProverError("failed to write transaction proof", err)
+		}
+	}
+
+	cli := NewCli(workSpace)
+	output, err := cli.Execute(ctx, "cargo", "run", "--release", "--manifest-path", aggregateHostManifest, "--",
+		"--input", aggregateInputFile, "--proof", aggregateProofFile, "--public-values", aggregatePublicValues,
+		"--commit", aggregateCommitFile)
+	if err != nil {
+		return AggregateProof{}, NewZkProverError("failed to aggregate proofs", err)
+	}
+
+	proof, err := cli.readFile(aggregateProofFile)
+	if err != nil {
+		return AggregateProof{}, err
+	}
+	publicValues, err := cli.readFile(aggregatePublicValues)
+	if err != nil {
+		return AggregateProof{}, err
+	}
+	transactions, err := ParseAggregatePublicValues(publicValues)
+	if err != nil {
+		return AggregateProof{}, err
+	}
+	commitData, err := cli.readFile(aggregateCommitFile)
+	if err != nil {
+		return AggregateProof{}, err
+	}
+	var commit struct {
+		AppExeCommit string `json:"app_exe_commit"`
+		AppVmCommit  string `json:"app_vm_commit"`
+	}
+	if err := json.Unmarshal(commitData, &commit); err != nil {
+		return AggregateProof{}, fmt.Errorf("failed to parse %s: %w", aggregateCommitFile, err)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return AggregateProof{}, err
+	}
+	aggVk, err := os.ReadFile(filepath.Join(home, ".openvm", "agg_stark.vk"))
+	if err != nil {
+		return AggregateProof{}, err
+	}
+
+	return AggregateProof{
+		Proof:        proof,
+		VerifyingKey: aggVk,
+		AppExeCommit: commit.AppExeCommit,
+		AppVmCommit:  commit.AppVmCommit,
+		PublicValues: publicValues,
+		Transactions: transactions,
+		Stdout:       output,
+		TimeMs:       time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// ParseAggregatePublicValues decodes the little-endian transaction count and the 128 byte entries after it.
+func ParseAggregatePublicValues(publicValues []byte) ([]AggregatedTransaction, error) {
+	if len(publicValues) < 4 {
+		return nil, fmt.Errorf("aggregate public values too short: %d bytes", len(publicValues))
+	}
+	count := int(binary.LittleEndian.Uint32(publicValues))
+	if len(publicValues) < 4+count*aggregateBytesPerTransaction {
+		return nil, fmt.Errorf("aggregate public values of %d bytes can't hold %d transactions", len(publicValues), count)
+	}
+
+	transactions := make([]AggregatedTransaction, count)
+	for i := range transactions {
+		entry := publicValues[4+i*aggregateBytesPerTransaction:]
+		transactions[i] = AggregatedTransaction{
+			TxHash:       "0x" + hex.EncodeToString(entry[:32]),
+			AppExeCommit: "0x" + hex.EncodeToString(entry[32:64]),
+			AppVmCommit:  "0x" + hex.EncodeToString(entry[64:96]),
+			PublicValues: "0x" + hex.EncodeToString(entry[96:128]),
+		}
+	}
+	return transactions, nil
+}
@@ -0,0 +1,852 @@
+package prover
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ElfLayout is where the sections are placed by the assembler.
+type ElfLayout struct {
+	TextAddr uint32
+	// Zero places the data right after the text
+	DataAddr uint32
+	Entry    string
+}
+
+// ErrUndefinedSymbol is returned when a label is referenced but never defined.
+var ErrUndefinedSymbol = errors.New("undefined symbol")
+
+// The data follows the text, so the program size isn't limited, the runners map memory up to its end.
+var DefaultElfLayout = ElfLayout{
+	TextAddr: 0x1000,
+	Entry:    "execute",
+}
+
+type asmSection int
+
+const (
+	sectionText asmSection = iota
+	sectionData
+)
+
+var sectionNames = [...]string{".text", ".data"}
+
+type asmItemKind int
+
+const (
+	itemInstruction asmItemKind = iota
+	itemBytes
+	itemWords
+)
+
+type asmItem struct {
+	kind     asmItemKind
+	section  asmSection
+	offset   uint32
+	size     uint32
+	line     int
+	mnemonic string
+	operands []string
+	data     []byte
+}
+
+type asmLabel struct {
+	section asmSection
+	offset  uint32
+}
+
+// AssembledProgram is the output of Assemble, the sections are placed at the layout addresses.
+type AssembledProgram struct {
+	Text     []byte
+	Data     []byte
+	TextAddr uint32
+	DataAddr uint32
+	Entry    uint32
+	Symbols  []ElfSymbol
+}
+
+type ElfSymbol struct {
+	Name    string
+	Value   uint32
+	Section string
+	Global  bool
+}
+
+type assembler struct {
+	items   []asmItem
+	labels  map[string]asmLabel
+	globals map[string]bool
+	sizes   [2]uint32
+	current asmSection
+}
+
+// Assemble encodes rv32im assembly (the subset of GNU as syntax used by the transpiler and lib.asm).
+func Assemble(source string, layout ElfLayout) (*AssembledProgram, error) {
+	asm := &assembler{
+		labels:  make(map[string]asmLabel),
+		globals: make(map[string]bool),
+	}
+	if err := asm.parse(source); err != nil {
+		return nil, err
+	}
+	return asm.encode(layout)
+}
+
+// AssembleElf assembles the source into a static rv32im ELF.
+func AssembleElf(source string, layout ElfLayout) ([]byte, error) {
+	program, err := Assemble(source, layout)
+	if err != nil {
+		return nil, err
+	}
+	return program.Elf(), nil
+}
+
+func (a *assembler) parse(source string) error {
+	lines := strings.Split(stripBlockComments(source), "\n")
+	for i, line := range lines {
+		lineNumber := i + 1
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+
+		// Labels, possibly followed by a statement
+		for {
+			idx := strings.Index(line, ":")
+			if idx < 0 || strings.ContainsAny(line[:idx], " \t,(") {
+				break
+			}
+			name := line[:idx]
+			if _, ok := a.labels[name]; ok {
+				return fmt.Errorf("line %d: label %s defined twice", lineNumber, name)
+			}
+			a.labels[name] = asmLabel{section: a.current, offset: a.sizes[a.current]}
+			line = strings.TrimSpace(line[idx+1:])
+		}
+		if line == "" {
+			continue
+		}
+
+		mnemonic, rest := line, ""
+		if idx := strings.IndexAny(line, " \t"); idx >= 0 {
+			mnemonic, rest = line[:idx], line[idx+1:]
+		}
+		mnemonic = strings.ToLower(mnemonic)
+		operands := splitOperands(rest)
+
+		var err error
+		if strings.HasPrefix(mnemonic, ".") {
+			err = a.directive(mnemonic, operands, lineNumber)
+		} else {
+			err = a.instruction(mnemonic, operands, lineNumber)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	return nil
+}
+
+func stripBlockComments(source string) string {
+	var builder strings.Builder
+	for {
+		start := strings.Index(source, "/*")
+		if start < 0 {
+			builder.WriteString(source)
+			return builder.String()
+		}
+		builder.WriteString(source[:start])
+		end := strings.Index(source[start+2:], "*/")
+		if end < 0 {
+			return builder.String()
+		}
+		// Keep the line numbers
+		builder.WriteString(strings.Repeat("\n", strings.Count(source[start:start+2+end], "\n")))
+		source = source[start+2+end+2:]
+	}
+}
+
+func splitOperands(rest string) []string {
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return nil
+	}
+	operands := strings.Split(rest, ",")
+	for i := range operands {
+		operands[i] = strings.TrimSpace(operands[i])
+	}
+	return operands
+}
+
+func (a *assembler) add(item asmItem) {
+	item.section = a.current
+	item.offset = a.sizes[a.current]
+	a.items = append(a.items, item)
+	a.sizes[a.current] += item.size
+}
+
+func (a *assembler) directive(name string, operands []string, line int) error {
+	switch name {
+	case ".section":
+		if len(operands) == 0 {
+			return fmt.Errorf(".section without a name")
+		}
+		return a.switchSection(operands[0])
+	case ".text", ".data":
+		return a.switchSection(name)
+	case ".global", ".globl":
+		for _, operand := range operands {
+			a.globals[operand] = true
+		}
+	case ".word":
+		// Words can reference labels, they are resolved when encoding
+		a.add(asmItem{kind: itemWords, size: uint32(4 * len(operands)), line: line, operands: operands})
+	case ".half", ".byte":
+		data := make([]byte, 0, 2*len(operands))
+		for _, operand := range operands {
+			value, err := parseImmediate(operand)
+			if err != nil {
+				return err
+			}
+			if name == ".half" {
+				data = binary.LittleEndian.AppendUint16(data, uint16(value))
+			} else {
+				data = append(data, byte(value))
+			}
+		}
+		a.add(asmItem{kind: itemBytes, size: uint32(len(data)), line: line, data: data})
+	case ".space", ".zero":
+		if len(operands) == 0 {
+			return fmt.Errorf("%s without a size", name)
+		}
+		size, err := parseImmediate(operands[0])
+		if err != nil {
+			return err
+		}
+		if size < 0 {
+			return fmt.Errorf("negative %s size %d", name, size)
+		}
+		a.add(asmItem{kind: itemBytes, size: uint32(size), line: line, data: make([]byte, size)})
+	case ".align", ".p2align", ".balign":
+		if len(operands) == 0 {
+			return fmt.Errorf("%s without an alignment", name)
+		}
+		value, err := parseImmediate(operands[0])
+		if err != nil {
+			return err
+		}
+		alignment := uint32(value)
+		if name != ".balign" {
+			// RISC-V .align is a power of two like .p2align
+			alignment = 1 << value
+		}
+		if alignment == 0 {
+			return nil
+		}
+		padding := (alignment - a.sizes[a.current]%alignment) % alignment
+		data := make([]byte, padding)
+		if a.current == sectionText {
+			for i := 0; i+4 <= len(data); i += 4 {
+				binary.LittleEndian.PutUint32(data[i:], encodeI(0, 0, 0, 0, 0x13))
+			}
+		}
+		a.add(asmItem{kind: itemBytes, size: padding, line: line, data: data})
+	default:
+		return fmt.Errorf("unsupported directive %s", name)
+	}
+	return nil
+}
+
+func (a *assembler) switchSection(name string) error {
+	switch name {
+	case ".text":
+		a.current = sectionText
+	case ".data":
+		a.current = sectionData
+	default:
+		return fmt.Errorf("unsupported section %s", name)
+	}
+	return nil
+}
+
+func (a *assembler) instruction(mnemonic string, operands []string, line int) error {
+	if a.current != sectionText {
+		return fmt.Errorf("instruction %s outside of .text", mnemonic)
+	}
+	size := uint32(4)
+	switch mnemonic {
+	case "la", "lla", "call", "tail":
+		size = 8
+	case "li":
+		if len(operands) != 2 {
+			return fmt.Errorf("li expects 2 operands")
+		}
+		value, err := parseWordImmediate(operands[1])
+		if err != nil {
+			return err
+		}
+		size = uint32(4 * len(loadImmediate(0, value)))
+	}
+	a.add(asmItem{kind: itemInstruction, size: size, line: line, mnemonic: mnemonic, operands: operands})
+	return nil
+}
+
+func (a *assembler) encode(layout ElfLayout) (*AssembledProgram, error) {
+	textAddr := layout.TextAddr
+	dataAddr := layout.DataAddr
+	textEnd := uint64(textAddr) + uint64(a.sizes[sectionText])
+	if dataAddr == 0 {
+		dataAddr = uint32((textEnd + 0xf) &^ 0xf)
+	} else if textEnd > uint64(dataAddr) && uint64(textAddr) < uint64(dataAddr)+uint64(a.sizes[sectionData]) {
+		return nil, fmt.Errorf("text section (0x%x-0x%x) overlaps the data section at 0x%x", textAddr, textEnd, dataAddr)
+	}
+	bases := [2]uint32{textAddr, dataAddr}
+	sections := [2][]byte{
+		make([]byte, a.sizes[sectionText]),
+		make([]byte, a.sizes[sectionData]),
+	}
+
+	resolve := func(name string) (uint32, error) {
+		label, ok := a.labels[name]
+		if !ok {
+			return 0, fmt.Errorf("%w %s", ErrUndefinedSymbol, name)
+		}
+		return bases[label.section] + label.offset, nil
+	}
+
+	for _, item := range a.items {
+		out := sections[item.section][item.offset : item.offset+item.size]
+		switch item.kind {
+		case itemBytes:
+			copy(out, item.data)
+		case itemWords:
+			for i, operand := range item.operands {
+				value, err := parseWordImmediate(operand)
+				if err != nil {
+					address, resolveErr := resolve(operand)
+					if resolveErr != nil {
+						return nil, fmt.Errorf("line %d: %w", item.line, resolveErr)
+					}
+					value = address
+				}
+				binary.LittleEndian.PutUint32(out[i*4:], value)
+			}
+		case itemInstruction:
+			pc := bases[item.section] + item.offset
+			words, err := encodeInstruction(item.mnemonic, item.operands, pc, resolve)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s %s: %w", item.line, item.mnemonic, strings.Join(item.operands, ", "), err)
+			}
+			if uint32(len(words)*4) != item.size {
+				return nil, fmt.Errorf("line %d: %s encoded to %d bytes, expected %d", item.line, item.mnemonic, len(words)*4, item.size)
+			}
+			for i, word := range words {
+				binary.LittleEndian.PutUint32(out[i*4:], word)
+			}
+		}
+	}
+
+	entry, err := resolve(layout.Entry)
+	if err != nil {
+		return nil, fmt.Errorf("entry point: %w", err)
+	}
+
+	symbols := make([]ElfSymbol, 0, len(a.labels))
+	for name, label := range a.labels {
+		symbols = append(symbols, ElfSymbol{
+			Name:    name,
+			Value:   bases[label.section] + label.offset,
+			Section: sectionNames[label.section],
+			Global:  a.globals[name],
+		})
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Value != symbols[j].Value {
+			return symbols[i].Value < symbols[j].Value
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+
+	return &AssembledProgram{
+		Text:     sections[sectionText],
+		Data:     sections[sectionData],
+		TextAddr: textAddr,
+		DataAddr: dataAddr,
+		Entry:    entry,
+		Symbols:  symbols,
+	}, nil
+}
+
+var registerNames = map[string]uint32{
+	"zero": 0, "ra": 1, "sp": 2, "gp": 3, "tp": 4,
+	"t0": 5, "t1": 6, "t2": 7, "s0": 8, "fp": 8, "s1": 9,
+	"a0": 10, "a1": 11, "a2": 12, "a3": 13, "a4": 14, "a5": 15, "a6": 16, "a7": 17,
+	"s2": 18, "s3": 19, "s4": 20, "s5": 21, "s6": 22, "s7": 23, "s8": 24, "s9": 25, "s10": 26, "s11": 27,
+	"t3": 28, "t4": 29, "t5": 30, "t6": 31,
+}
+
+func parseRegister(name string) (uint32, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if reg, ok := registerNames[name]; ok {
+		return reg, nil
+	}
+	if strings.HasPrefix(name, "x") {
+		if reg, err := strconv.ParseUint(name[1:], 10, 5); err == nil {
+			return uint32(reg), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown register %q", name)
+}
+
+func parseImmediate(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	parsed, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid immediate %q", value)
+	}
+	return parsed, nil
+}
+
+// parseWordImmediate accepts both signed and unsigned 32-bit values, like GNU as.
+func parseWordImmediate(value string) (uint32, error) {
+	parsed, err := parseImmediate(value)
+	if err != nil {
+		return 0, err
+	}
+	if parsed < -(1<<31) || parsed > (1<<32)-1 {
+		return 0, fmt.Errorf("immediate %d does not fit in 32 bits", parsed)
+	}
+	return uint32(parsed), nil
+}
+
+// parseMemoryOperand parses offset(register).
+func parseMemoryOperand(operand string) (int64, uint32, error) {
+	open := strings.Index(operand, "(")
+	if open < 0 || !strings.HasSuffix(operand, ")") {
+		return 0, 0, fmt.Errorf("invalid memory operand %q", operand)
+	}
+	offset := int64(0)
+	if offsetText := strings.TrimSpace(operand[:open]); offsetText != "" {
+		var err error
+		offset, err = parseImmediate(offsetText)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	reg, err := parseRegister(operand[open+1 : len(operand)-1])
+	return offset, reg, err
+}
+
+type rType struct{ funct7, funct3 uint32 }
+
+var rTypeInstructions = map[string]rType{
+	"add": {0x00, 0}, "sub": {0x20, 0}, "sll": {0x00, 1}, "slt": {0x00, 2},
+	"sltu": {0x00, 3}, "xor": {0x00, 4}, "srl": {0x00, 5}, "sra": {0x20, 5},
+	"or": {0x00, 6}, "and": {0x00, 7},
+	"mul": {0x01, 0}, "mulh": {0x01, 1}, "mulhsu": {0x01, 2}, "mulhu": {0x01, 3},
+	"div": {0x01, 4}, "divu": {0x01, 5}, "rem": {0x01, 6}, "remu": {0x01, 7},
+}
+
+var iTypeInstructions = map[string]uint32{
+	"addi": 0, "slti": 2, "sltiu": 3, "xori": 4, "ori": 6, "andi": 7,
+}
+
+var shiftInstructions = map[string]rType{
+	"slli": {0x00, 1}, "srli": {0x00, 5}, "srai": {0x20, 5},
+}
+
+var loadInstructions = map[string]uint32{
+	"lb": 0, "lh": 1, "lw": 2, "lbu": 4, "lhu": 5,
+}
+
+var storeInstructions = map[string]uint32{
+	"sb": 0, "sh": 1, "sw": 2,
+}
+
+var branchInstructions = map[string]uint32{
+	"beq": 0, "bne": 1, "blt": 4, "bge": 5, "bltu": 6, "bgeu": 7,
+}
+
+// Branch pseudo instructions, the operands are swapped
+var swappedBranches = map[string]string{
+	"bgt": "blt", "ble": "bge", "bgtu": "bltu", "bleu": "bgeu",
+}
+
+// Branch pseudo instructions comparing against zero, zeroFirst puts zero as rs1
+var zeroBranches = map[string]struct {
+	branch    string
+	zeroFirst bool
+}{
+	"beqz": {"beq", false}, "bnez": {"bne", false}, "bltz": {"blt", false},
+	"bgez": {"bge", false}, "blez": {"bge", true}, "bgtz": {"blt", true},
+}
+
+func expectOperands(operands []string, count int) error {
+	if len(operands) != count {
+		return fmt.Errorf("expected %d operands, got %d", count, len(operands))
+	}
+	return nil
+}
+
+func parseRegisters(operands []string) ([]uint32, error) {
+	registers := make([]uint32, len(operands))
+	for i, operand := range operands {
+		reg, err := parseRegister(operand)
+		if err != nil {
+			return nil, err
+		}
+		registers[i] = reg
+	}
+	return registers, nil
+}
+
+func encodeInstruction(mnemonic string, operands []string, pc uint32, resolve func(string) (uint32, error)) ([]uint32, error) {
+	if op, ok := rTypeInstructions[mnemonic]; ok {
+		if err := expectOperands(operands, 3); err != nil {
+			return nil, err
+		}
+		regs, err := parseRegisters(operands)
+		if err != nil {
+			return nil, err
+		}
+		return []uint32{encodeR(op.funct7, regs[2], regs[1], op.funct3, regs[0], 0x33)}, nil
+	}
+	if funct3, ok := iTypeInstructions[mnemonic]; ok {
+		if err := expectOperands(operands, 3); err != nil {
+			return nil, err
+		}
+		regs, err := parseRegisters(operands[:2])
+		if err != nil {
+			return nil, err
+		}
+		imm, err := parseImmediate(operands[2])
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSigned(imm, 12); err != nil {
+			return nil, err
+		}
+		return []uint32{encodeI(int32(imm), regs[1], funct3, regs[0], 0x13)}, nil
+	}
+	if op, ok := shiftInstructions[mnemonic]; ok {
+		if err := expectOperands(operands, 3); err != nil {
+			return nil, err
+		}
+		regs, err := parseRegisters(operands[:2])
+		if err != nil {
+			return nil, err
+		}
+		shamt, err := parseImmediate(operands[2])
+		if err != nil {
+			return nil, err
+		}
+		if shamt < 0 || shamt > 31 {
+			return nil, fmt.Errorf("shift amount %d out of range", shamt)
+		}
+		return []uint32{encodeR(op.funct7, uint32(shamt), regs[1], op.funct3, regs[0], 0x13)}, nil
+	}
+	if funct3, ok := loadInstructions[mnemonic]; ok {
+		if err := expectOperands(operands, 2); err != nil {
+			return nil, err
+		}
+		rd, err := parseRegister(operands[0])
+		if err != nil {
+			return nil, err
+		}
+		offset, rs1, err := parseMemoryOperand(operands[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSigned(offset, 12); err != nil {
+			return nil, err
+		}
+		return []uint32{encodeI(int32(offset), rs1, funct3, rd, 0x03)}, nil
+	}
+	if funct3, ok := storeInstructions[mnemonic]; ok {
+		if err := expectOperands(operands, 2); err != nil {
+			return nil, err
+		}
+		rs2, err := parseRegister(operands[0])
+		if err != nil {
+			return nil, err
+		}
+		offset, rs1, err := parseMemoryOperand(operands[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSigned(offset, 12); err != nil {
+			return nil, err
+		}
+		return []uint32{encodeS(int32(offset), rs2, rs1, funct3, 0x23)}, nil
+	}
+	if branch, ok := swappedBranches[mnemonic]; ok {
+		if err := expectOperands(operands, 3); err != nil {
+			return nil, err
+		}
+		return encodeInstruction(branch, []string{operands[1], operands[0], operands[2]}, pc, resolve)
+	}
+	if branch, ok := zeroBranches[mnemonic]; ok {
+		if err := expectOperands(operands, 2); err != nil {
+			return nil, err
+		}
+		if branch.zeroFirst {
+			return encodeInstruction(branch.branch, []string{"zero", operands[0], operands[1]}, pc, resolve)
+		}
+		return encodeInstruction(branch.branch, []string{operands[0], "zero", operands[1]}, pc, resolve)
+	}
+	if funct3, ok := branchInstructions[mnemonic]; ok {
+		if err := expectOperands(operands, 3); err != nil {
+			return nil, err
+		}
+		regs, err := parseRegisters(operands[:2])
+		if err != nil {
+			return nil, err
+		}
+		target, err := resolve(operands[2])
+		if err != nil {
+			return nil, err
+		}
+		offset := int64(int32(target - pc))
+		if err := checkSigned(offset, 13); err != nil {
+			return nil, err
+		}
+		return []uint32{encodeB(int32(offset), regs[1], regs[0], funct3)}, nil
+	}
+
+	switch mnemonic {
+	case "lui", "auipc":
+		if err := expectOperands(operands, 2); err != nil {
+			return nil, err
+		}
+		rd, err := parseRegister(operands[0])
+		if err != nil {
+			return nil, err
+		}
+		imm, err := parseImmediate(operands[1])
+		if err != nil {
+			return nil, err
+		}
+		if imm < 0 || imm > 0xfffff {
+			return nil, fmt.Errorf("immediate %d out of range", imm)
+		}
+		opcode := uint32(0x37)
+		if mnemonic == "auipc" {
+			opcode = 0x17
+		}
+		return []uint32{encodeU(uint32(imm), rd, opcode)}, nil
+	case "jal", "j":
+		rd := uint32(1)
+		if mnemonic == "j" {
+			rd = 0
+		}
+		if len(operands) == 2 {
+			var err error
+			rd, err = parseRegister(operands[0])
+			if err != nil {
+				return nil, err
+			}
+			operands = operands[1:]
+		}
+		if err := expectOperands(operands, 1); err != nil {
+			return nil, err
+		}
+		target, err := resolve(operands[0])
+		if err != nil {
+			return nil, err
+		}
+		offset := int64(int32(target - pc))
+		if err := checkSigned(offset, 21); err != nil {
+			return nil, err
+		}
+		return []uint32{encodeJ(int32(offset), rd)}, nil
+	case "jalr":
+		switch len(operands) {
+		case 1:
+			rs1, err := parseRegister(operands[0])
+			if err != nil {
+				return nil, err
+			}
+			return []uint32{encodeI(0, rs1, 0, 1, 0x67)}, nil
+		case 2:
+			rd, err := parseRegister(operands[0])
+			if err != nil {
+				return nil, err
+			}
+			offset, rs1, err := parseMemoryOperand(operands[1])
+			if err != nil {
+				return nil, err
+			}
+			if err := checkSigned(offset, 12); err != nil {
+				return nil, err
+			}
+			return []uint32{encodeI(int32(offset), rs1, 0, rd, 0x67)}, nil
+		case 3:
+			regs, err := parseRegisters(operands[:2])
+			if err != nil {
+				return nil, err
+			}
+			offset, err := parseImmediate(operands[2])
+			if err != nil {
+				return nil, err
+			}
+			if err := checkSigned(offset, 12); err != nil {
+				return nil, err
+			}
+			return []uint32{encodeI(int32(offset), regs[1], 0, regs[0], 0x67)}, nil
+		}
+		return nil, fmt.Errorf("expected 1 to 3 operands, got %d", len(operands))
+	case "jr":
+		if err := expectOperands(operands, 1); err != nil {
+			return nil, err
+		}
+		rs1, err := parseRegister(operands[0])
+		if err != nil {
+			return nil, err
+		}
+		return []uint32{encodeI(0, rs1, 0, 0, 0x67)}, nil
+	case "ret":
+		return []uint32{encodeI(0, 1, 0, 0, 0x67)}, nil
+	case "nop":
+		return []uint32{encodeI(0, 0, 0, 0, 0x13)}, nil
+	case "ebreak":
+		return []uint32{EbreakInstr}, nil
+	case "ecall":
+		return []uint32{0x00000073}, nil
+	case "fence":
+		return []uint32{0x0ff0000f}, nil
+	case "mv", "not", "neg", "seqz", "snez":
+		if err := expectOperands(operands, 2); err != nil {
+			return nil, err
+		}
+		regs, err := parseRegisters(operands)
+		if err != nil {
+			return nil, err
+		}
+		rd, rs := regs[0], regs[1]
+		switch mnemonic {
+		case "mv":
+			return []uint32{encodeI(0, rs, 0, rd, 0x13)}, nil
+		case "not":
+			return []uint32{encodeI(-1, rs, 4, rd, 0x13)}, nil
+		case "neg":
+			return []uint32{encodeR(0x20, rs, 0, 0, rd, 0x33)}, nil
+		case "seqz":
+			return []uint32{encodeI(1, rs, 3, rd, 0x13)}, nil
+		default:
+			return []uint32{encodeR(0, rs, 0, 3, rd, 0x33)}, nil
+		}
+	case "li":
+		if err := expectOperands(operands, 2); err != nil {
+			return nil, err
+		}
+		rd, err := parseRegister(operands[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := parseWordImmediate(operands[1])
+		if err != nil {
+			return nil, err
+		}
+		return loadImmediate(rd, value), nil
+	case "la", "lla", "call", "tail":
+		var rd, link uint32
+		var symbol string
+		switch mnemonic {
+		case "call":
+			if err := expectOperands(operands, 1); err != nil {
+				return nil, err
+			}
+			rd, link, symbol = 1, 1, operands[0]
+		case "tail":
+			if err := expectOperands(operands, 1); err != nil {
+				return nil, err
+			}
+			rd, link, symbol = 6, 0, operands[0]
+		default:
+			if err := expectOperands(operands, 2); err != nil {
+				return nil, err
+			}
+			var err error
+			rd, err = parseRegister(operands[0])
+			if err != nil {
+				return nil, err
+			}
+			symbol = operands[1]
+		}
+		target, err := resolve(symbol)
+		if err != nil {
+			return nil, err
+		}
+		hi, lo := splitPcRelative(target - pc)
+		auipc := encodeU(hi, rd, 0x17)
+		if mnemonic == "la" || mnemonic == "lla" {
+			return []uint32{auipc, encodeI(lo, rd, 0, rd, 0x13)}, nil
+		}
+		return []uint32{auipc, encodeI(lo, rd, 0, link, 0x67)}, nil
+	}
+	return nil, fmt.Errorf("unsupported instruction")
+}
+
+// loadImmediate expands li into lui/addi.
+func loadImmediate(rd uint32, value uint32) []uint32 {
+	signed := int32(value)
+	if signed >= -2048 && signed < 2048 {
+		return []uint32{encodeI(signed, 0, 0, rd, 0x13)}
+	}
+	hi, lo := splitPcRelative(value)
+	instructions := []uint32{encodeU(hi, rd, 0x37)}
+	if lo != 0 {
+		instructions = append(instructions, encodeI(lo, rd, 0, rd, 0x13))
+	}
+	return instructions
+}
+
+// splitPcRelative splits a value into the upper 20 bits and the sign extended lower 12 bits.
+func splitPcRelative(value uint32) (uint32, int32) {
+	hi := ((value + 0x800) >> 12) & 0xfffff
+	lo := int32(value - hi<<12)
+	return hi, lo
+}
+
+func checkSigned(value int64, bits uint) error {
+	limit := int64(1) << (bits - 1)
+	if value < -limit || value >= limit {
+		return fmt.Errorf("immediate %d does not fit in %d bits", value, bits)
+	}
+	return nil
+}
+
+func encodeR(funct7, rs2, rs1, funct3, rd, opcode uint32) uint32 {
+	return funct7<<25 | rs2<<20 | rs1<<15 | funct3<<12 | rd<<7 | opcode
+}
+
+func encodeI(imm int32, rs1, funct3, rd, opcode uint32) uint32 {
+	return (uint32(imm)&0xfff)<<20 | rs1<<15 | funct3<<12 | rd<<7 | opcode
+}
+
+func encodeS(imm int32, rs2, rs1, funct3, opcode uint32) uint32 {
+	value := uint32(imm)
+	return (value>>5&0x7f)<<25 | rs2<<20 | rs1<<15 | funct3<<12 | (value&0x1f)<<7 | opcode
+}
+
+func encodeB(imm int32, rs2, rs1, funct3 uint32) uint32 {
+	value := uint32(imm)
+	return (value>>12&0x1)<<31 | (value>>5&0x3f)<<25 | rs2<<20 | rs1<<15 | funct3<<12 |
+		(value>>1&0xf)<<8 | (value>>11&0x1)<<7 | 0x63
+}
+
+func encodeU(imm, rd, opcode uint32) uint32 {
+	return (imm&0xfffff)<<12 | rd<<7 | opcode
+}
+
+func encodeJ(imm int32, rd uint32) uint32 {
+	value := uint32(imm)
+	return (value>>20&0x1)<<31 | (value>>1&0x3ff)<<21 | (value>>11&0x1)<<20 | (value>>12&0xff)<<12 | rd<<7 | 0x6f
+}
@@ -0,0 +1,64 @@
+package prover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrNotSupported = errors.New("operation not supported by backend")
+
+type ProofKind int
+
+const (
+	ProofKindApp ProofKind = iota
+	ProofKindStark
+)
+
+type ProofArtifacts struct {
+	Proof        []byte
+	VerifyingKey []byte
+	Stdout       string
+}
+
+// Backend is a zkVM (or emulator) the transpiled assembly can be executed and proven on.
+type Backend interface {
+	Name() string
+	// PrecompileSymbol maps a lib.asm routine to the backend specific implementation.
+	PrecompileSymbol(function string) string
+	// KeepsBreakpoints reports if the EBREAK stack snapshot markers should stay in the assembly.
+	KeepsBreakpoints() bool
+	Assemble(file *AssemblyFile) (string, error)
+	// Build creates a workspace with the assembly and compiles it.
+	Build(ctx context.Context, assembly string) (*Cli, error)
+	Keygen(ctx context.Context, cli *Cli) error
+	Execute(ctx context.Context, cli *Cli) (string, error)
+	Prove(ctx context.Context, cli *Cli, kind ProofKind) (ProofArtifacts, error)
+	Verify(ctx context.Context, artifacts ProofArtifacts) (VerificationResult, error)
+}
+
+var backends = map[string]func() Backend{
+	"openvm":  func() Backend { return NewOpenVMBackend() },
+	"risc0":   func() Backend { return NewRiscZeroBackend() },
+	"sp1":     func() Backend { return NewSP1Backend() },
+	"unicorn": func() Backend { return NewUnicornBackend() },
+}
+
+func NewBackend(name string) (Backend, error) {
+	newBackend, ok := backends[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q (available: %s)", name, strings.Join(BackendNames(), ", "))
+	}
+	return newBackend(), nil
+}
+
+func BackendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,12 +1,9 @@
 package prover
 
 import (
-	"bytes"
 	_ "embed"
 	"fmt"
-	"log"
-	"os"
-	"os/exec"
+	"sort"
 	"strings"
 
 	"github.com/holiman/uint256"
@@ -14,23 +11,58 @@ import (
 
 const InstructionEBREAK = "EBREAK"
 
-type RuntimeTarget int
-
-const (
-	RuntimeUnicorn RuntimeTarget = iota
-	RuntimeTargetOpenVM
-)
-
 type AssemblyFile struct {
 	Instructions []Instruction
 	DataSection  []DataVariable
+	// Optional, used to break down the cycle count
+	Sections []InstructionSection
+}
+
+// InstructionSection names the instructions from Start until the next section (e.g. the EVM opcode they implement).
+type InstructionSection struct {
+	Name  string
+	Start int
+	// Optional, written as a comment before the section in the assembly
+	Source *SourceLocation
+}
+
+// SourceLocation is the contract instruction an EVM opcode was traced from.
+type SourceLocation struct {
+	TxIndex int    `json:"tx_index"`
+	Address string `json:"address"`
+	Pc      uint64 `json:"pc"`
+	// Optional, when the source map of the contract is known
+	Solidity *SolidityLocation `json:"solidity,omitempty"`
+}
+
+func (l SourceLocation) String() string {
+	if l.Solidity != nil {
+		return fmt.Sprintf("tx %d %s pc %d (%s)", l.TxIndex, l.Address, l.Pc, l.Solidity)
+	}
+	return fmt.Sprintf("tx %d %s pc %d", l.TxIndex, l.Address, l.Pc)
+}
+
+// SectionAt returns the section of the instruction at the index, nil when it is before the first section.
+func (a *AssemblyFile) SectionAt(instruction int) *InstructionSection {
+	index := sort.Search(len(a.Sections), func(i int) bool {
+		return a.Sections[i].Start > instruction
+	}) - 1
+	if index < 0 || instruction >= len(a.Instructions) {
+		return nil
+	}
+	return &a.Sections[index]
 }
 
 type DataVariable struct {
 	Name  string
 	Value *uint256.Int
+	// Raw bytes (e.g. call return data), used instead of Value when set
+	Bytes []byte `json:",omitempty"`
 }
 
+// Maximum call depth in the EVM, each frame stores sp and s3 on the call context stack.
+const callContextStackSize = 1024 * 8
+
 //go:embed resources/lib.asm
 var libFile []byte
 
@@ -39,8 +71,28 @@ type Instruction struct {
 	Operands []string
 }
 
+// SolidityCoverage counts the executed EVM opcodes of every Solidity line, by file and line.
+func (a *AssemblyFile) SolidityCoverage() map[string]map[int]int {
+	coverage := make(map[string]map[int]int)
+	for _, section := range a.Sections {
+		if section.Source == nil || section.Source.Solidity == nil {
+			continue
+		}
+		location := section.Source.Solidity
+		if coverage[location.File] == nil {
+			coverage[location.File] = make(map[int]int)
+		}
+		coverage[location.File][location.Line]++
+	}
+	return coverage
+}
+
 func (a *AssemblyFile) toDebugFile() string {
-	instructions := a.toFile(RuntimeUnicorn)
+	return a.toEmulatorFile(a.toFile(NewUnicornBackend()))
+}
+
+// toEmulatorFile wraps the instructions so they run on the emulators, execution ends with a jump to address 0.
+func (a *AssemblyFile) toEmulatorFile(instructions string) string {
 	dataSection := a.generateDataSection()
 	file := `
 .section .data
@@ -64,6 +116,10 @@ func (a *AssemblyFile) generateDataSection() string {
 
 	var lines []string
 	for _, dataVar := range a.DataSection {
+		if dataVar.Bytes != nil {
+			lines = append(lines, generateByteBlob(dataVar.Name, dataVar.Bytes)...)
+			continue
+		}
 		bytes := dataVar.Value.Bytes32()
 		lines = append(lines, fmt.Sprintf("%s:", dataVar.Name))
 		for i := 0; i < 8; i++ {
@@ -78,33 +134,34 @@ func (a *AssemblyFile) generateDataSection() string {
 	return strings.Join(lines, "\n")
 }
 
-func (a *AssemblyFile) toZkFile() string {
-	return a.toFile(RuntimeTargetOpenVM)
+func generateByteBlob(name string, data []byte) []string {
+	lines := []string{fmt.Sprintf("%s:", name)}
+	// Pad to a word boundary so the following variables stay aligned
+	padded := make([]byte, (len(data)+3)/4*4)
+	copy(padded, data)
+	for i := 0; i < len(padded); i += 4 {
+		lines = append(lines, fmt.Sprintf("    .byte 0x%02x, 0x%02x, 0x%02x, 0x%02x", padded[i], padded[i+1], padded[i+2], padded[i+3]))
+	}
+	return lines
 }
 
-func (a *AssemblyFile) toFile(target RuntimeTarget) string {
-	// TODO: this should have a nicer abstraction
-	openVMPrecompiles := map[string]string{
-		"add256_stack_scratch": "openvm_add256_stack_scratch",
-		"eq256_stack_scratch":  "openvm_eq256_stack_scratch",
-		"lt256_stack_scratch":  "openvm_lt256_stack_scratch",
-		"gt256_stack_scratch":  "openvm_gt256_stack_scratch",
-		"shr256_stack_scratch": "openvm_shr256_stack_scratch",
-		"not256_stack_scratch": "openvm_not256_stack_scratch",
-	}
+func (a *AssemblyFile) toFile(backend Backend) string {
 	instructions := make([]string, 0)
-	for _, instr := range a.Instructions {
-		if (target != RuntimeUnicorn) && instr.Name == InstructionEBREAK {
+	section := 0
+	for i, instr := range a.Instructions {
+		for section < len(a.Sections) && a.Sections[section].Start <= i {
+			if source := a.Sections[section].Source; source != nil {
+				instructions = append(instructions, fmt.Sprintf("\t# %s: %s", source, a.Sections[section].Name))
+			}
+			section++
+		}
+
+		if !backend.KeepsBreakpoints() && instr.Name == InstructionEBREAK {
 			continue
 		}
 
-		// TODO: this should have a nicer abstraction ...
-		if target == RuntimeTargetOpenVM && instr.Name == "call" {
-			functionName := instr.Operands[0]
-			precompile, ok := openVMPrecompiles[functionName]
-			if ok {
-				functionName = precompile
-			}
+		if instr.Name == "call" {
+			functionName := backend.PrecompileSymbol(instr.Operands[0])
 			stringified := fmt.Sprintf("\t%s %s", instr.Name, functionName)
 			instructions = append(instructions, stringified)
 		} else {
@@ -118,76 +175,62 @@ func (a *AssemblyFile) toFile(target RuntimeTarget) string {
 }
 
 func (f *AssemblyFile) ToToolChainCompatibleAssembly() (string, error) {
+	return f.ToBackendAssembly(NewOpenVMBackend())
+}
+
+func (f *AssemblyFile) ToBackendAssembly(backend Backend) (string, error) {
+	return backend.Assemble(f)
+}
+
+// Assembly for the zkVM guests, the transpiled program is called as a function from the guest.
+func (f *AssemblyFile) toGuestAssembly(backend Backend) string {
 	dataSection := f.generateDataSection()
 	format := `
 .section .data
 %s
 
+.align 4
+call_context_stack:
+	.space %d
+call_context_stack_top:
+
 .section .text
 .global execute
 execute:
-	# Save stack
+	# Save the callee-saved registers used by the transpiled program
+	addi sp, sp, -32
+	sw ra, 28(sp)
+	sw s1, 24(sp)
+	sw s2, 20(sp)
+	sw s3, 16(sp)
+	sw s4, 12(sp)
+	sw s5, 8(sp)
+
+	# Setup stack and call context
 	mv s2, sp
-	mv s1, ra
+	mv s3, sp
+	la s1, call_context_stack_top
+	li s4, 0
 
 %s
 
 	# Restore stack
 	mv sp, s2
-	mv ra, s1
+	lw s5, 8(sp)
+	lw s4, 12(sp)
+	lw s3, 16(sp)
+	lw s2, 20(sp)
+	lw s1, 24(sp)
+	lw ra, 28(sp)
+	addi sp, sp, 32
 	ret
 
 %s
 	`
-	return fmt.Sprintf(format, dataSection, f.toZkFile(), libFile), nil
+	return fmt.Sprintf(format, dataSection, callContextStackSize, f.toFile(backend), libFile)
 }
 
 // Used by the testing setup
 func (f *AssemblyFile) ToBytecode() ([]byte, error) {
-	assembly := f.toDebugFile()
-	tmpFile, err := os.CreateTemp("", "*.s")
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if err := os.Remove(tmpFile.Name()); err != nil {
-			log.Printf("Failed to remove temporary file %s: %v", tmpFile.Name(), err)
-		}
-	}()
-
-	_, err = tmpFile.WriteString(assembly)
-	if err != nil {
-		return nil, err
-	}
-	err = tmpFile.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	objFile := tmpFile.Name() + ".o"
-	cmd := exec.Command("riscv64-linux-gnu-gcc",
-		"-march=rv32im",
-		"-mabi=ilp32",
-		"-nostdlib",
-		"-nostartfiles",
-		"-static",
-		"-Wl,--entry=execute",
-		"-Wl,-Ttext=0x1000",
-		"-Wl,-Tdata=0x12000",
-		"-o", objFile,
-		tmpFile.Name())
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to compile: %s", stderr.String())
-	}
-
-	defer func() {
-		if err := os.Remove(objFile); err != nil {
-			log.Printf("Failed to remove temporary file %s: %v", objFile, err)
-		}
-	}()
-
-	return os.ReadFile(objFile)
+	return AssembleElf(f.toDebugFile(), DefaultElfLayout)
 }
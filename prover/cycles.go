@@ -0,0 +1,147 @@
+package prover
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Label put in front of each AssemblyFile section when counting cycles
+const sectionLabelPrefix = "__section_"
+
+// Instructions outside of a named section, i.e. before the first one
+const unsectionedName = "execute"
+
+type CycleReport struct {
+	Total uint64 `json:"total"`
+	// Instructions executed in the transpiled code, by section name
+	Sections map[string]uint64 `json:"sections"`
+	// Instructions executed in the lib.asm routines
+	Routines map[string]uint64 `json:"routines"`
+}
+
+// CountCycles runs the program on the emulator and counts the executed instructions.
+// The zkVM precompiles are not used, so the routines are the lib.asm implementations.
+func (f *AssemblyFile) CountCycles() (*CycleReport, error) {
+	program, err := Assemble(f.toCycleCountFile(), ElfLayout{
+		TextAddr: DefaultElfLayout.TextAddr,
+		Entry:    DefaultElfLayout.Entry,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every text word belongs to the closest section label or global symbol before it
+	names := []string{unsectionedName}
+	isSection := []bool{true}
+	buckets := make([]int, len(program.Text)/4)
+	for _, symbol := range program.Symbols {
+		if symbol.Section != ".text" || symbol.Name == DefaultElfLayout.Entry {
+			continue
+		}
+		sectionName, isSectionLabel := f.sectionName(symbol.Name)
+		if !isSectionLabel && !symbol.Global {
+			continue
+		}
+		name := symbol.Name
+		if isSectionLabel {
+			name = sectionName
+		}
+		names = append(names, name)
+		isSection = append(isSection, isSectionLabel)
+		for i := int(symbol.Value-program.TextAddr) / 4; i < len(buckets); i++ {
+			buckets[i] = len(names) - 1
+		}
+	}
+
+	emu, err := newCycleCountEmulator(program)
+	if err != nil {
+		return nil, NewPreRuntimeError(err)
+	}
+
+	counts := make([]uint64, len(names))
+	emu.PC = program.Entry
+	for emu.PC != 0 {
+		index := int(emu.PC-program.TextAddr) / 4
+		if emu.PC < program.TextAddr || index >= len(buckets) {
+			return nil, NewRuntimeError(fmt.Errorf("pc 0x%x is outside of the text section", emu.PC))
+		}
+		counts[buckets[index]]++
+		if err := emu.Step(); err != nil {
+			return nil, NewRuntimeError(err)
+		}
+	}
+
+	report := &CycleReport{
+		Total:    emu.Steps,
+		Sections: make(map[string]uint64),
+		Routines: make(map[string]uint64),
+	}
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+		if isSection[i] {
+			report.Sections[names[i]] += count
+		} else {
+			report.Routines[names[i]] += count
+		}
+	}
+	return report, nil
+}
+
+func (f *AssemblyFile) sectionName(label string) (string, bool) {
+	if !strings.HasPrefix(label, sectionLabelPrefix) {
+		return "", false
+	}
+	var index int
+	if _, err := fmt.Sscanf(label[len(sectionLabelPrefix):], "%d", &index); err != nil || index >= len(f.Sections) {
+		return "", false
+	}
+	return f.Sections[index].Name, true
+}
+
+// toCycleCountFile is the debug file without EBREAK and with a label at the start of each section.
+func (f *AssemblyFile) toCycleCountFile() string {
+	lines := make([]string, 0, len(f.Instructions)+len(f.Sections))
+	section := 0
+	for i, instr := range f.Instructions {
+		for section < len(f.Sections) && f.Sections[section].Start <= i {
+			lines = append(lines, fmt.Sprintf("%s%d:", sectionLabelPrefix, section))
+			section++
+		}
+		if strings.EqualFold(instr.Name, InstructionEBREAK) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("\t%s %s", instr.Name, strings.Join(instr.Operands, ", ")))
+	}
+	for ; section < len(f.Sections); section++ {
+		lines = append(lines, fmt.Sprintf("%s%d:", sectionLabelPrefix, section))
+	}
+	return f.toEmulatorFile(strings.Join(lines, "\n"))
+}
+
+func newCycleCountEmulator(program *AssembledProgram) (*Emulator, error) {
+	emu := NewEmulator()
+	end := program.DataAddr + uint32(len(program.Data))
+	if err := emu.MapMemory(program.TextAddr, (end-program.TextAddr+0xfff)&^0xfff); err != nil {
+		return nil, err
+	}
+	if err := emu.WriteMemory(program.TextAddr, program.Text); err != nil {
+		return nil, err
+	}
+	if err := emu.WriteMemory(program.DataAddr, program.Data); err != nil {
+		return nil, err
+	}
+
+	if err := emu.MapMemory(runnerStackEnd-runnerStackSize, runnerStackSize); err != nil {
+		return nil, err
+	}
+	if err := emu.MapMemory(runnerContextStackTop-runnerContextStackSize, runnerContextStackSize); err != nil {
+		return nil, err
+	}
+
+	emu.Registers[RegSP] = runnerStackTop
+	emu.Registers[RegS3] = runnerStackTop
+	emu.Registers[RegS1] = runnerContextStackTop
+	return emu, nil
+}
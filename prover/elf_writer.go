@@ -0,0 +1,156 @@
+package prover
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	elfHeaderSize        = 52
+	elfProgramHeaderSize = 32
+	elfSectionHeaderSize = 40
+	elfSymbolSize        = 16
+)
+
+// Section indexes in the written ELF
+const (
+	elfSectionText = 1 + iota
+	elfSectionData
+	elfSectionSymtab
+	elfSectionStrtab
+	elfSectionShstrtab
+	elfSectionCount
+)
+
+type elfStringTable struct {
+	data bytes.Buffer
+}
+
+func newElfStringTable() *elfStringTable {
+	table := &elfStringTable{}
+	table.data.WriteByte(0)
+	return table
+}
+
+func (t *elfStringTable) add(name string) uint32 {
+	offset := uint32(t.data.Len())
+	t.data.WriteString(name)
+	t.data.WriteByte(0)
+	return offset
+}
+
+// Elf writes a static little endian ELF32 executable with the text and data loaded at their addresses.
+func (p *AssembledProgram) Elf() []byte {
+	le := binary.LittleEndian
+
+	shstrtab := newElfStringTable()
+	sectionNameOffsets := [elfSectionCount]uint32{}
+	for index, name := range []string{".text", ".data", ".symtab", ".strtab", ".shstrtab"} {
+		sectionNameOffsets[elfSectionText+index] = shstrtab.add(name)
+	}
+
+	// Local symbols have to come before the global ones
+	symbols := append([]ElfSymbol(nil), p.Symbols...)
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return !symbols[i].Global && symbols[j].Global
+	})
+	strtab := newElfStringTable()
+	var symtab bytes.Buffer
+	symtab.Write(make([]byte, elfSymbolSize))
+	firstGlobal := uint32(1)
+	for _, symbol := range symbols {
+		entry := make([]byte, elfSymbolSize)
+		le.PutUint32(entry[0:], strtab.add(symbol.Name))
+		le.PutUint32(entry[4:], symbol.Value)
+		bind := elf.STB_LOCAL
+		if symbol.Global {
+			bind = elf.STB_GLOBAL
+		} else {
+			firstGlobal++
+		}
+		entry[12] = elf.ST_INFO(bind, elf.STT_NOTYPE)
+		sectionIndex := uint16(elfSectionText)
+		if symbol.Section == ".data" {
+			sectionIndex = elfSectionData
+		}
+		le.PutUint16(entry[14:], sectionIndex)
+		symtab.Write(entry)
+	}
+
+	programHeaderCount := 2
+	offset := uint32(elfHeaderSize + programHeaderCount*elfProgramHeaderSize)
+	align := func(value uint32) uint32 {
+		return (value + 3) &^ 3
+	}
+	textOffset := align(offset)
+	dataOffset := align(textOffset + uint32(len(p.Text)))
+	symtabOffset := align(dataOffset + uint32(len(p.Data)))
+	strtabOffset := symtabOffset + uint32(symtab.Len())
+	shstrtabOffset := strtabOffset + uint32(strtab.data.Len())
+	sectionHeaderOffset := align(shstrtabOffset + uint32(shstrtab.data.Len()))
+
+	out := make([]byte, sectionHeaderOffset+elfSectionCount*elfSectionHeaderSize)
+
+	// ELF header
+	copy(out[0:], elf.ELFMAG)
+	out[elf.EI_CLASS] = byte(elf.ELFCLASS32)
+	out[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	out[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	le.PutUint16(out[16:], uint16(elf.ET_EXEC))
+	le.PutUint16(out[18:], uint16(elf.EM_RISCV))
+	le.PutUint32(out[20:], uint32(elf.EV_CURRENT))
+	le.PutUint32(out[24:], p.Entry)
+	le.PutUint32(out[28:], elfHeaderSize)
+	le.PutUint32(out[32:], sectionHeaderOffset)
+	le.PutUint16(out[40:], elfHeaderSize)
+	le.PutUint16(out[42:], elfProgramHeaderSize)
+	le.PutUint16(out[44:], uint16(programHeaderCount))
+	le.PutUint16(out[46:], elfSectionHeaderSize)
+	le.PutUint16(out[48:], elfSectionCount)
+	le.PutUint16(out[50:], elfSectionShstrtab)
+
+	// Program headers
+	writeProgramHeader := func(index int, fileOffset, addr, size uint32, flags elf.ProgFlag) {
+		header := out[elfHeaderSize+index*elfProgramHeaderSize:]
+		le.PutUint32(header[0:], uint32(elf.PT_LOAD))
+		le.PutUint32(header[4:], fileOffset)
+		le.PutUint32(header[8:], addr)
+		le.PutUint32(header[12:], addr)
+		le.PutUint32(header[16:], size)
+		le.PutUint32(header[20:], size)
+		le.PutUint32(header[24:], uint32(flags))
+		le.PutUint32(header[28:], 4)
+	}
+	writeProgramHeader(0, textOffset, p.TextAddr, uint32(len(p.Text)), elf.PF_R|elf.PF_X)
+	writeProgramHeader(1, dataOffset, p.DataAddr, uint32(len(p.Data)), elf.PF_R|elf.PF_W)
+
+	copy(out[textOffset:], p.Text)
+	copy(out[dataOffset:], p.Data)
+	copy(out[symtabOffset:], symtab.Bytes())
+	copy(out[strtabOffset:], strtab.data.Bytes())
+	copy(out[shstrtabOffset:], shstrtab.data.Bytes())
+
+	// Section headers, index 0 is the null section
+	writeSectionHeader := func(index int, sectionType elf.SectionType, flags elf.SectionFlag, addr, fileOffset, size, link, info, alignment, entrySize uint32) {
+		header := out[sectionHeaderOffset+uint32(index)*elfSectionHeaderSize:]
+		le.PutUint32(header[0:], sectionNameOffsets[index])
+		le.PutUint32(header[4:], uint32(sectionType))
+		le.PutUint32(header[8:], uint32(flags))
+		le.PutUint32(header[12:], addr)
+		le.PutUint32(header[16:], fileOffset)
+		le.PutUint32(header[20:], size)
+		le.PutUint32(header[24:], link)
+		le.PutUint32(header[28:], info)
+		le.PutUint32(header[32:], alignment)
+		le.PutUint32(header[36:], entrySize)
+	}
+	writeSectionHeader(elfSectionText, elf.SHT_PROGBITS, elf.SHF_ALLOC|elf.SHF_EXECINSTR, p.TextAddr, textOffset, uint32(len(p.Text)), 0, 0, 4, 0)
+	writeSectionHeader(elfSectionData, elf.SHT_PROGBITS, elf.SHF_ALLOC|elf.SHF_WRITE, p.DataAddr, dataOffset, uint32(len(p.Data)), 0, 0, 4, 0)
+	writeSectionHeader(elfSectionSymtab, elf.SHT_SYMTAB, 0, 0, symtabOffset, uint32(symtab.Len()), elfSectionStrtab, firstGlobal, 4, elfSymbolSize)
+	writeSectionHeader(elfSectionStrtab, elf.SHT_STRTAB, 0, 0, strtabOffset, uint32(strtab.data.Len()), 0, 0, 1, 0)
+	writeSectionHeader(elfSectionShstrtab, elf.SHT_STRTAB, 0, 0, shstrtabOffset, uint32(shstrtab.data.Len()), 0, 0, 1, 0)
+
+	return out
+}
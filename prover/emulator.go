@@ -0,0 +1,387 @@
+package prover
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+)
+
+// Register numbers used by the transpiled code
+const (
+	RegZero = 0
+	RegRA   = 1
+	RegSP   = 2
+	RegT0   = 5
+	RegS1   = 9
+	RegA0   = 10
+	RegS2   = 18
+	RegS3   = 19
+	RegS4   = 20
+	RegS5   = 21
+)
+
+type memoryRegion struct {
+	start uint32
+	data  []byte
+}
+
+// Emulator is a rv32im interpreter, it only implements what the transpiled code and lib.asm needs.
+type Emulator struct {
+	Registers [32]uint32
+	PC        uint32
+	// Number of executed instructions
+	Steps   uint64
+	regions []memoryRegion
+	// Called before the pc is moved past an EBREAK
+	OnEbreak func(emu *Emulator) error
+}
+
+func NewEmulator() *Emulator {
+	return &Emulator{}
+}
+
+func (e *Emulator) MapMemory(start, size uint32) error {
+	end := uint64(start) + uint64(size)
+	for _, region := range e.regions {
+		regionEnd := uint64(region.start) + uint64(len(region.data))
+		if uint64(start) < regionEnd && uint64(region.start) < end {
+			return fmt.Errorf("memory region 0x%x-0x%x overlaps 0x%x-0x%x", start, end, region.start, regionEnd)
+		}
+	}
+	e.regions = append(e.regions, memoryRegion{start: start, data: make([]byte, size)})
+	return nil
+}
+
+func (e *Emulator) slice(addr, size uint32) ([]byte, bool) {
+	for _, region := range e.regions {
+		offset := uint64(addr) - uint64(region.start)
+		if addr >= region.start && offset+uint64(size) <= uint64(len(region.data)) {
+			return region.data[offset : offset+uint64(size)], true
+		}
+	}
+	return nil, false
+}
+
+func (e *Emulator) ReadMemory(addr, size uint32) ([]byte, error) {
+	data, ok := e.slice(addr, size)
+	if !ok {
+		return nil, fmt.Errorf("unmapped memory read of %d bytes at 0x%x (pc 0x%x)", size, addr, e.PC)
+	}
+	return data, nil
+}
+
+func (e *Emulator) WriteMemory(addr uint32, data []byte) error {
+	dst, ok := e.slice(addr, uint32(len(data)))
+	if !ok {
+		return fmt.Errorf("unmapped memory write of %d bytes at 0x%x (pc 0x%x)", len(data), addr, e.PC)
+	}
+	copy(dst, data)
+	return nil
+}
+
+func (e *Emulator) ReadWord(addr uint32) (uint32, error) {
+	data, err := e.ReadMemory(addr, 4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(data), nil
+}
+
+// LoadElf writes the allocated sections of a rv32 ELF into memory and returns the entry point.
+func (e *Emulator) LoadElf(bytecode []byte) (uint32, error) {
+	elfFile, err := elf.NewFile(bytes.NewReader(bytecode))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ELF file: %v", err)
+	}
+	defer elfFile.Close()
+
+	if elfFile.Machine != elf.EM_RISCV || elfFile.Class != elf.ELFCLASS32 {
+		return 0, fmt.Errorf("expected a rv32 ELF, got %v %v", elfFile.Machine, elfFile.Class)
+	}
+
+	for _, section := range elfFile.Sections {
+		if section.Type != elf.SHT_PROGBITS || section.Size == 0 || section.Addr == 0 {
+			continue
+		}
+
+		data, err := section.Data()
+		if err != nil {
+			return 0, err
+		}
+		if err := e.WriteMemory(uint32(section.Addr), data); err != nil {
+			return 0, err
+		}
+	}
+
+	return uint32(elfFile.Entry), nil
+}
+
+// Run executes from entry until the pc reaches until.
+func (e *Emulator) Run(entry, until uint32) error {
+	e.PC = entry
+	for e.PC != until {
+		if err := e.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Emulator) setRegister(rd uint32, value uint32) {
+	if rd != RegZero {
+		e.Registers[rd] = value
+	}
+}
+
+// Step executes a single instruction.
+func (e *Emulator) Step() error {
+	instr, err := e.ReadWord(e.PC)
+	if err != nil {
+		return err
+	}
+	e.Steps++
+
+	opcode := instr & 0x7f
+	rd := (instr >> 7) & 0x1f
+	funct3 := (instr >> 12) & 0x7
+	rs1 := (instr >> 15) & 0x1f
+	rs2 := (instr >> 20) & 0x1f
+	funct7 := instr >> 25
+	a := e.Registers[rs1]
+	b := e.Registers[rs2]
+	nextPC := e.PC + 4
+
+	switch opcode {
+	case 0x37: // LUI
+		e.setRegister(rd, instr&0xfffff000)
+	case 0x17: // AUIPC
+		e.setRegister(rd, e.PC+(instr&0xfffff000))
+	case 0x6f: // JAL
+		e.setRegister(rd, nextPC)
+		nextPC = e.PC + immJ(instr)
+	case 0x67: // JALR
+		target := (a + immI(instr)) &^ 1
+		e.setRegister(rd, nextPC)
+		nextPC = target
+	case 0x63: // BRANCH
+		var taken bool
+		switch funct3 {
+		case 0x0:
+			taken = a == b
+		case 0x1:
+			taken = a != b
+		case 0x4:
+			taken = int32(a) < int32(b)
+		case 0x5:
+			taken = int32(a) >= int32(b)
+		case 0x6:
+			taken = a < b
+		case 0x7:
+			taken = a >= b
+		default:
+			return e.illegal(instr)
+		}
+		if taken {
+			nextPC = e.PC + immB(instr)
+		}
+	case 0x03: // LOAD
+		addr := a + immI(instr)
+		var value uint32
+		switch funct3 {
+		case 0x0, 0x4:
+			data, err := e.ReadMemory(addr, 1)
+			if err != nil {
+				return err
+			}
+			value = uint32(data[0])
+			if funct3 == 0x0 {
+				value = uint32(int32(int8(data[0])))
+			}
+		case 0x1, 0x5:
+			data, err := e.ReadMemory(addr, 2)
+			if err != nil {
+				return err
+			}
+			half := binary.LittleEndian.Uint16(data)
+			value = uint32(half)
+			if funct3 == 0x1 {
+				value = uint32(int32(int16(half)))
+			}
+		case 0x2:
+			value, err = e.ReadWord(addr)
+			if err != nil {
+				return err
+			}
+		default:
+			return e.illegal(instr)
+		}
+		e.setRegister(rd, value)
+	case 0x23: // STORE
+		addr := a + immS(instr)
+		var data []byte
+		switch funct3 {
+		case 0x0:
+			data = []byte{byte(b)}
+		case 0x1:
+			data = binary.LittleEndian.AppendUint16(nil, uint16(b))
+		case 0x2:
+			data = binary.LittleEndian.AppendUint32(nil, b)
+		default:
+			return e.illegal(instr)
+		}
+		if err := e.WriteMemory(addr, data); err != nil {
+			return err
+		}
+	case 0x13: // OP-IMM
+		imm := immI(instr)
+		shamt := rs2
+		switch funct3 {
+		case 0x0:
+			e.setRegister(rd, a+imm)
+		case 0x2:
+			e.setRegister(rd, boolToWord(int32(a) < int32(imm)))
+		case 0x3:
+			e.setRegister(rd, boolToWord(a < imm))
+		case 0x4:
+			e.setRegister(rd, a^imm)
+		case 0x6:
+			e.setRegister(rd, a|imm)
+		case 0x7:
+			e.setRegister(rd, a&imm)
+		case 0x1:
+			e.setRegister(rd, a<<shamt)
+		case 0x5:
+			if funct7 == 0x20 {
+				e.setRegister(rd, uint32(int32(a)>>shamt))
+			} else {
+				e.setRegister(rd, a>>shamt)
+			}
+		}
+	case 0x33: // OP
+		value, err := e.executeOp(instr, funct3, funct7, a, b)
+		if err != nil {
+			return err
+		}
+		e.setRegister(rd, value)
+	case 0x0f: // FENCE
+	case 0x73: // SYSTEM
+		switch instr {
+		case EbreakInstr:
+			if e.OnEbreak != nil {
+				if err := e.OnEbreak(e); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unsupported system instruction 0x%08x at 0x%x", instr, e.PC)
+		}
+	default:
+		return e.illegal(instr)
+	}
+
+	e.PC = nextPC
+	return nil
+}
+
+func (e *Emulator) executeOp(instr, funct3, funct7, a, b uint32) (uint32, error) {
+	switch funct7 {
+	case 0x00:
+		switch funct3 {
+		case 0x0:
+			return a + b, nil
+		case 0x1:
+			return a << (b & 0x1f), nil
+		case 0x2:
+			return boolToWord(int32(a) < int32(b)), nil
+		case 0x3:
+			return boolToWord(a < b), nil
+		case 0x4:
+			return a ^ b, nil
+		case 0x5:
+			return a >> (b & 0x1f), nil
+		case 0x6:
+			return a | b, nil
+		case 0x7:
+			return a & b, nil
+		}
+	case 0x20:
+		switch funct3 {
+		case 0x0:
+			return a - b, nil
+		case 0x5:
+			return uint32(int32(a) >> (b & 0x1f)), nil
+		}
+	case 0x01: // M extension
+		switch funct3 {
+		case 0x0:
+			return a * b, nil
+		case 0x1:
+			return uint32(uint64(int64(int32(a))*int64(int32(b))) >> 32), nil
+		case 0x2:
+			return uint32(uint64(int64(int32(a))*int64(b)) >> 32), nil
+		case 0x3:
+			return uint32((uint64(a) * uint64(b)) >> 32), nil
+		case 0x4:
+			if b == 0 {
+				return 0xffffffff, nil
+			}
+			if int32(a) == -1<<31 && int32(b) == -1 {
+				return a, nil
+			}
+			return uint32(int32(a) / int32(b)), nil
+		case 0x5:
+			if b == 0 {
+				return 0xffffffff, nil
+			}
+			return a / b, nil
+		case 0x6:
+			if b == 0 {
+				return a, nil
+			}
+			if int32(a) == -1<<31 && int32(b) == -1 {
+				return 0, nil
+			}
+			return uint32(int32(a) % int32(b)), nil
+		case 0x7:
+			if b == 0 {
+				return a, nil
+			}
+			return a % b, nil
+		}
+	}
+	return 0, e.illegal(instr)
+}
+
+func (e *Emulator) illegal(instr uint32) error {
+	return fmt.Errorf("illegal instruction 0x%08x at 0x%x", instr, e.PC)
+}
+
+func boolToWord(value bool) uint32 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func immI(instr uint32) uint32 {
+	return uint32(int32(instr) >> 20)
+}
+
+func immS(instr uint32) uint32 {
+	return uint32(int32(instr&0xfe000000)>>20) | (instr>>7)&0x1f
+}
+
+func immB(instr uint32) uint32 {
+	return uint32(int32(instr&0x80000000)>>19) |
+		(instr<<4)&0x800 |
+		(instr>>20)&0x7e0 |
+		(instr>>7)&0x1e
+}
+
+func immJ(instr uint32) uint32 {
+	return uint32(int32(instr&0x80000000)>>11) |
+		instr&0xff000 |
+		(instr>>9)&0x800 |
+		(instr>>20)&0x7fe
+}
@@ -0,0 +1,124 @@
+package prover
+
+import (
+	"io"
+
+	"github.com/holiman/uint256"
+)
+
+// EmulatorRunner runs the debug bytecode on the pure Go rv32im Emulator with the same memory layout as Unicorn.
+type EmulatorRunner struct{}
+
+func NewEmulatorRunner() (*EmulatorRunner, error) {
+	return &EmulatorRunner{}, nil
+}
+
+func (r *EmulatorRunner) Execute(bytecode []byte) (*ExecutionResult, error) {
+	emu, err := newDebugEmulator(bytecode)
+	if err != nil {
+		return nil, NewPreRuntimeError(err)
+	}
+
+	allStackSnapshots := make([][]uint256.Int, 0)
+	emu.OnEbreak = func(emu *Emulator) error {
+		snapshot, err := emu.StackSnapshot()
+		if err != nil {
+			return err
+		}
+		allStackSnapshots = append(allStackSnapshots, snapshot)
+		return nil
+	}
+
+	entryPoint, err := emu.LoadElf(bytecode)
+	if err != nil {
+		return nil, NewPreRuntimeError(err)
+	}
+
+	// The debug assembly ends with a jump to address 0
+	if err := emu.Run(entryPoint, 0); err != nil {
+		return nil, NewRuntimeError(err)
+	}
+
+	return &ExecutionResult{
+		StackSnapshots: &allStackSnapshots,
+	}, nil
+}
+
+// Stepper runs the debug bytecode one EBREAK snapshot at a time, for the step debugger.
+type Stepper struct {
+	emu  *Emulator
+	done bool
+}
+
+func NewStepper(bytecode []byte) (*Stepper, error) {
+	emu, err := newDebugEmulator(bytecode)
+	if err != nil {
+		return nil, NewPreRuntimeError(err)
+	}
+	entryPoint, err := emu.LoadElf(bytecode)
+	if err != nil {
+		return nil, NewPreRuntimeError(err)
+	}
+	emu.PC = entryPoint
+	return &Stepper{emu: emu}, nil
+}
+
+// Next runs until after the next EBREAK and returns its stack snapshot, io.EOF when the program ended.
+func (s *Stepper) Next() ([]uint256.Int, error) {
+	var snapshot []uint256.Int
+	hit := false
+	s.emu.OnEbreak = func(emu *Emulator) error {
+		var err error
+		snapshot, err = emu.StackSnapshot()
+		hit = true
+		return err
+	}
+
+	for !hit {
+		// The debug assembly ends with a jump to address 0
+		if s.done || s.emu.PC == 0 {
+			s.done = true
+			return nil, io.EOF
+		}
+		if err := s.emu.Step(); err != nil {
+			s.done = true
+			return nil, NewRuntimeError(err)
+		}
+	}
+	return snapshot, nil
+}
+
+func (s *Stepper) Emulator() *Emulator {
+	return s.emu
+}
+
+// newDebugEmulator maps the memory for the program and sets up the registers like the Unicorn runner.
+func newDebugEmulator(bytecode []byte) (*Emulator, error) {
+	programSize, err := programMemorySize(bytecode)
+	if err != nil {
+		return nil, err
+	}
+	emu := NewEmulator()
+	regions := [][2]uint32{
+		{0, programSize},
+		{runnerStackEnd - runnerStackSize, runnerStackSize},
+		{runnerContextStackTop - runnerContextStackSize, runnerContextStackSize},
+	}
+	for _, region := range regions {
+		if err := emu.MapMemory(region[0], region[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	emu.Registers[RegSP] = runnerStackTop
+	emu.Registers[RegS3] = runnerStackTop
+	emu.Registers[RegS1] = runnerContextStackTop
+	return emu, nil
+}
+
+// StackSnapshot decodes the EVM stack of the current frame.
+func (e *Emulator) StackSnapshot() ([]uint256.Int, error) {
+	return readStackSnapshot(uint64(e.Registers[RegSP]), uint64(e.Registers[RegS3]), func(addr uint64) (uint32, error) {
+		return e.ReadWord(uint32(addr))
+	})
+}
@@ -0,0 +1,49 @@
+use openvm::io::{read, read_vec, reveal_u32};
+use openvm_verify_stark::define_verify_openvm_stark;
+
+// The root verifier of the aggregation keys the transaction proofs were made with, written by the host
+define_verify_openvm_stark!(
+    verify_transaction,
+    env!("CARGO_MANIFEST_DIR"),
+    "root_verifier.asm"
+);
+
+// Public values: the transaction count, then per transaction its hash, app exe commit, app vm commit and public
+// values. The hash is a label given by the host, the proof doesn't bind it to the transaction: the app exe commit
+// of the transpiled program is what identifies the execution that was proven.
+const WORDS_PER_TRANSACTION: usize = 32;
+
+fn reveal_bytes(bytes: &[u8], index: usize) {
+    for (i, word) in bytes.chunks(4).enumerate() {
+        let mut padded = [0u8; 4];
+        padded[..word.len()].copy_from_slice(word);
+        reveal_u32(u32::from_le_bytes(padded), index + i);
+    }
+}
+
+fn main() {
+    let count: u32 = read();
+    reveal_u32(count, 0);
+
+    for i in 0..count as usize {
+        let tx_hash = read_vec();
+        let app_exe_commit: [u32; 8] = read();
+        let app_vm_commit: [u32; 8] = read();
+        let user_public_values = read_vec();
+        assert_eq!(tx_hash.len(), 32);
+        assert!(user_public_values.len() <= 32);
+
+        verify_transaction(&app_exe_commit, &app_vm_commit, &user_public_values);
+
+        let index = 1 + i * WORDS_PER_TRANSACTION;
+        reveal_bytes(&tx_hash, index);
+        for (j, word) in app_exe_commit.iter().enumerate() {
+            reveal_u32(*word, index + 8 + j);
+        }
+        // The vm commit has to be revealed too, any VM config would verify otherwise
+        for (j, word) in app_vm_commit.iter().enumerate() {
+            reveal_u32(*word, index + 16 + j);
+        }
+        reveal_bytes(&user_public_values, index + 24);
+    }
+}
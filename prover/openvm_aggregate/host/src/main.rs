@@ -0,0 +1,138 @@
+use std::path::PathBuf;
+
+use anyhow::Context;
+use clap::Parser;
+use openvm_build::GuestOptions;
+use openvm_sdk::{
+    config::{AppConfig, SdkVmConfig},
+    fs::read_object_from_file,
+    keygen::AggProvingKey,
+    types::{VmStarkProof, VmStarkProofBytes},
+    Sdk, StdIn, F, SC,
+};
+use openvm_stark_sdk::openvm_stark_backend::p3_field::PrimeField32;
+use openvm_verify_stark::host::{
+    compute_hint_key_for_verify_openvm_stark, encode_proof_to_kv_store_value,
+};
+use serde::{Deserialize, Serialize};
+
+const ROOT_VERIFIER_ASM: &str = "root_verifier.asm";
+// Public values of the aggregate: the transaction count, then 128 bytes per transaction
+const PUBLIC_VALUES_PER_TRANSACTION: usize = 128;
+
+#[derive(Parser)]
+struct Args {
+    /// JSON list of {"tx_hash", "proof"} with the paths of the transaction STARK proofs
+    #[arg(long)]
+    input: PathBuf,
+    #[arg(long, default_value = "aggregate.stark.proof")]
+    proof: PathBuf,
+    #[arg(long, default_value = "public_values.bin")]
+    public_values: PathBuf,
+    /// JSON with the app exe and vm commits of the aggregation program
+    #[arg(long, default_value = "aggregate_commit.json")]
+    commit: PathBuf,
+}
+
+#[derive(Deserialize)]
+struct TransactionProof {
+    tx_hash: String,
+    proof: PathBuf,
+}
+
+// The agg vk verifies the proof of any program, these commits identify the aggregation program
+#[derive(Serialize)]
+struct AggregateCommit {
+    app_exe_commit: String,
+    app_vm_commit: String,
+}
+
+// Little-endian words, like the commits of the transactions in the public values
+fn commit_hex(digest: [u32; 8]) -> String {
+    let bytes: Vec<u8> = digest.iter().flat_map(|word| word.to_le_bytes()).collect();
+    format!("0x{}", hex::encode(bytes))
+}
+
+fn main() -> anyhow::Result<()> {
+    let args = Args::parse();
+    let transactions: Vec<TransactionProof> =
+        serde_json::from_slice(&std::fs::read(&args.input).context("reading input")?)?;
+
+    // The transaction proofs are verified against the aggregation keys of `cargo openvm setup`
+    let home = std::env::var("HOME").context("HOME is not set")?;
+    let agg_pk: AggProvingKey =
+        read_object_from_file(PathBuf::from(home).join(".openvm").join("agg_stark.pk"))?;
+
+    let guest_dir = PathBuf::from(env!("CARGO_MANIFEST_DIR")).join("../guest");
+    let mut app_config: AppConfig<SdkVmConfig> =
+        toml::from_str(&std::fs::read_to_string(guest_dir.join("openvm.toml"))?)?;
+    app_config.app_vm_config.system.config.num_public_values =
+        4 + transactions.len() * PUBLIC_VALUES_PER_TRANSACTION;
+
+    let sdk = Sdk::new(app_config)?.with_agg_pk(agg_pk);
+    std::fs::write(
+        guest_dir.join(ROOT_VERIFIER_ASM),
+        sdk.generate_root_verifier_asm(),
+    )?;
+
+    let mut stdin = StdIn::default();
+    stdin.write(&(transactions.len() as u32));
+    for transaction in &transactions {
+        let bytes: VmStarkProofBytes = serde_json::from_slice(
+            &std::fs::read(&transaction.proof)
+                .with_context(|| format!("reading {}", transaction.proof.display()))?,
+        )?;
+        let proof = VmStarkProof::<SC>::try_from(bytes)?;
+        let app_exe_commit = proof.app_commit.app_exe_commit.to_u32_digest();
+        let app_vm_commit = proof.app_commit.app_vm_commit.to_u32_digest();
+        let user_public_values: Vec<u8> = proof
+            .user_public_values
+            .iter()
+            .map(|value: &F| value.as_canonical_u32() as u8)
+            .collect();
+
+        let tx_hash = hex::decode(transaction.tx_hash.trim_start_matches("0x"))?;
+        stdin.write_bytes(&tx_hash);
+        stdin.write(&app_exe_commit);
+        stdin.write(&app_vm_commit);
+        stdin.write_bytes(&user_public_values);
+        stdin.add_key_value(
+            compute_hint_key_for_verify_openvm_stark(
+                ROOT_VERIFIER_ASM,
+                &app_exe_commit,
+                &app_vm_commit,
+                &user_public_values,
+            ),
+            encode_proof_to_kv_store_value(&proof.inner),
+        );
+    }
+
+    let elf = sdk.build(
+        GuestOptions::default(),
+        &guest_dir,
+        &Default::default(),
+        None,
+    )?;
+    let (proof, app_commit) = sdk.prove(elf, stdin)?;
+    Sdk::verify_proof(&sdk.agg_pk().get_agg_vk(), app_commit, &proof)?;
+
+    let public_values: Vec<u8> = proof
+        .user_public_values
+        .iter()
+        .map(|value| value.as_canonical_u32() as u8)
+        .collect();
+    std::fs::write(&args.public_values, &public_values)?;
+    let commit = AggregateCommit {
+        app_exe_commit: commit_hex(app_commit.app_exe_commit.to_u32_digest()),
+        app_vm_commit: commit_hex(app_commit.app_vm_commit.to_u32_digest()),
+    };
+    std::fs::write(&args.commit, serde_json::to_vec(&commit)?)?;
+    let bytes = VmStarkProofBytes::new(app_commit, proof)?;
+    std::fs::write(&args.proof, serde_json::to_vec(&bytes)?)?;
+    println!(
+        "Aggregated {} transaction proofs into {}",
+        transactions.len(),
+        args.proof.display()
+    );
+    Ok(())
+}
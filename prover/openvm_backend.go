@@ -0,0 +1,171 @@
+package prover
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//go:embed openvm/*
+var zkVMToolchain embed.FS
+
+var openVMPrecompiles = map[string]string{
+	"add256_stack_scratch": "openvm_add256_stack_scratch",
+	"eq256_stack_scratch":  "openvm_eq256_stack_scratch",
+	"lt256_stack_scratch":  "openvm_lt256_stack_scratch",
+	"gt256_stack_scratch":  "openvm_gt256_stack_scratch",
+	"shr256_stack_scratch": "openvm_shr256_stack_scratch",
+	"not256_stack_scratch": "openvm_not256_stack_scratch",
+}
+
+type OpenVMBackend struct {
+	// Builds and keys are reused from here unless OPENVM_BUILD_CACHE is off, see openvm_cache.go
+	cache *openVMCache
+}
+
+func NewOpenVMBackend() *OpenVMBackend {
+	return &OpenVMBackend{cache: openVMCacheFromEnv()}
+}
+
+func (b *OpenVMBackend) Name() string {
+	return "openvm"
+}
+
+func (b *OpenVMBackend) PrecompileSymbol(function string) string {
+	if precompile, ok := openVMPrecompiles[function]; ok {
+		return precompile
+	}
+	return function
+}
+
+func (b *OpenVMBackend) KeepsBreakpoints() bool {
+	return false
+}
+
+func (b *OpenVMBackend) Assemble(file *AssemblyFile) (string, error) {
+	return file.toGuestAssembly(b), nil
+}
+
+func (b *OpenVMBackend) Build(ctx context.Context, assembly string) (*Cli, error) {
+	workSpace, err := setupWorkspace(zkVMToolchain, "openvm", filepath.Join("src", "risc.asm"), []byte(assembly))
+	if err != nil {
+		return nil, NewZkProverError("failed to setup workspace", err)
+	}
+
+	cli := NewCli(workSpace)
+	cli.tmpDir = filepath.Dir(workSpace)
+	if b.cache != nil {
+		cli.buildCached, err = b.cache.build(ctx, &cli, assembly)
+	} else {
+		_, err = cli.Execute(ctx, "cargo", "openvm", "build")
+	}
+	if err != nil {
+		cli.removeWorkspace()
+		return nil, err
+	}
+	return &cli, nil
+}
+
+func (b *OpenVMBackend) Keygen(ctx context.Context, cli *Cli) error {
+	if b.cache != nil {
+		var err error
+		cli.keygenCached, err = b.cache.keygen(ctx, cli)
+		return err
+	}
+	_, err := cli.Execute(ctx, "cargo", "openvm", "keygen")
+	return err
+}
+
+func (b *OpenVMBackend) Execute(ctx context.Context, cli *Cli) (string, error) {
+	output, err := cli.Execute(ctx, "cargo", "openvm", "run", "--exe", openVMExePath)
+	if err != nil {
+		return "", err
+	}
+
+	executionOutput := ""
+	for _, line := range bytes.Split([]byte(output), []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("Execution output:")) {
+			executionOutput = string(line)
+			break
+		}
+	}
+	if executionOutput == "" {
+		return "", fmt.Errorf("execution output not found in the output: %s", output)
+	}
+
+	return executionOutput, nil
+}
+
+func (b *OpenVMBackend) Prove(ctx context.Context, cli *Cli, kind ProofKind) (ProofArtifacts, error) {
+	switch kind {
+	case ProofKindApp:
+		output, err := cli.Execute(ctx, "cargo", "openvm", "prove", "app", "--exe", openVMExePath)
+		if err != nil {
+			return ProofArtifacts{}, err
+		}
+		proof, err := cli.readFile("prover.app.proof")
+		if err != nil {
+			return ProofArtifacts{}, err
+		}
+		appVk, err := cli.readFile(openVMAppVkPath)
+		if err != nil {
+			return ProofArtifacts{}, err
+		}
+		return ProofArtifacts{
+			Proof:        proof,
+			VerifyingKey: appVk,
+			Stdout:       output,
+		}, nil
+	case ProofKindStark:
+		output, err := cli.Execute(ctx, "cargo", "openvm", "prove", "stark", "--exe", openVMExePath)
+		if err != nil {
+			return ProofArtifacts{}, err
+		}
+		proof, err := cli.readFile("prover.stark.proof")
+		if err != nil {
+			return ProofArtifacts{}, err
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ProofArtifacts{}, err
+		}
+		aggVk, err := os.ReadFile(filepath.Join(home, ".openvm", "agg_stark.vk"))
+		if err != nil {
+			return ProofArtifacts{}, err
+		}
+		return ProofArtifacts{
+			Proof:        proof,
+			VerifyingKey: aggVk,
+			Stdout:       output,
+		}, nil
+	}
+	return ProofArtifacts{}, fmt.Errorf("unknown proof kind %d", kind)
+}
+
+func (b *OpenVMBackend) Verify(ctx context.Context, artifacts ProofArtifacts) (VerificationResult, error) {
+	tmpDir, err := os.MkdirTemp("", "openvm-verify-*")
+	if err != nil {
+		return VerificationResult{}, NewZkProverError("failed to create temp directory", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	appVKPath := filepath.Join(tmpDir, "app.vk")
+	if err := os.WriteFile(appVKPath, artifacts.VerifyingKey, 0644); err != nil {
+		return VerificationResult{}, NewZkProverError("failed to write app.vk file", err)
+	}
+
+	proofPath := filepath.Join(tmpDir, "proof.app.proof")
+	if err := os.WriteFile(proofPath, artifacts.Proof, 0644); err != nil {
+		return VerificationResult{}, NewZkProverError("failed to write proof file", err)
+	}
+
+	cli := NewCli(tmpDir)
+	output, err := cli.Execute(ctx, "cargo", "openvm", "verify", "app", "--app-vk", appVKPath, "--proof", proofPath)
+	return VerificationResult{
+		Stdout: output,
+		Valid:  err == nil,
+	}, err
+}
@@ -0,0 +1,331 @@
+package prover
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// =============================================================================
+// OPENVM BUILD CACHE
+// =============================================================================
+
+// Directory reusing the dependency build, keys and built programs across runs, off disables the cache. Defaults to
+// erigon-transpiler-risc-v/openvm in the user cache directory.
+const (
+	openVMCacheEnv      = "OPENVM_BUILD_CACHE"
+	openVMCacheDisabled = "off"
+)
+
+// Size in MiB the built programs are kept under, the least recently used are removed first
+const (
+	openVMCacheSizeEnv       = "OPENVM_BUILD_CACHE_MAX_MB"
+	defaultOpenVMCacheSizeMB = 1024
+)
+
+// Paths in the guest crate, the defaults of cargo openvm
+const (
+	openVMExePath   = "target/openvm/release/prover.vmexe"
+	openVMAppPkPath = "target/openvm/app.pk"
+	openVMAppVkPath = "target/openvm/app.vk"
+)
+
+// How often a held lock is tried again
+const fileLockPollInterval = 100 * time.Millisecond
+
+const (
+	openVMExtractedMarker = "extracted"
+	openVMKeysMarker      = "keys"
+)
+
+// openVMCache keeps one guest crate per toolchain, whose target directory holds the dependency build and the
+// keys, and the executable of every built program:
+//
+//	<dir>/toolchain-<key>/openvm     guest crate the programs are built in, one at a time
+//	<dir>/programs/<key>.vmexe       built programs, by the hash of the toolchain and assembly
+//
+// The crate is locked while a program is built, so cache misses of concurrent workers and processes are built one
+// after the other. A build only compiles the guest crate again, sharing the dependency build is worth the wait.
+type openVMCache struct {
+	dir      string
+	maxBytes int64
+}
+
+// openVMCacheFromEnv is nil when the cache is disabled or there is no user cache directory.
+func openVMCacheFromEnv() *openVMCache {
+	dir := os.Getenv(openVMCacheEnv)
+	if dir == openVMCacheDisabled {
+		return nil
+	}
+	if dir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(userCache, "erigon-transpiler-risc-v", "openvm")
+	}
+	sizeMB := int64(defaultOpenVMCacheSizeMB)
+	if value := os.Getenv(openVMCacheSizeEnv); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil && parsed > 0 {
+			sizeMB = parsed
+		}
+	}
+	return &openVMCache{dir: dir, maxBytes: sizeMB << 20}
+}
+
+// toolchainDir is the directory of the embedded guest crate with the bigint crate and openvm.toml.
+func (c *openVMCache) toolchainDir() (string, error) {
+	key, err := toolchainKey(zkVMToolchain)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.dir, "toolchain-"+key[:16]), nil
+}
+
+// programKey hashes what the program depends on: the toolchain, lib.asm and the transpiled risc.asm.
+func (c *openVMCache) programKey(assembly string) (string, error) {
+	key, err := toolchainKey(zkVMToolchain)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.New()
+	writeCacheKeyPart(digest, []byte(key))
+	writeCacheKeyPart(digest, libFile)
+	writeCacheKeyPart(digest, []byte(assembly))
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// build places the executable of the assembly in the workspace, from the cache or built in the toolchain crate,
+// where only the guest crate is compiled again.
+func (c *openVMCache) build(ctx context.Context, cli *Cli, assembly string) (bool, error) {
+	key, err := c.programKey(assembly)
+	if err != nil {
+		return false, err
+	}
+	cli.cacheKey = key
+	entry := filepath.Join(c.dir, "programs", key+".vmexe")
+	exe := filepath.Join(cli.workSpace, openVMExePath)
+	if err := os.MkdirAll(filepath.Dir(exe), 0755); err != nil {
+		return false, err
+	}
+
+	// Entries are renamed into place, so a hit never reads a partial file
+	if err := copyFile(entry, exe); err == nil {
+		now := time.Now()
+		return true, os.Chtimes(entry, now, now)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	toolchain, err := c.toolchainDir()
+	if err != nil {
+		return false, err
+	}
+	err = withFileLock(ctx, filepath.Join(toolchain, "lock"), func() error {
+		crate, err := c.extract(toolchain)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(crate, "src", "risc.asm"), []byte(assembly), 0644); err != nil {
+			return err
+		}
+		builder := NewCli(crate)
+		if _, err := builder.Execute(ctx, "cargo", "openvm", "build"); err != nil {
+			return err
+		}
+		if err := copyFile(filepath.Join(crate, openVMExePath), exe); err != nil {
+			return err
+		}
+		if err := c.store(exe, entry); err != nil {
+			return err
+		}
+		return c.evict()
+	})
+	return false, err
+}
+
+// keygen links the keys of the toolchain into the workspace, they only depend on openvm.toml.
+func (c *openVMCache) keygen(ctx context.Context, cli *Cli) (bool, error) {
+	toolchain, err := c.toolchainDir()
+	if err != nil {
+		return false, err
+	}
+
+	cached := true
+	var crate string
+	err = withFileLock(ctx, filepath.Join(toolchain, "lock"), func() error {
+		crate, err = c.extract(toolchain)
+		if err != nil {
+			return err
+		}
+		marker := filepath.Join(toolchain, openVMKeysMarker)
+		if _, err := os.Stat(marker); err == nil {
+			return nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		cached = false
+		keygen := NewCli(crate)
+		if _, err := keygen.Execute(ctx, "cargo", "openvm", "keygen"); err != nil {
+			return err
+		}
+		return os.WriteFile(marker, nil, 0644)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, key := range []string{openVMAppPkPath, openVMAppVkPath} {
+		if err := os.Symlink(filepath.Join(crate, key), filepath.Join(cli.workSpace, key)); err != nil {
+			return false, err
+		}
+	}
+	return cached, nil
+}
+
+// extract writes the guest crate into the toolchain directory the first time, the lock has to be held.
+func (c *openVMCache) extract(toolchain string) (string, error) {
+	crate := filepath.Join(toolchain, "openvm")
+	marker := filepath.Join(toolchain, openVMExtractedMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return crate, nil
+	}
+	if err := extractEmbedFS(zkVMToolchain, toolchain); err != nil {
+		return "", err
+	}
+	return crate, os.WriteFile(marker, nil, 0644)
+}
+
+// store copies the executable next to the entry and renames it into place.
+func (c *openVMCache) store(exe, entry string) error {
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return err
+	}
+	tmp := entry + ".tmp"
+	if err := copyFile(exe, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, entry)
+}
+
+// evict removes the least recently used programs until they fit in the size limit, the lock has to be held.
+func (c *openVMCache) evict() error {
+	dir := filepath.Join(c.dir, "programs")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var programs []fs.FileInfo
+	var total int64
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".vmexe" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		programs = append(programs, info)
+		total += info.Size()
+	}
+	sort.Slice(programs, func(i, j int) bool {
+		return programs[i].ModTime().Before(programs[j].ModTime())
+	})
+
+	for _, program := range programs {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(dir, program.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		total -= program.Size()
+	}
+	return nil
+}
+
+// toolchainKey hashes the paths and contents of the embedded toolchain.
+func toolchainKey(toolchain fs.ReadFileFS) (string, error) {
+	digest := sha256.New()
+	err := fs.WalkDir(toolchain, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := toolchain.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		writeCacheKeyPart(digest, []byte(path))
+		writeCacheKeyPart(digest, content)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// writeCacheKeyPart length prefixes the part, so different splits of the same bytes hash differently.
+func writeCacheKeyPart(digest hash.Hash, part []byte) {
+	digest.Write(binary.BigEndian.AppendUint64(nil, uint64(len(part))))
+	digest.Write(part)
+}
+
+// withFileLock runs the function holding an exclusive lock of the file, shared by all processes. The lock is polled,
+// a blocking flock couldn't be interrupted when the context is canceled.
+func withFileLock(ctx context.Context, path string, run func() error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	for {
+		err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) && !errors.Is(err, syscall.EINTR) {
+			return fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the lock of %s: %w", path, ctx.Err())
+		case <-time.After(fileLockPollInterval):
+		}
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+	return run()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -6,6 +6,7 @@ import (
 	"embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -33,21 +34,72 @@ func (e *ZkProverError) Error() string {
 	return fmt.Sprintf("ZkProver error: %s: %v", e.Message, e.Underlying)
 }
 
-//go:embed openvm/*
-var zkVMToolchain embed.FS
-
 type ZkProver struct {
 	content string
+	backend Backend
+	// Optional, used to count the executed instructions
+	file   *AssemblyFile
+	cycles *CycleReport
 }
 
 func NewZkProver(content string) *ZkProver {
+	return NewZkProverWithBackend(content, NewOpenVMBackend())
+}
+
+func NewZkProverWithBackend(content string, backend Backend) *ZkProver {
 	return &ZkProver{
 		content: content,
+		backend: backend,
+	}
+}
+
+// NewZkProverFromAssembly proves the assembly on the backend and reports its executed instructions.
+func NewZkProverFromAssembly(file *AssemblyFile, backend Backend) (*ZkProver, error) {
+	content, err := file.ToBackendAssembly(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ZkProver{
+		content: content,
+		backend: backend,
+		file:    file,
+	}, nil
+}
+
+func (zkVm *ZkProver) Backend() Backend {
+	return zkVm.backend
+}
+
+// Content is the assembly built and proven on the backend.
+func (zkVm *ZkProver) Content() string {
+	return zkVm.content
+}
+
+// CountCycles counts the executed instructions of the assembly once, the proof reuses the report.
+func (zkVm *ZkProver) CountCycles() (*CycleReport, error) {
+	if zkVm.cycles != nil {
+		return zkVm.cycles, nil
+	}
+	if zkVm.file == nil {
+		return nil, fmt.Errorf("no assembly file to count the cycles of, use NewZkProverFromAssembly")
 	}
+	cycles, err := zkVm.file.CountCycles()
+	if err != nil {
+		return nil, err
+	}
+	zkVm.cycles = cycles
+	return cycles, nil
 }
 
 type Cli struct {
 	workSpace string
+	// Temporary directory holding the workspace, removed once the artifacts were read
+	tmpDir string
+	// Only set when the backend caches its builds, see openVMCache
+	cacheKey     string
+	buildCached  bool
+	keygenCached bool
 }
 
 func NewCli(workSpace string) Cli {
@@ -85,21 +137,30 @@ func (cli *Cli) readFile(name string) ([]byte, error) {
 	return content, nil
 }
 
+func (cli *Cli) removeWorkspace() {
+	if cli.tmpDir != "" {
+		os.RemoveAll(cli.tmpDir)
+	}
+}
+
 type ProofTiming struct {
-	BuildTimeMs  int64
-	KeygenTimeMs int64
-	SetupTimeMs  int64
-	ProveTimeMs  int64
-	ReadTimeMs   int64
-	TotalTimeMs  int64
+	BuildTimeMs      int64
+	KeygenTimeMs     int64
+	BuildCached      bool
+	KeygenCached     bool
+	SetupTimeMs      int64
+	ProveTimeMs      int64
+	CycleCountTimeMs int64
+	TotalTimeMs      int64
 }
 
 type ProofGeneration struct {
-	Proof                 []byte
-	AppVK                 []byte
-	Stdout                string
-	Timing                ProofTiming
-	EstimatedInstructions int64
+	Proof  []byte
+	AppVK  []byte
+	Stdout string
+	Timing ProofTiming
+	// Only set when the prover was created from an AssemblyFile
+	Cycles *CycleReport
 }
 
 type VerificationResult struct {
@@ -108,130 +169,77 @@ type VerificationResult struct {
 }
 
 type ResultsFile struct {
-	AppVK string `json:"AppVK"`
-	Proof string `json:"Proof"`
+	AppVK   string `json:"AppVK"`
+	Proof   string `json:"Proof"`
+	Backend string `json:"Backend,omitempty"`
+	// Not needed for verification
+	Soundness *SoundnessReport `json:"Soundness,omitempty"`
 }
 
 func (zkVm *ZkProver) Prove(ctx context.Context) (ProofGeneration, error) {
-	setupStart := time.Now()
-	cli, setupTiming, err := zkVm.SetupExecution(ctx)
-	if err != nil {
-		return ProofGeneration{}, NewZkProverError("failed to setup execution", err)
-	}
-	setupTime := time.Since(setupStart)
-
-	proveStart := time.Now()
-	output, err := cli.Execute(ctx, "cargo", "openvm", "prove", "app")
-	if err != nil {
-		return ProofGeneration{}, NewZkProverError("failed to execute prove command", err)
-	}
-	proveTime := time.Since(proveStart)
-
-	readStart := time.Now()
-	proof, err := cli.readFile("prover.app.proof")
-	if err != nil {
-		return ProofGeneration{}, err
-	}
-	appVk, err := cli.readFile("target/openvm/app.vk")
-	if err != nil {
-		return ProofGeneration{}, err
-	}
-	estimatedInstructions := zkVm.getEstimatedInstructionCount(cli)
-
-	readTime := time.Since(readStart)
-
-	totalTime := setupTime + proveTime + readTime
-
-	results := ProofGeneration{
-		Proof:  proof,
-		AppVK:  appVk,
-		Stdout: output,
-		Timing: ProofTiming{
-			BuildTimeMs:  setupTiming.BuildTimeMs,
-			KeygenTimeMs: setupTiming.KeygenTimeMs,
-			SetupTimeMs:  setupTime.Milliseconds(),
-			ProveTimeMs:  proveTime.Milliseconds(),
-			ReadTimeMs:   readTime.Milliseconds(),
-			TotalTimeMs:  totalTime.Milliseconds(),
-		},
-		EstimatedInstructions: estimatedInstructions,
-	}
-
-	return results, nil
+	return zkVm.prove(ctx, ProofKindApp)
 }
 
 func (zkVm *ZkProver) StarkProve(ctx context.Context) (ProofGeneration, error) {
+	return zkVm.prove(ctx, ProofKindStark)
+}
+
+func (zkVm *ZkProver) prove(ctx context.Context, kind ProofKind) (ProofGeneration, error) {
 	setupStart := time.Now()
 	cli, setupTiming, err := zkVm.SetupExecution(ctx)
 	if err != nil {
 		return ProofGeneration{}, NewZkProverError("failed to setup execution", err)
 	}
+	defer cli.removeWorkspace()
 	setupTime := time.Since(setupStart)
 
 	proveStart := time.Now()
-	output, err := cli.Execute(ctx, "cargo", "openvm", "prove", "stark")
+	artifacts, err := zkVm.backend.Prove(ctx, cli, kind)
 	if err != nil {
 		return ProofGeneration{}, NewZkProverError("failed to execute prove command", err)
 	}
 	proveTime := time.Since(proveStart)
 
-	readStart := time.Now()
-	proof, err := cli.readFile("prover.stark.proof")
-	if err != nil {
-		return ProofGeneration{}, err
-	}
-	appVk, err := os.ReadFile("/root/.openvm/agg_stark.vk")
-	if err != nil {
-		return ProofGeneration{}, err
+	cycleCountStart := time.Now()
+	var cycles *CycleReport
+	if zkVm.file != nil {
+		cycles, err = zkVm.CountCycles()
+		if err != nil {
+			return ProofGeneration{}, NewZkProverError("failed to count cycles", err)
+		}
 	}
-	
-	estimatedInstructions := zkVm.getEstimatedInstructionCount(cli)
-	
-	readTime := time.Since(readStart)
+	cycleCountTime := time.Since(cycleCountStart)
 
-	totalTime := setupTime + proveTime + readTime
+	totalTime := setupTime + proveTime + cycleCountTime
 
 	results := ProofGeneration{
-		Proof:  proof,
-		AppVK:  appVk,
-		Stdout: output,
+		Proof:  artifacts.Proof,
+		AppVK:  artifacts.VerifyingKey,
+		Stdout: artifacts.Stdout,
 		Timing: ProofTiming{
-			BuildTimeMs:  setupTiming.BuildTimeMs,
-			KeygenTimeMs: setupTiming.KeygenTimeMs,
-			SetupTimeMs:  setupTime.Milliseconds(),
-			ProveTimeMs:  proveTime.Milliseconds(),
-			ReadTimeMs:   readTime.Milliseconds(),
-			TotalTimeMs:  totalTime.Milliseconds(),
+			BuildTimeMs:      setupTiming.BuildTimeMs,
+			KeygenTimeMs:     setupTiming.KeygenTimeMs,
+			BuildCached:      setupTiming.BuildCached,
+			KeygenCached:     setupTiming.KeygenCached,
+			SetupTimeMs:      setupTime.Milliseconds(),
+			ProveTimeMs:      proveTime.Milliseconds(),
+			CycleCountTimeMs: cycleCountTime.Milliseconds(),
+			TotalTimeMs:      totalTime.Milliseconds(),
 		},
-		EstimatedInstructions: estimatedInstructions,
+		Cycles: cycles,
 	}
 
 	return results, nil
 }
 
 func (zkVm *ZkProver) TestRun(ctx context.Context) (string, error) {
-	cli, _, err := zkVm.SetupExecution(ctx)
-	if err != nil {
-		return "", err
-	}
-
-	output, err := cli.Execute(ctx, "cargo", "openvm", "run")
+	cli, err := zkVm.backend.Build(ctx, zkVm.content)
 	if err != nil {
 		return "", err
 	}
+	defer cli.removeWorkspace()
 
-	executionOutput := ""
-	for _, line := range bytes.Split([]byte(output), []byte("\n")) {
-		if bytes.HasPrefix(line, []byte("Execution output:")) {
-			executionOutput = string(line)
-			break
-		}
-	}
-	if executionOutput == "" {
-		return "", fmt.Errorf("execution output not found in the output: %s", output)
-	}
-
-	return executionOutput, nil
+	return zkVm.backend.Execute(ctx, cli)
 }
 
 func VerifyFromResults(ctx context.Context, resultsPath string) (VerificationResult, error) {
@@ -259,29 +267,20 @@ func VerifyFromResults(ctx context.Context, resultsPath string) (VerificationRes
 		return VerificationResult{}, NewZkProverError("failed to decode Proof hex", err)
 	}
 
-	tmpDir, err := os.MkdirTemp("", "openvm-verify-*")
-	if err != nil {
-		return VerificationResult{}, NewZkProverError("failed to create temp directory", err)
+	// Older results files don't record the backend, those are OpenVM proofs
+	backendName := results.Backend
+	if backendName == "" {
+		backendName = NewOpenVMBackend().Name()
 	}
-	defer os.RemoveAll(tmpDir)
-
-	appVKPath := filepath.Join(tmpDir, "app.vk")
-	if err := os.WriteFile(appVKPath, appVKBytes, 0644); err != nil {
-		return VerificationResult{}, NewZkProverError("failed to write app.vk file", err)
-	}
-
-	proofPath := filepath.Join(tmpDir, "proof.app.proof")
-	if err := os.WriteFile(proofPath, proofBytes, 0644); err != nil {
-		return VerificationResult{}, NewZkProverError("failed to write proof file", err)
-	}
-
-	cli := NewCli(tmpDir)
-	output, err := cli.Execute(ctx, "cargo", "openvm", "verify", "app", "--app-vk", appVKPath, "--proof", proofPath)
-	result := VerificationResult{
-		Stdout: output,
-		Valid:  err == nil,
+	backend, err := NewBackend(backendName)
+	if err != nil {
+		return VerificationResult{}, NewZkProverError("failed to select backend", err)
 	}
 
+	result, err := backend.Verify(ctx, ProofArtifacts{
+		Proof:        proofBytes,
+		VerifyingKey: appVKBytes,
+	})
 	if err != nil {
 		return result, NewZkProverError("verification failed", err)
 	}
@@ -292,26 +291,24 @@ func VerifyFromResults(ctx context.Context, resultsPath string) (VerificationRes
 type SetupTiming struct {
 	BuildTimeMs  int64
 	KeygenTimeMs int64
+	// Build cache of the backend, the key is empty when it has none
+	CacheKey     string
+	BuildCached  bool
+	KeygenCached bool
 }
 
 func (zkVm *ZkProver) SetupExecution(ctx context.Context) (*Cli, SetupTiming, error) {
-	workSpace, err := setupWorkspace([]byte(zkVm.content))
-	if err != nil {
-		return nil, SetupTiming{}, NewZkProverError("failed to setup workspace", err)
-	}
-
-	cli := NewCli(workSpace)
-
 	buildStart := time.Now()
-	_, err = cli.Execute(ctx, "cargo", "openvm", "build")
+	cli, err := zkVm.backend.Build(ctx, zkVm.content)
 	if err != nil {
 		return nil, SetupTiming{}, NewZkProverError("failed to build project", err)
 	}
 	buildTime := time.Since(buildStart)
 
 	keygenStart := time.Now()
-	_, err = cli.Execute(ctx, "cargo", "openvm", "keygen")
+	err = zkVm.backend.Keygen(ctx, cli)
 	if err != nil {
+		cli.removeWorkspace()
 		return nil, SetupTiming{}, NewZkProverError("failed to generate keys", err)
 	}
 	keygenTime := time.Since(keygenStart)
@@ -319,31 +316,73 @@ func (zkVm *ZkProver) SetupExecution(ctx context.Context) (*Cli, SetupTiming, er
 	timing := SetupTiming{
 		BuildTimeMs:  buildTime.Milliseconds(),
 		KeygenTimeMs: keygenTime.Milliseconds(),
+		CacheKey:     cli.cacheKey,
+		BuildCached:  cli.buildCached,
+		KeygenCached: cli.keygenCached,
 	}
 
-	return &cli, timing, nil
+	return cli, timing, nil
 }
 
-func setupWorkspace(newRiscContent []byte) (string, error) {
+// setupWorkspace extracts an embedded guest crate to a temporary directory and writes the assembly to it, the
+// directory is the parent of the returned workspace.
+func setupWorkspace(toolchain embed.FS, crate string, assemblyPath string, assembly []byte) (string, error) {
 	tmpDir, err := os.MkdirTemp("", "zkvm-toolchain-*")
 	if err != nil {
 		return "", err
 	}
 
-	if err := extractEmbedFS(zkVMToolchain, tmpDir); err != nil {
+	if err := extractEmbedFS(toolchain, tmpDir); err != nil {
+		os.RemoveAll(tmpDir)
 		return "", err
 	}
 
-	workspaceDirectory := path.Join(tmpDir, "openvm")
+	workspaceDirectory := path.Join(tmpDir, crate)
 
-	riscPath := filepath.Join(workspaceDirectory, "src", "risc.asm")
-	if err := os.WriteFile(riscPath, newRiscContent, 0644); err != nil {
-		return "", fmt.Errorf("failed to write risc.asm: %w", err)
+	riscPath := filepath.Join(workspaceDirectory, assemblyPath)
+	if err := os.WriteFile(riscPath, assembly, 0644); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to write %s: %w", assemblyPath, err)
 	}
 
 	return workspaceDirectory, nil
 }
 
+// hostWorkspace extracts the toolchain into the user cache directory and builds its host the first time, later
+// calls with the same toolchain reuse the build. The guest is left as the template, it is only used to verify.
+func hostWorkspace(ctx context.Context, toolchain embed.FS, crate string, build func(cli *Cli) error) (*Cli, error) {
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	key, err := toolchainKey(toolchain)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(userCache, "erigon-transpiler-risc-v", crate+"-host-"+key[:16])
+
+	cli := NewCli(filepath.Join(dir, crate))
+	err = withFileLock(ctx, filepath.Join(dir, "lock"), func() error {
+		marker := filepath.Join(dir, "built")
+		if _, err := os.Stat(marker); err == nil {
+			return nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := extractEmbedFS(toolchain, dir); err != nil {
+			return err
+		}
+		if err := build(&cli); err != nil {
+			return err
+		}
+		return os.WriteFile(marker, nil, 0644)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cli, nil
+}
+
 func extractEmbedFS(fsys embed.FS, dstDir string) error {
 	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil || path == "." {
@@ -383,13 +422,3 @@ func extractFile(fsys embed.FS, srcPath, dstPath string) error {
 	_, err = io.Copy(dst, src)
 	return err
 }
-
-func (zkVm *ZkProver) getEstimatedInstructionCount(cli *Cli) int64 {
-	output, err := cli.Execute(context.Background(), "riscv64-unknown-elf-objdump", "-d", "target/riscv32im-risc0-zkvm-elf/release/prover")
-	if err != nil {
-		return 0
-	}
-
-	lines := bytes.Split([]byte(output), []byte("\n"))
-	return int64(len(lines))
-}
@@ -0,0 +1,68 @@
+use clap::{Parser, Subcommand};
+use methods::{PROVER_ELF, PROVER_ID};
+use risc0_zkvm::{default_executor, default_prover, sha::Digest, ExecutorEnv, ProverOpts, Receipt};
+
+#[derive(Parser)]
+struct Args {
+    #[command(subcommand)]
+    command: Command,
+}
+
+#[derive(Subcommand)]
+enum Command {
+    Execute,
+    Prove {
+        /// composite or succinct
+        #[arg(long, default_value = "composite")]
+        mode: String,
+        #[arg(long, default_value = "receipt.bin")]
+        receipt: String,
+        #[arg(long, default_value = "image_id.bin")]
+        image_id: String,
+    },
+    Verify {
+        #[arg(long)]
+        receipt: String,
+        #[arg(long)]
+        image_id: String,
+    },
+}
+
+fn main() -> anyhow::Result<()> {
+    let args = Args::parse();
+
+    match args.command {
+        Command::Execute => {
+            let env = ExecutorEnv::builder().build()?;
+            let session = default_executor().execute(env, PROVER_ELF)?;
+            println!(
+                "Execution output: cycles={} journal={:?}",
+                session.cycles(),
+                session.journal.bytes
+            );
+        }
+        Command::Prove {
+            mode,
+            receipt,
+            image_id,
+        } => {
+            let opts = match mode.as_str() {
+                "composite" => ProverOpts::composite(),
+                "succinct" => ProverOpts::succinct(),
+                other => anyhow::bail!("unknown proof mode {other}"),
+            };
+            let env = ExecutorEnv::builder().build()?;
+            let info = default_prover().prove_with_opts(env, PROVER_ELF, &opts)?;
+            std::fs::write(&receipt, bincode::serialize(&info.receipt)?)?;
+            std::fs::write(&image_id, bincode::serialize(&Digest::from(PROVER_ID))?)?;
+            println!("Receipt written to {receipt}");
+        }
+        Command::Verify { receipt, image_id } => {
+            let receipt: Receipt = bincode::deserialize(&std::fs::read(&receipt)?)?;
+            let image_id: Digest = bincode::deserialize(&std::fs::read(&image_id)?)?;
+            receipt.verify(image_id)?;
+            println!("Proof verified");
+        }
+    }
+    Ok(())
+}
@@ -0,0 +1,28 @@
+#![no_main]
+risc0_zkvm::guest::entry!(main);
+
+use risc0_zkvm::guest::env;
+use std::arch::global_asm;
+
+global_asm!(include_str!("./risc.asm"));
+
+// Same helpers as the OpenVM guest so the transpiled assembly can call them
+#[unsafe(no_mangle)]
+extern "C" fn read_u64_func() -> u64 {
+    env::read::<u64>()
+}
+
+#[unsafe(no_mangle)]
+extern "C" fn reveal_u32_func(val: u32, idx: u32) {
+    env::commit(&(idx, val));
+}
+
+unsafe extern "C" {
+    fn execute();
+}
+
+fn main() {
+    unsafe {
+        execute();
+    }
+}
@@ -0,0 +1,3 @@
+// Will be generated
+execute:
+    ret
@@ -0,0 +1,141 @@
+package prover
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//go:embed risc0/*
+var risc0Toolchain embed.FS
+
+const (
+	risc0ReceiptFile = "receipt.bin"
+	risc0ImageIdFile = "image_id.bin"
+)
+
+// RiscZeroBackend runs the assembly inside a RISC Zero guest, the host crate drives execute/prove/verify.
+type RiscZeroBackend struct{}
+
+func NewRiscZeroBackend() *RiscZeroBackend {
+	return &RiscZeroBackend{}
+}
+
+func (b *RiscZeroBackend) Name() string {
+	return "risc0"
+}
+
+// The bigint precompiles are OpenVM specific, RISC Zero uses the lib.asm routines.
+func (b *RiscZeroBackend) PrecompileSymbol(function string) string {
+	return function
+}
+
+func (b *RiscZeroBackend) KeepsBreakpoints() bool {
+	return false
+}
+
+func (b *RiscZeroBackend) Assemble(file *AssemblyFile) (string, error) {
+	return file.toGuestAssembly(b), nil
+}
+
+// Build compiles the host, the guest is compiled and embedded by the methods crate.
+func (b *RiscZeroBackend) Build(ctx context.Context, assembly string) (*Cli, error) {
+	workSpace, err := setupWorkspace(risc0Toolchain, "risc0", filepath.Join("methods", "guest", "src", "risc.asm"), []byte(assembly))
+	if err != nil {
+		return nil, NewZkProverError("failed to setup workspace", err)
+	}
+
+	cli := NewCli(workSpace)
+	cli.tmpDir = filepath.Dir(workSpace)
+	if _, err := cli.Execute(ctx, "cargo", "build", "--release", "--bin", "host"); err != nil {
+		cli.removeWorkspace()
+		return nil, err
+	}
+	return &cli, nil
+}
+
+// The image id is computed when the guest is built.
+func (b *RiscZeroBackend) Keygen(ctx context.Context, cli *Cli) error {
+	return nil
+}
+
+func (b *RiscZeroBackend) Execute(ctx context.Context, cli *Cli) (string, error) {
+	output, err := cli.Execute(ctx, "cargo", "run", "--release", "--bin", "host", "--", "execute")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range bytes.Split([]byte(output), []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("Execution output:")) {
+			return string(line), nil
+		}
+	}
+	return "", fmt.Errorf("execution output not found in the output: %s", output)
+}
+
+func (b *RiscZeroBackend) Prove(ctx context.Context, cli *Cli, kind ProofKind) (ProofArtifacts, error) {
+	var mode string
+	switch kind {
+	case ProofKindApp:
+		mode = "composite"
+	case ProofKindStark:
+		mode = "succinct"
+	default:
+		return ProofArtifacts{}, fmt.Errorf("unknown proof kind %d", kind)
+	}
+
+	output, err := cli.Execute(ctx, "cargo", "run", "--release", "--bin", "host", "--",
+		"prove", "--mode", mode, "--receipt", risc0ReceiptFile, "--image-id", risc0ImageIdFile)
+	if err != nil {
+		return ProofArtifacts{}, err
+	}
+	receipt, err := cli.readFile(risc0ReceiptFile)
+	if err != nil {
+		return ProofArtifacts{}, err
+	}
+	imageId, err := cli.readFile(risc0ImageIdFile)
+	if err != nil {
+		return ProofArtifacts{}, err
+	}
+	return ProofArtifacts{
+		Proof:        receipt,
+		VerifyingKey: imageId,
+		Stdout:       output,
+	}, nil
+}
+
+func (b *RiscZeroBackend) Verify(ctx context.Context, artifacts ProofArtifacts) (VerificationResult, error) {
+	// The image id comes from the results, so the host is built once without the guest and reused
+	cli, err := hostWorkspace(ctx, risc0Toolchain, "risc0", func(cli *Cli) error {
+		_, err := cli.Execute(ctx, "env", "RISC0_SKIP_BUILD=1", "cargo", "build", "--release", "--bin", "host")
+		return err
+	})
+	if err != nil {
+		return VerificationResult{}, NewZkProverError("failed to build the host", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "risc0-verify-*")
+	if err != nil {
+		return VerificationResult{}, NewZkProverError("failed to create temp directory", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	receiptPath := filepath.Join(tmpDir, risc0ReceiptFile)
+	if err := os.WriteFile(receiptPath, artifacts.Proof, 0644); err != nil {
+		return VerificationResult{}, NewZkProverError("failed to write receipt file", err)
+	}
+	imageIdPath := filepath.Join(tmpDir, risc0ImageIdFile)
+	if err := os.WriteFile(imageIdPath, artifacts.VerifyingKey, 0644); err != nil {
+		return VerificationResult{}, NewZkProverError("failed to write image id file", err)
+	}
+
+	output, err := cli.Execute(ctx, "env", "RISC0_SKIP_BUILD=1", "cargo", "run", "--release", "--bin", "host", "--",
+		"verify", "--receipt", receiptPath, "--image-id", imageIdPath)
+	return VerificationResult{
+		Stdout: output,
+		Valid:  err == nil,
+	}, err
+}
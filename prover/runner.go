@@ -0,0 +1,111 @@
+package prover
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/holiman/uint256"
+)
+
+const EbreakInstr = 0x00100073
+
+// Memory layout shared by the runners: the program is mapped from address 0 to the end of its sections, see
+// programMemorySize, and the stack grows down from runnerStackEnd.
+const (
+	runnerStackEnd = 0x80000000
+	// Large enough for the deepest call frames of a block
+	runnerStackSize        = 16 << 20
+	runnerStackTop         = runnerStackEnd - 16
+	runnerContextStackTop  = 0x6fff0000
+	runnerContextStackSize = 0x10000
+	// Mapped even for small programs, which used to be placed at fixed addresses in it
+	minProgramMemorySize = 0x20000
+)
+
+// programMemorySize is the page aligned size of the memory from address 0 to the end of the ELF sections.
+func programMemorySize(bytecode []byte) (uint32, error) {
+	elfFile, err := elf.NewFile(bytes.NewReader(bytecode))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ELF file: %v", err)
+	}
+	defer elfFile.Close()
+
+	end := uint64(minProgramMemorySize)
+	for _, section := range elfFile.Sections {
+		if section.Flags&elf.SHF_ALLOC != 0 {
+			end = max(end, section.Addr+section.Size)
+		}
+	}
+	size := (end + 0xfff) &^ 0xfff
+	if size > runnerContextStackTop-runnerContextStackSize {
+		return 0, fmt.Errorf("program of 0x%x bytes overlaps the call context stack", size)
+	}
+	return uint32(size), nil
+}
+
+type ExecutionResult struct {
+	StackSnapshots *[][]uint256.Int
+}
+
+// Runner executes the debug bytecode from ToBytecode and collects the EBREAK stack snapshots.
+type Runner interface {
+	Execute(bytecode []byte) (*ExecutionResult, error)
+}
+
+// readStackSnapshot decodes the 256-bit entries between sp and the logical stack top.
+func readStackSnapshot(sp, logicalStackTop uint64, readWord func(addr uint64) (uint32, error)) ([]uint256.Int, error) {
+	stackTop := logicalStackTop
+	if stackTop == 0 {
+		stackTop = runnerStackTop
+	}
+
+	if sp > stackTop {
+		return nil, fmt.Errorf("stack pointer (%d) exceeds stack top (%d)", sp, stackTop)
+	}
+	numWords := (stackTop - sp) / 4
+	// 8 words per 256-bit entry (32-bit words)
+	numEntries := numWords / 8
+	stack := make([]uint256.Int, numEntries)
+	for i := range numEntries {
+		// Read 8 consecutive 4-byte words for each 256-bit entry
+		result := make([]byte, 32)
+		for wordIdx := 0; wordIdx < 8; wordIdx++ {
+			word, err := readWord(sp + ((i*8 + uint64(wordIdx)) * 4))
+			if err != nil {
+				return nil, err
+			}
+			// Place word in big-endian position (reverse word order)
+			resultStart := (7 - wordIdx) * 4
+			binary.BigEndian.PutUint32(result[resultStart:resultStart+4], word)
+		}
+		stack[uint64(len(stack)-1)-i].SetBytes(result)
+	}
+	return stack, nil
+}
+
+type RuntimeError struct {
+	Err   error
+	Stage string // "pre-runtime", "runtime" and "post-runtime"
+}
+
+func (e RuntimeError) Error() string {
+	return fmt.Sprintf("%s error: %v", e.Stage, e.Err)
+}
+
+func (e RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+func NewRuntimeError(err error) error {
+	return RuntimeError{Err: err, Stage: "runtime"}
+}
+
+func NewPreRuntimeError(err error) error {
+	return RuntimeError{Err: err, Stage: "pre-runtime"}
+}
+
+func NewPostRuntimeError(err error) error {
+	return RuntimeError{Err: err, Stage: "post-runtime"}
+}
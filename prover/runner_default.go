@@ -0,0 +1,8 @@
+//go:build !unicorn
+
+package prover
+
+// NewRunner uses the Go emulator, build with the unicorn tag to use Unicorn instead.
+func NewRunner() (Runner, error) {
+	return NewEmulatorRunner()
+}
@@ -1,39 +1,212 @@
 package prover
 
 import (
+	"bytes"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/erigontech/erigon-lib/crypto"
 )
 
+// SolidityContract is a compiled contract with its runtime source map.
+type SolidityContract struct {
+	Bytecode []byte
+	// One entry per instruction of the runtime bytecode
+	SourceMap []SourceMapEntry
+	// Source files by their index in the solc source list, the File of the source map entries
+	Sources map[int]SoliditySource
+	// Instruction index of each pc, built on the first Locate
+	instructions map[uint64]int
+}
+
+type SoliditySource struct {
+	Name    string
+	Content []byte
+}
+
+// SourceMapEntry is a decompressed solc source map entry, File is -1 for compiler generated code.
+type SourceMapEntry struct {
+	Start         int
+	Length        int
+	File          int
+	Jump          string
+	ModifierDepth int
+}
+
+type SolidityLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func (l SolidityLocation) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+type solcCombinedOutput struct {
+	Contracts map[string]struct {
+		BinRuntime    string `json:"bin-runtime"`
+		SrcmapRuntime string `json:"srcmap-runtime"`
+	} `json:"contracts"`
+	// Source files by the id the source maps use, their index
+	SourceList []string `json:"sourceList"`
+}
+
 func CompileSolidity(source, contractName string) ([]byte, error) {
-	tmpFile, err := os.CreateTemp("", "*.sol")
+	contract, err := CompileSolidityContract(source, contractName)
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(tmpFile.Name())
+	return contract.Bytecode, nil
+}
+
+// CompileSolidityContract compiles the source as <contractName>.sol and returns the contract with its source map.
+func CompileSolidityContract(source, contractName string) (*SolidityContract, error) {
+	tmpDir, err := os.MkdirTemp("", "solc-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fileName := contractName + ".sol"
+	if err := os.WriteFile(filepath.Join(tmpDir, fileName), []byte(source), 0644); err != nil {
+		return nil, err
+	}
+	return compileSolidity(tmpDir, fileName, contractName)
+}
 
-	tmpFile.WriteString(source)
-	tmpFile.Close()
+// CompileSolidityFile compiles a file on disk (e.g. contracts/Counter.sol), the locations use the path as given.
+func CompileSolidityFile(path, contractName string) (*SolidityContract, error) {
+	return compileSolidity("", path, contractName)
+}
 
-	cmd := exec.Command("solc", "--optimize", "--via-ir", "--bin-runtime", tmpFile.Name())
+func compileSolidity(dir, fileName, contractName string) (*SolidityContract, error) {
+	cmd := exec.Command("solc", "--optimize", "--via-ir", "--combined-json", "bin-runtime,srcmap-runtime", fileName)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("solc failed: %w: %s", err, stderr.String())
+	}
+
+	var combined solcCombinedOutput
+	if err := json.Unmarshal(output, &combined); err != nil {
+		return nil, fmt.Errorf("failed to parse solc output: %w", err)
+	}
+
+	compiled, ok := combined.Contracts[fileName+":"+contractName]
+	if !ok {
+		return nil, fmt.Errorf("contract %s not found in %s", contractName, fileName)
+	}
+	bytecode, err := hex.DecodeString(compiled.BinRuntime)
+	if err != nil {
+		return nil, err
+	}
+	sourceMap, err := ParseSourceMap(compiled.SrcmapRuntime)
 	if err != nil {
 		return nil, err
 	}
 
-	lines := strings.Split(string(output), "\n")
-	for i, line := range lines {
-		if strings.Contains(line, contractName) && i+2 < len(lines) {
-			bytecode, err := hex.DecodeString(lines[i+2])
-			return bytecode, err
+	sources := make(map[int]SoliditySource, len(combined.SourceList))
+	for id, name := range combined.SourceList {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources[id] = SoliditySource{Name: name, Content: content}
+	}
+
+	return &SolidityContract{
+		Bytecode:  bytecode,
+		SourceMap: sourceMap,
+		Sources:   sources,
+	}, nil
+}
+
+// ParseSourceMap decompresses a "s:l:f:j:m;..." source map, empty fields repeat the previous entry.
+func ParseSourceMap(sourceMap string) ([]SourceMapEntry, error) {
+	if sourceMap == "" {
+		return nil, nil
+	}
+
+	var entries []SourceMapEntry
+	var previous SourceMapEntry
+	for _, item := range strings.Split(sourceMap, ";") {
+		entry := previous
+		for i, field := range strings.Split(item, ":") {
+			if field == "" {
+				continue
+			}
+			if i == 3 {
+				entry.Jump = field
+				continue
+			}
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source map entry %q: %w", item, err)
+			}
+			switch i {
+			case 0:
+				entry.Start = value
+			case 1:
+				entry.Length = value
+			case 2:
+				entry.File = value
+			case 4:
+				entry.ModifierDepth = value
+			}
+		}
+		entries = append(entries, entry)
+		previous = entry
+	}
+	return entries, nil
+}
+
+// instructionIndexes maps the pc of every instruction to its index, the push data is skipped.
+func instructionIndexes(bytecode []byte) map[uint64]int {
+	indexes := make(map[uint64]int)
+	index := 0
+	for pc := 0; pc < len(bytecode); pc++ {
+		indexes[uint64(pc)] = index
+		index++
+		// PUSH1 to PUSH32
+		if op := bytecode[pc]; op >= 0x60 && op <= 0x7f {
+			pc += int(op-0x60) + 1
 		}
 	}
+	return indexes
+}
+
+// Locate returns the Solidity file, line and column (1-based) of the instruction at pc.
+func (c *SolidityContract) Locate(pc uint64) (SolidityLocation, bool) {
+	if c.instructions == nil {
+		c.instructions = instructionIndexes(c.Bytecode)
+	}
+	index, ok := c.instructions[pc]
+	if !ok || index >= len(c.SourceMap) {
+		return SolidityLocation{}, false
+	}
+	entry := c.SourceMap[index]
+	source, ok := c.Sources[entry.File]
+	if !ok || entry.Start < 0 || entry.Start > len(source.Content) {
+		return SolidityLocation{}, false
+	}
 
-	return nil, nil
+	before := source.Content[:entry.Start]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := entry.Start - (bytes.LastIndexByte(before, '\n') + 1) + 1
+	return SolidityLocation{File: source.Name, Line: line, Column: column}, true
 }
 
 func EncodeCallData(signature string) []byte {
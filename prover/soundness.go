@@ -0,0 +1,47 @@
+package prover
+
+// OpcodeClass describes how much of an EVM opcode is constrained by the proof.
+type OpcodeClass string
+
+const (
+	// Result computed by the transpiled code
+	OpcodeComputed OpcodeClass = "computed"
+	// Result computed by a lib.asm routine or zkVM precompile
+	OpcodeHostOptimized OpcodeClass = "host_optimized"
+	// Result copied from the next stack snapshot of the trace
+	OpcodeTraceSupplied OpcodeClass = "trace_supplied"
+	// Host-optimized opcode replaced by a zero when they are disabled
+	OpcodeDummyZero OpcodeClass = "dummy_zero"
+	// Arguments are popped, the side effects are not implemented
+	OpcodeNoOp OpcodeClass = "no_op"
+)
+
+type SoundnessReport struct {
+	// Opcode name => class => count
+	Opcodes map[string]map[OpcodeClass]int `json:"opcodes"`
+	Totals  map[OpcodeClass]int            `json:"totals"`
+	// Share of the opcodes that are computed or host-optimized
+	ConstrainedPercentage float64 `json:"constrained_percentage"`
+}
+
+func NewSoundnessReport() *SoundnessReport {
+	return &SoundnessReport{
+		Opcodes: make(map[string]map[OpcodeClass]int),
+		Totals:  make(map[OpcodeClass]int),
+	}
+}
+
+func (r *SoundnessReport) Add(opcode string, class OpcodeClass) {
+	if r.Opcodes[opcode] == nil {
+		r.Opcodes[opcode] = make(map[OpcodeClass]int)
+	}
+	r.Opcodes[opcode][class]++
+	r.Totals[class]++
+
+	total := 0
+	for _, count := range r.Totals {
+		total += count
+	}
+	constrained := r.Totals[OpcodeComputed] + r.Totals[OpcodeHostOptimized]
+	r.ConstrainedPercentage = 100 * float64(constrained) / float64(total)
+}
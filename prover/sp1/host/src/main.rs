@@ -0,0 +1,77 @@
+use clap::{Parser, Subcommand};
+use sp1_sdk::{ProverClient, SP1ProofWithPublicValues, SP1Stdin, SP1VerifyingKey};
+
+#[derive(Parser)]
+struct Args {
+    #[command(subcommand)]
+    command: Command,
+}
+
+#[derive(Subcommand)]
+enum Command {
+    Execute {
+        #[arg(long)]
+        elf: String,
+    },
+    Prove {
+        #[arg(long)]
+        elf: String,
+        /// core or compressed
+        #[arg(long, default_value = "core")]
+        mode: String,
+        #[arg(long, default_value = "proof.bin")]
+        proof: String,
+        #[arg(long, default_value = "vk.bin")]
+        vk: String,
+    },
+    Verify {
+        #[arg(long)]
+        proof: String,
+        #[arg(long)]
+        vk: String,
+    },
+}
+
+fn main() -> anyhow::Result<()> {
+    sp1_sdk::utils::setup_logger();
+    let args = Args::parse();
+    let client = ProverClient::from_env();
+    let stdin = SP1Stdin::new();
+
+    match args.command {
+        Command::Execute { elf } => {
+            let elf = std::fs::read(elf)?;
+            let (output, report) = client.execute(&elf, &stdin).run()?;
+            println!(
+                "Execution output: cycles={} public_values={:?}",
+                report.total_instruction_count(),
+                output.as_slice()
+            );
+        }
+        Command::Prove {
+            elf,
+            mode,
+            proof,
+            vk,
+        } => {
+            let elf = std::fs::read(elf)?;
+            let (pk, verifying_key) = client.setup(&elf);
+            let builder = client.prove(&pk, &stdin);
+            let result = match mode.as_str() {
+                "core" => builder.core().run()?,
+                "compressed" => builder.compressed().run()?,
+                other => anyhow::bail!("unknown proof mode {other}"),
+            };
+            result.save(&proof)?;
+            std::fs::write(&vk, bincode::serialize(&verifying_key)?)?;
+            println!("Proof written to {proof}");
+        }
+        Command::Verify { proof, vk } => {
+            let proof = SP1ProofWithPublicValues::load(&proof)?;
+            let vk: SP1VerifyingKey = bincode::deserialize(&std::fs::read(&vk)?)?;
+            client.verify(&proof, &vk)?;
+            println!("Proof verified");
+        }
+    }
+    Ok(())
+}
@@ -0,0 +1,155 @@
+package prover
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//go:embed sp1/*
+var sp1Toolchain embed.FS
+
+const (
+	sp1HostManifest = "host/Cargo.toml"
+	sp1ElfPath      = "program/target/elf-compilation/riscv32im-succinct-zkvm-elf/release/prover"
+	sp1ProofFile    = "proof.bin"
+	sp1VkFile       = "vk.bin"
+)
+
+// SP1Backend runs the assembly inside a SP1 guest, the host crate drives execute/prove/verify.
+type SP1Backend struct{}
+
+func NewSP1Backend() *SP1Backend {
+	return &SP1Backend{}
+}
+
+func (b *SP1Backend) Name() string {
+	return "sp1"
+}
+
+// The bigint precompiles are OpenVM specific, SP1 uses the lib.asm routines.
+func (b *SP1Backend) PrecompileSymbol(function string) string {
+	return function
+}
+
+func (b *SP1Backend) KeepsBreakpoints() bool {
+	return false
+}
+
+func (b *SP1Backend) Assemble(file *AssemblyFile) (string, error) {
+	return file.toGuestAssembly(b), nil
+}
+
+func (b *SP1Backend) Build(ctx context.Context, assembly string) (*Cli, error) {
+	workSpace, err := setupWorkspace(sp1Toolchain, "sp1", filepath.Join("program", "src", "risc.asm"), []byte(assembly))
+	if err != nil {
+		return nil, NewZkProverError("failed to setup workspace", err)
+	}
+
+	cli := NewCli(workSpace)
+	cli.tmpDir = filepath.Dir(workSpace)
+	programCli := NewCli(filepath.Join(workSpace, "program"))
+	if _, err := programCli.Execute(ctx, "cargo", "prove", "build"); err != nil {
+		cli.removeWorkspace()
+		return nil, err
+	}
+
+	if err := buildSP1Host(ctx, &cli); err != nil {
+		cli.removeWorkspace()
+		return nil, err
+	}
+	return &cli, nil
+}
+
+// SP1 derives the keys from the ELF when proving.
+func (b *SP1Backend) Keygen(ctx context.Context, cli *Cli) error {
+	return nil
+}
+
+func (b *SP1Backend) Execute(ctx context.Context, cli *Cli) (string, error) {
+	output, err := runSP1Host(ctx, cli, "execute", "--elf", sp1ElfPath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range bytes.Split([]byte(output), []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("Execution output:")) {
+			return string(line), nil
+		}
+	}
+	return "", fmt.Errorf("execution output not found in the output: %s", output)
+}
+
+func (b *SP1Backend) Prove(ctx context.Context, cli *Cli, kind ProofKind) (ProofArtifacts, error) {
+	var mode string
+	switch kind {
+	case ProofKindApp:
+		mode = "core"
+	case ProofKindStark:
+		mode = "compressed"
+	default:
+		return ProofArtifacts{}, fmt.Errorf("unknown proof kind %d", kind)
+	}
+
+	output, err := runSP1Host(ctx, cli, "prove", "--elf", sp1ElfPath, "--mode", mode, "--proof", sp1ProofFile, "--vk", sp1VkFile)
+	if err != nil {
+		return ProofArtifacts{}, err
+	}
+	proof, err := cli.readFile(sp1ProofFile)
+	if err != nil {
+		return ProofArtifacts{}, err
+	}
+	vk, err := cli.readFile(sp1VkFile)
+	if err != nil {
+		return ProofArtifacts{}, err
+	}
+	return ProofArtifacts{
+		Proof:        proof,
+		VerifyingKey: vk,
+		Stdout:       output,
+	}, nil
+}
+
+func (b *SP1Backend) Verify(ctx context.Context, artifacts ProofArtifacts) (VerificationResult, error) {
+	// Verification only needs the host, it is built once and reused
+	cli, err := hostWorkspace(ctx, sp1Toolchain, "sp1", func(cli *Cli) error {
+		return buildSP1Host(ctx, cli)
+	})
+	if err != nil {
+		return VerificationResult{}, NewZkProverError("failed to build the host", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "sp1-verify-*")
+	if err != nil {
+		return VerificationResult{}, NewZkProverError("failed to create temp directory", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	proofPath := filepath.Join(tmpDir, sp1ProofFile)
+	if err := os.WriteFile(proofPath, artifacts.Proof, 0644); err != nil {
+		return VerificationResult{}, NewZkProverError("failed to write proof file", err)
+	}
+	vkPath := filepath.Join(tmpDir, sp1VkFile)
+	if err := os.WriteFile(vkPath, artifacts.VerifyingKey, 0644); err != nil {
+		return VerificationResult{}, NewZkProverError("failed to write vk file", err)
+	}
+
+	output, err := runSP1Host(ctx, cli, "verify", "--proof", proofPath, "--vk", vkPath)
+	return VerificationResult{
+		Stdout: output,
+		Valid:  err == nil,
+	}, err
+}
+
+func buildSP1Host(ctx context.Context, cli *Cli) error {
+	_, err := cli.Execute(ctx, "cargo", "build", "--release", "--manifest-path", sp1HostManifest)
+	return err
+}
+
+func runSP1Host(ctx context.Context, cli *Cli, args ...string) (string, error) {
+	command := append([]string{"cargo", "run", "--release", "--manifest-path", sp1HostManifest, "--"}, args...)
+	return cli.Execute(ctx, command...)
+}
@@ -0,0 +1,83 @@
+package prover
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const unicornProgramFile = "program.elf"
+
+// UnicornBackend runs the debug assembly with NewRunner (Unicorn or the Go emulator), it can't generate proofs.
+type UnicornBackend struct{}
+
+func NewUnicornBackend() *UnicornBackend {
+	return &UnicornBackend{}
+}
+
+func (b *UnicornBackend) Name() string {
+	return "unicorn"
+}
+
+func (b *UnicornBackend) PrecompileSymbol(function string) string {
+	return function
+}
+
+func (b *UnicornBackend) KeepsBreakpoints() bool {
+	return true
+}
+
+func (b *UnicornBackend) Assemble(file *AssemblyFile) (string, error) {
+	return file.toDebugFile(), nil
+}
+
+func (b *UnicornBackend) Build(ctx context.Context, assembly string) (*Cli, error) {
+	workSpace, err := os.MkdirTemp("", "unicorn-*")
+	if err != nil {
+		return nil, NewZkProverError("failed to setup workspace", err)
+	}
+	cli := NewCli(workSpace)
+	cli.tmpDir = workSpace
+
+	bytecode, err := AssembleElf(assembly, DefaultElfLayout)
+	if err != nil {
+		cli.removeWorkspace()
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(workSpace, unicornProgramFile), bytecode, 0644); err != nil {
+		cli.removeWorkspace()
+		return nil, err
+	}
+
+	return &cli, nil
+}
+
+func (b *UnicornBackend) Keygen(ctx context.Context, cli *Cli) error {
+	return ErrNotSupported
+}
+
+func (b *UnicornBackend) Execute(ctx context.Context, cli *Cli) (string, error) {
+	bytecode, err := cli.readFile(unicornProgramFile)
+	if err != nil {
+		return "", err
+	}
+
+	runner, err := NewRunner()
+	if err != nil {
+		return "", err
+	}
+	result, err := runner.Execute(bytecode)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Execution output: %d stack snapshots", len(*result.StackSnapshots)), nil
+}
+
+func (b *UnicornBackend) Prove(ctx context.Context, cli *Cli, kind ProofKind) (ProofArtifacts, error) {
+	return ProofArtifacts{}, ErrNotSupported
+}
+
+func (b *UnicornBackend) Verify(ctx context.Context, artifacts ProofArtifacts) (VerificationResult, error) {
+	return VerificationResult{}, ErrNotSupported
+}
@@ -1,3 +1,5 @@
+//go:build unicorn
+
 package prover
 
 import (
@@ -10,60 +12,54 @@ import (
 	uc "github.com/unicorn-engine/unicorn/bindings/go/unicorn"
 )
 
-const EbreakInstr = 0x00100073
-
 type VmRunner struct{}
 
-type ExecutionResult struct {
-	StackSnapshots *[][]uint256.Int
-}
-
 func NewUnicornRunner() (*VmRunner, error) {
 	return &VmRunner{}, nil
 }
 
+// NewRunner uses Unicorn when built with the unicorn tag.
+func NewRunner() (Runner, error) {
+	return NewUnicornRunner()
+}
+
 func (vm *VmRunner) Execute(bytecode []byte) (*ExecutionResult, error) {
-	mu, err := uc.NewUnicorn(uc.ARCH_RISCV, uc.MODE_RISCV64)
+	// The guests are rv32im, like the Go emulator
+	mu, err := uc.NewUnicorn(uc.ARCH_RISCV, uc.MODE_RISCV32)
 	if err != nil {
 		return nil, err
 	}
 
-	codeAddr := uint64(0x10000)
-	stackAddr := uint64(0x7fff0000)
-	memSize := uint64(0x10000)
-
-	err = mu.MemMap(0, memSize)
+	programSize, err := programMemorySize(bytecode)
 	if err != nil {
 		return nil, NewPreRuntimeError(err)
 	}
-
-	err = mu.MemMap(codeAddr, memSize)
+	err = mu.MemMap(0, uint64(programSize))
 	if err != nil {
 		return nil, NewPreRuntimeError(err)
 	}
-	err = mu.MemMap(stackAddr, memSize)
+	err = mu.MemMap(runnerStackEnd-runnerStackSize, runnerStackSize)
 	if err != nil {
 		return nil, NewPreRuntimeError(err)
 	}
 
-	stackTop := stackAddr + memSize - 16
-	err = mu.RegWrite(uc.RISCV_REG_SP, stackTop)
+	err = mu.RegWrite(uc.RISCV_REG_SP, runnerStackTop)
 	if err != nil {
 		return nil, NewPreRuntimeError(err)
 	}
-	
-	err = mu.RegWrite(uc.RISCV_REG_S3, stackTop)
+
+	err = mu.RegWrite(uc.RISCV_REG_S3, runnerStackTop)
 	if err != nil {
 		return nil, NewPreRuntimeError(err)
 	}
-	
-	contextStackTop := uint64(0x6fff0000)
+
+	contextStackTop := uint64(runnerContextStackTop)
 	err = mu.RegWrite(uc.RISCV_REG_S1, contextStackTop)
 	if err != nil {
 		return nil, NewPreRuntimeError(err)
 	}
-	
-	err = mu.MemMap(contextStackTop-0x10000, 0x10000)
+
+	err = mu.MemMap(contextStackTop-runnerContextStackSize, runnerContextStackSize)
 	if err != nil {
 		return nil, NewPreRuntimeError(err)
 	}
@@ -101,7 +97,7 @@ func (vm *VmRunner) Execute(bytecode []byte) (*ExecutionResult, error) {
 		*/
 
 		if instr == uint32(EbreakInstr) {
-			snapshot, err := printStackState(mu, stackAddr, memSize)
+			snapshot, err := printStackState(mu)
 			if err != nil {
 				panic(NewRuntimeError(err))
 			}
@@ -197,41 +193,21 @@ func debugStackMemory(mu uc.Unicorn, stackBase, memSize uint64) {
 	}
 */
 
-func printStackState(mu uc.Unicorn, stackBase, memSize uint64) ([]uint256.Int, error) {
+func printStackState(mu uc.Unicorn) ([]uint256.Int, error) {
 	sp, _ := mu.RegRead(uc.RISCV_REG_SP)
-	
+
 	logicalStackTop, err := mu.RegRead(uc.RISCV_REG_S3)
-	var stackTop uint64
-	if err != nil || logicalStackTop == 0 {
-		stackTop = stackBase + memSize - 16
-	} else {
-		stackTop = logicalStackTop
+	if err != nil {
+		logicalStackTop = 0
 	}
 
-	if sp > stackTop {
-		return nil, fmt.Errorf("stack pointer (%d) exceeds stack top (%d)", sp, stackTop)
-	}
-	numWords := (stackTop - sp) / 4
-	// 8 words per 256-bit entry (32-bit words)
-	numEntries := numWords / 8
-	stack := make([]uint256.Int, numEntries)
-	for i := range numEntries {
-		// Read 8 consecutive 4-byte words for each 256-bit entry
-		result := make([]byte, 32)
-		for wordIdx := 0; wordIdx < 8; wordIdx++ {
-			addr := sp + ((i*8 + uint64(wordIdx)) * 4)
-			data, err := mu.MemRead(addr, 4)
-			if err != nil {
-				return nil, err
-			}
-			word := binary.LittleEndian.Uint32(data)
-			// Place word in big-endian position (reverse word order)
-			resultStart := (7 - wordIdx) * 4
-			binary.BigEndian.PutUint32(result[resultStart:resultStart+4], word)
+	return readStackSnapshot(sp, logicalStackTop, func(addr uint64) (uint32, error) {
+		data, err := mu.MemRead(addr, 4)
+		if err != nil {
+			return 0, err
 		}
-		stack[uint64(len(stack)-1)-i].SetBytes(result)
-	}
-	return stack, nil
+		return binary.LittleEndian.Uint32(data), nil
+	})
 }
 
 func loadElfSections(mu uc.Unicorn, bytecode []byte) (uint64, error) {
@@ -267,28 +243,3 @@ func loadElfSections(mu uc.Unicorn, bytecode []byte) (uint64, error) {
 
 	return elfFile.Entry, nil
 }
-
-type RuntimeError struct {
-	Err   error
-	Stage string // "pre-runtime", "runtime" and "post-runtime"
-}
-
-func (e RuntimeError) Error() string {
-	return fmt.Sprintf("%s error: %v", e.Stage, e.Err)
-}
-
-func (e RuntimeError) Unwrap() error {
-	return e.Err
-}
-
-func NewRuntimeError(err error) error {
-	return RuntimeError{Err: err, Stage: "runtime"}
-}
-
-func NewPreRuntimeError(err error) error {
-	return RuntimeError{Err: err, Stage: "pre-runtime"}
-}
-
-func NewPostRuntimeError(err error) error {
-	return RuntimeError{Err: err, Stage: "post-runtime"}
-}
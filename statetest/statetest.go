@@ -0,0 +1,330 @@
+// Package statetest runs the Ethereum GeneralStateTests fixtures against the transpiler.
+package statetest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+	"erigon-transpiler-risc-v/transpiler"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/erigontech/erigon-lib/chain"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/tests"
+	"github.com/holiman/uint256"
+)
+
+// Fixture is one test of a GeneralStateTests file
+type Fixture struct {
+	Env         Env                     `json:"env"`
+	Pre         map[string]Account      `json:"pre"`
+	Transaction Transaction             `json:"transaction"`
+	Post        map[string][]PostResult `json:"post"`
+}
+
+type Env struct {
+	Coinbase   string `json:"currentCoinbase"`
+	Difficulty string `json:"currentDifficulty"`
+	GasLimit   string `json:"currentGasLimit"`
+	Number     string `json:"currentNumber"`
+	Timestamp  string `json:"currentTimestamp"`
+	BaseFee    string `json:"currentBaseFee"`
+	Random     string `json:"currentRandom"`
+}
+
+type Account struct {
+	Balance string            `json:"balance"`
+	Code    string            `json:"code"`
+	Nonce   string            `json:"nonce"`
+	Storage map[string]string `json:"storage"`
+}
+
+type Transaction struct {
+	Data         []string `json:"data"`
+	GasLimit     []string `json:"gasLimit"`
+	Value        []string `json:"value"`
+	GasPrice     string   `json:"gasPrice"`
+	MaxFeePerGas string   `json:"maxFeePerGas"`
+	To           string   `json:"to"`
+	Sender       string   `json:"sender"`
+	SecretKey    string   `json:"secretKey"`
+}
+
+type PostResult struct {
+	Indexes struct {
+		Data  int `json:"data"`
+		Gas   int `json:"gas"`
+		Value int `json:"value"`
+	} `json:"indexes"`
+	ExpectException string `json:"expectException"`
+}
+
+type Status string
+
+const (
+	Pass Status = "pass"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+type Result struct {
+	Name   string `json:"name"`
+	Fork   string `json:"fork"`
+	Index  int    `json:"index"`
+	Status Status `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Load reads a GeneralStateTests JSON file, which can hold several fixtures.
+func Load(path string) (map[string]*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures map[string]*Fixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// Run executes every post entry of the fixture, optionally only for one fork.
+// The transaction is executed as a message call from the sender, the nonce, intrinsic gas and fees are not checked.
+// An entry passes when the transpiled code reproduces every EVM stack snapshot.
+func (st *Fixture) Run(name string, fork string) []Result {
+	forks := make([]string, 0, len(st.Post))
+	for postFork := range st.Post {
+		if fork == "" || postFork == fork {
+			forks = append(forks, postFork)
+		}
+	}
+	sort.Strings(forks)
+
+	var results []Result
+	for _, postFork := range forks {
+		for index, post := range st.Post[postFork] {
+			result := Result{Name: name, Fork: postFork, Index: index}
+			result.Status, result.Reason = st.runPost(postFork, post)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func (st *Fixture) runPost(fork string, post PostResult) (Status, string) {
+	if post.ExpectException != "" {
+		return Skip, "invalid transaction: " + post.ExpectException
+	}
+	if st.Transaction.To == "" {
+		return Skip, "contract creation"
+	}
+	chainConfig, ok := tests.Forks[fork]
+	if !ok {
+		return Skip, "unsupported fork"
+	}
+
+	indexes := post.Indexes
+	if indexes.Data >= len(st.Transaction.Data) || indexes.Gas >= len(st.Transaction.GasLimit) || indexes.Value >= len(st.Transaction.Value) {
+		return Fail, "post indexes out of range"
+	}
+
+	stateTracer, sender, err := st.newTracer(chainConfig)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	data, err := decodeHex(st.Transaction.Data[indexes.Data])
+	if err != nil {
+		return Fail, err.Error()
+	}
+	gasLimit, err := parseUint(st.Transaction.GasLimit[indexes.Gas])
+	if err != nil {
+		return Fail, err.Error()
+	}
+	value, err := parseUint(st.Transaction.Value[indexes.Value])
+	if err != nil {
+		return Fail, err.Error()
+	}
+
+	// Exceptional halts are expected by some fixtures, the trace up to the halt is still compared
+	instructions, executionState, _, err := stateTracer.ExecuteCall(sender, libcommon.HexToAddress(st.Transaction.To), data, gasLimit.Uint64(), value)
+	if err != nil && !executionError(err) {
+		return Fail, fmt.Sprintf("call: %v", err)
+	}
+	if len(instructions) == 0 {
+		return Skip, "no code executed"
+	}
+
+	txTranspiler := transpiler.NewTestTranspiler()
+	txTranspiler.EnableSnapshots()
+	snapshot, err := txTranspiler.ProcessExecution(instructions, executionState)
+	if err != nil {
+		return Fail, fmt.Sprintf("transpile: %v", err)
+	}
+	bytecode, err := txTranspiler.ToAssembly().ToBytecode()
+	if err != nil {
+		return Fail, fmt.Sprintf("assemble: %v", err)
+	}
+	runner, err := prover.NewRunner()
+	if err != nil {
+		return Fail, err.Error()
+	}
+	execution, err := runner.Execute(bytecode)
+	if err != nil {
+		return Fail, err.Error()
+	}
+
+	divergence := transpiler.FirstStackDivergence(snapshot.Snapshots, *execution.StackSnapshots)
+	if divergence != nil {
+		opcode := "end of trace"
+		if divergence.Snapshot < len(instructions) {
+			opcode = instructions[divergence.Snapshot].Opcode.String()
+		}
+		return Fail, fmt.Sprintf("stack diverged after instruction %d (%s) at slot %d", divergence.Snapshot, opcode, divergence.Slot)
+	}
+	return Pass, ""
+}
+
+// executionError reports whether err ends the execution of the code: a revert or an exceptional halt. Other errors,
+// e.g. an insufficient balance, mean the call couldn't be made.
+func executionError(err error) bool {
+	var invalidOpCode *vm.ErrInvalidOpCode
+	var stackUnderflow *vm.ErrStackUnderflow
+	var stackOverflow *vm.ErrStackOverflow
+	if errors.As(err, &invalidOpCode) || errors.As(err, &stackUnderflow) || errors.As(err, &stackOverflow) {
+		return true
+	}
+	halts := []error{
+		vm.ErrExecutionReverted, vm.ErrOutOfGas, vm.ErrCodeStoreOutOfGas, vm.ErrInvalidJump, vm.ErrWriteProtection,
+		vm.ErrReturnDataOutOfBounds, vm.ErrGasUintOverflow, vm.ErrInvalidCode, vm.ErrMaxCodeSizeExceeded,
+	}
+	return slices.ContainsFunc(halts, func(halt error) bool { return errors.Is(err, halt) })
+}
+
+func (st *Fixture) newTracer(chainConfig *chain.Config) (*tracer.SimpleTracer, libcommon.Address, error) {
+	env := tracer.DefaultTracerEnv()
+	env.ChainConfig = chainConfig
+	env.Coinbase = libcommon.HexToAddress(st.Env.Coinbase)
+
+	var err error
+	var values [4]*uint256.Int
+	for i, field := range []string{st.Env.GasLimit, st.Env.Number, st.Env.Timestamp, st.Env.Difficulty} {
+		if values[i], err = parseUint(field); err != nil {
+			return nil, libcommon.Address{}, err
+		}
+	}
+	env.GasLimit = values[0].Uint64()
+	env.BlockNumber = values[1].Uint64()
+	env.Time = values[2].Uint64()
+	env.Difficulty = values[3].ToBig()
+	if st.Env.BaseFee != "" {
+		if env.BaseFee, err = parseUint(st.Env.BaseFee); err != nil {
+			return nil, libcommon.Address{}, err
+		}
+	}
+	if st.Env.Random != "" {
+		random := libcommon.HexToHash(st.Env.Random)
+		env.PrevRanDao = &random
+	}
+
+	gasPrice := st.Transaction.GasPrice
+	if gasPrice == "" {
+		gasPrice = st.Transaction.MaxFeePerGas
+	}
+	if env.GasPrice, err = parseUint(gasPrice); err != nil {
+		return nil, libcommon.Address{}, err
+	}
+
+	sender, err := st.sender()
+	if err != nil {
+		return nil, libcommon.Address{}, err
+	}
+	env.Origin = sender
+
+	stateTracer := tracer.NewSimpleTracerWithEnv(env)
+	for address, account := range st.Pre {
+		if err := setupAccount(stateTracer, libcommon.HexToAddress(address), account); err != nil {
+			return nil, libcommon.Address{}, fmt.Errorf("pre-state of %s: %w", address, err)
+		}
+	}
+	return stateTracer, sender, nil
+}
+
+func (st *Fixture) sender() (libcommon.Address, error) {
+	if st.Transaction.Sender != "" {
+		return libcommon.HexToAddress(st.Transaction.Sender), nil
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(st.Transaction.SecretKey, "0x"))
+	if err != nil {
+		return libcommon.Address{}, fmt.Errorf("invalid secret key: %w", err)
+	}
+	return crypto.PubkeyToAddress(key.PublicKey), nil
+}
+
+func setupAccount(stateTracer *tracer.SimpleTracer, address libcommon.Address, account Account) error {
+	balance, err := parseUint(account.Balance)
+	if err != nil {
+		return err
+	}
+	nonce, err := parseUint(account.Nonce)
+	if err != nil {
+		return err
+	}
+	code, err := decodeHex(account.Code)
+	if err != nil {
+		return err
+	}
+	storage := make(map[libcommon.Hash]uint256.Int, len(account.Storage))
+	for key, value := range account.Storage {
+		keyValue, err := parseUint(key)
+		if err != nil {
+			return err
+		}
+		slotValue, err := parseUint(value)
+		if err != nil {
+			return err
+		}
+		storage[libcommon.Hash(keyValue.Bytes32())] = *slotValue
+	}
+	return stateTracer.SetupAccount(address, balance, nonce.Uint64(), code, storage)
+}
+
+// parseUint accepts the 0x prefixed hex and decimal numbers used by the fixtures
+func parseUint(value string) (*uint256.Int, error) {
+	if value == "" {
+		return uint256.NewInt(0), nil
+	}
+	base := 10
+	if strings.HasPrefix(value, "0x") {
+		value = value[2:]
+		base = 16
+	}
+	number, ok := new(big.Int).SetString(value, base)
+	if !ok && value == "" {
+		number, ok = new(big.Int), true
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	result, overflow := uint256.FromBig(number)
+	if overflow {
+		return nil, fmt.Errorf("number %q does not fit in 256 bits", value)
+	}
+	return result, nil
+}
+
+func decodeHex(value string) ([]byte, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex %q: %w", value, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,81 @@
+package statetest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// PUSH1 5, PUSH1 3, ADD, PUSH1 0, SSTORE, STOP
+const addFixture = `{
+  "add": {
+    "env": {
+      "currentCoinbase": "0x2adc25665018aa1fe0e6bc666dac8fc2697ff9ba",
+      "currentDifficulty": "0x020000",
+      "currentGasLimit": "0x05f5e100",
+      "currentNumber": "0x01",
+      "currentTimestamp": "0x03e8",
+      "currentBaseFee": "0x0a",
+      "currentRandom": "0x0000000000000000000000000000000000000000000000000000000000020000"
+    },
+    "pre": {
+      "0x1000000000000000000000000000000000000000": {
+        "balance": "0x00",
+        "code": "0x600560030160005500",
+        "nonce": "0x00",
+        "storage": {}
+      },
+      "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b": {
+        "balance": "0x3635c9adc5dea00000",
+        "code": "0x",
+        "nonce": "0x00",
+        "storage": {}
+      }
+    },
+    "transaction": {
+      "data": ["0x"],
+      "gasLimit": ["0x0186a0"],
+      "gasPrice": "0x0a",
+      "nonce": "0x00",
+      "secretKey": "0x45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8",
+      "to": "0x1000000000000000000000000000000000000000",
+      "value": ["0x00", "0x01", "0x3635c9adc5dea00001"]
+    },
+    "post": {
+      "Cancun": [
+        {"indexes": {"data": 0, "gas": 0, "value": 0}},
+        {"indexes": {"data": 0, "gas": 0, "value": 1}},
+        {"indexes": {"data": 0, "gas": 0, "value": 0}, "expectException": "TransactionException.INTRINSIC_GAS_TOO_LOW"},
+        {"indexes": {"data": 0, "gas": 0, "value": 2}}
+      ],
+      "Frontier2": [
+        {"indexes": {"data": 0, "gas": 0, "value": 0}}
+      ]
+    }
+  }
+}`
+
+func TestStateTests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "add.json")
+	assert.NoError(t, os.WriteFile(path, []byte(addFixture), 0644))
+
+	fixtures, err := Load(path)
+	assert.NoError(t, err)
+	assert.Contains(t, fixtures, "add")
+
+	results := fixtures["add"].Run("add", "")
+	assert.Equal(t, 5, len(results))
+	statuses := make([]Status, len(results))
+	for i, result := range results {
+		statuses[i] = result.Status
+	}
+	// Forks are sorted, the unknown fork and the invalid transaction are skipped, a value above the balance fails
+	assert.Equal(t, []Status{Pass, Pass, Skip, Fail, Skip}, statuses, "%+v", results)
+	assert.Contains(t, results[3].Reason, "insufficient balance")
+
+	results = fixtures["add"].Run("add", "Frontier2")
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, Skip, results[0].Status)
+}
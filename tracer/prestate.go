@@ -0,0 +1,230 @@
+package tracer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/types/accounts"
+	"github.com/erigontech/erigon/core/state"
+	"github.com/holiman/uint256"
+)
+
+// =============================================================================
+// PRESTATE STATE READER
+// =============================================================================
+
+// PrestateAccount is an account of a prestateTracer dump.
+type PrestateAccount struct {
+	Balance uint256.Int
+	Nonce   uint64
+	Code    []byte
+	Storage map[libcommon.Hash]uint256.Int
+}
+
+type prestateAccountJSON struct {
+	Balance string            `json:"balance"`
+	Nonce   json.RawMessage   `json:"nonce"`
+	Code    string            `json:"code"`
+	Storage map[string]string `json:"storage"`
+}
+
+// PrestateStateReader serves the accounts of a prestate dump, any other account is empty.
+type PrestateStateReader struct {
+	accounts map[libcommon.Address]*PrestateAccount
+}
+
+func NewPrestateStateReader(accounts map[libcommon.Address]*PrestateAccount) *PrestateStateReader {
+	return &PrestateStateReader{accounts: accounts}
+}
+
+func (r *PrestateStateReader) ReadAccountData(address libcommon.Address) (*accounts.Account, error) {
+	prestate, ok := r.accounts[address]
+	if !ok {
+		return nil, nil
+	}
+	account := &accounts.Account{
+		Nonce:    prestate.Nonce,
+		Balance:  prestate.Balance,
+		Root:     emptyRootHash,
+		CodeHash: emptyCodeHash,
+	}
+	if len(prestate.Code) > 0 {
+		account.CodeHash = libcommon.BytesToHash(crypto.Keccak256(prestate.Code))
+		account.Incarnation = 1
+	}
+	return account, nil
+}
+
+func (r *PrestateStateReader) ReadAccountDataForDebug(address libcommon.Address) (*accounts.Account, error) {
+	return r.ReadAccountData(address)
+}
+
+func (r *PrestateStateReader) ReadAccountStorage(address libcommon.Address, key libcommon.Hash) (uint256.Int, bool, error) {
+	if prestate, ok := r.accounts[address]; ok {
+		value, found := prestate.Storage[key]
+		return value, found, nil
+	}
+	return uint256.Int{}, false, nil
+}
+
+func (r *PrestateStateReader) HasStorage(address libcommon.Address) (bool, error) {
+	prestate, ok := r.accounts[address]
+	return ok && len(prestate.Storage) > 0, nil
+}
+
+func (r *PrestateStateReader) ReadAccountCode(address libcommon.Address) ([]byte, error) {
+	if prestate, ok := r.accounts[address]; ok {
+		return prestate.Code, nil
+	}
+	return nil, nil
+}
+
+func (r *PrestateStateReader) ReadAccountCodeSize(address libcommon.Address) (int, error) {
+	code, err := r.ReadAccountCode(address)
+	return len(code), err
+}
+
+func (r *PrestateStateReader) ReadAccountIncarnation(address libcommon.Address) (uint64, error) {
+	if prestate, ok := r.accounts[address]; ok && len(prestate.Code) > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// LoadPrestate reads the result of a prestateTracer call, with or without the JSON-RPC envelope.
+// For a diffMode result the pre state is used.
+func LoadPrestate(path string) (map[libcommon.Address]*PrestateAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = unwrapRPCResult(data)
+	if err != nil {
+		return nil, err
+	}
+	return parsePrestate(data)
+}
+
+func parsePrestate(data []byte) (map[libcommon.Address]*PrestateAccount, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse prestate: %w", err)
+	}
+	if pre, ok := raw["pre"]; ok {
+		if _, ok := raw["post"]; ok {
+			return parsePrestate(pre)
+		}
+	}
+
+	result := make(map[libcommon.Address]*PrestateAccount, len(raw))
+	for key, encoded := range raw {
+		if !libcommon.IsHexAddress(key) {
+			return nil, fmt.Errorf("invalid prestate address %q", key)
+		}
+		var account prestateAccountJSON
+		if err := json.Unmarshal(encoded, &account); err != nil {
+			return nil, fmt.Errorf("account %s: %w", key, err)
+		}
+		parsed, err := parsePrestateAccount(account)
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %w", key, err)
+		}
+		result[libcommon.HexToAddress(key)] = parsed
+	}
+	return result, nil
+}
+
+func parsePrestateAccount(raw prestateAccountJSON) (*PrestateAccount, error) {
+	account := &PrestateAccount{Storage: make(map[libcommon.Hash]uint256.Int, len(raw.Storage))}
+
+	balance, err := decodeHex(raw.Balance)
+	if err != nil || len(balance) > 32 {
+		return nil, fmt.Errorf("invalid balance %q", raw.Balance)
+	}
+	account.Balance.SetBytes(balance)
+
+	// Geth writes the nonce as a number, other clients as a quantity
+	if len(raw.Nonce) > 0 {
+		var quantity string
+		if json.Unmarshal(raw.Nonce, &quantity) == nil {
+			account.Nonce, err = parseQuantity(quantity)
+		} else {
+			account.Nonce, err = strconv.ParseUint(string(raw.Nonce), 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid nonce: %w", err)
+		}
+	}
+
+	if account.Code, err = decodeHex(raw.Code); err != nil {
+		return nil, fmt.Errorf("invalid code: %w", err)
+	}
+
+	for key, value := range raw.Storage {
+		slot, err := decodeHex(key)
+		if err != nil || len(slot) > 32 {
+			return nil, fmt.Errorf("invalid storage slot %q", key)
+		}
+		content, err := decodeHex(value)
+		if err != nil || len(content) > 32 {
+			return nil, fmt.Errorf("invalid storage value %q", value)
+		}
+		account.Storage[libcommon.BytesToHash(slot)] = *new(uint256.Int).SetBytes(content)
+	}
+	return account, nil
+}
+
+// LoadStateFile reads a prestate dump or an execution witness. The state of a witness is the one after its latest
+// header, the parent of the block it was taken for.
+func LoadStateFile(path string) (state.StateReader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = unwrapRPCResult(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if _, ok := keys["state"]; !ok {
+		prestate, err := parsePrestate(data)
+		if err != nil {
+			return nil, err
+		}
+		return NewPrestateStateReader(prestate), nil
+	}
+
+	witness, err := LoadExecutionWitness(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(witness.Headers) == 0 {
+		return nil, errors.New("execution witness has no headers to take the state root from")
+	}
+	latest := witness.Headers[0]
+	for _, header := range witness.Headers[1:] {
+		if header.Number.Cmp(latest.Number) > 0 {
+			latest = header
+		}
+	}
+	return NewWitnessStateReader(witness, latest.Root), nil
+}
+
+func decodeHex(value string) ([]byte, error) {
+	value = strings.TrimPrefix(value, "0x")
+	if len(value)%2 == 1 {
+		value = "0" + value
+	}
+	return hex.DecodeString(value)
+}
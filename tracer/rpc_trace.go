@@ -0,0 +1,473 @@
+package tracer
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/holiman/uint256"
+)
+
+// =============================================================================
+// JSON-RPC TRACE SOURCE
+// =============================================================================
+
+// StructLog is one opcode of a debug_traceTransaction struct-logger trace, the stack is bottom first.
+type StructLog struct {
+	Pc         uint64   `json:"pc"`
+	Op         string   `json:"op"`
+	Gas        uint64   `json:"gas"`
+	Depth      int      `json:"depth"`
+	Stack      []string `json:"stack"`
+	Memory     []string `json:"memory,omitempty"`
+	ReturnData string   `json:"returnData,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type structLoggerResult struct {
+	Gas        uint64      `json:"gas"`
+	Failed     bool        `json:"failed"`
+	StructLogs []StructLog `json:"structLogs"`
+}
+
+// RPCBlock is the part of an eth_getBlockByNumber response the struct logs are rebuilt with.
+type RPCBlock struct {
+	Number    string            `json:"number"`
+	Timestamp string            `json:"timestamp"`
+	Miner     libcommon.Address `json:"miner"`
+}
+
+type RPCTransaction struct {
+	Hash  libcommon.Hash     `json:"hash"`
+	From  libcommon.Address  `json:"from"`
+	To    *libcommon.Address `json:"to"`
+	Input string             `json:"input"`
+	Value string             `json:"value"`
+}
+
+type rpcTransactionInfo struct {
+	RPCTransaction
+	BlockNumber      string `json:"blockNumber"`
+	TransactionIndex string `json:"transactionIndex"`
+}
+
+// DialRPC connects to an http(s):// or ws(s):// JSON-RPC endpoint, the calls are bound by their context.
+func DialRPC(ctx context.Context, url string) (*rpc.Client, error) {
+	client, err := rpc.DialContext(ctx, url, log.Root())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+	return client, nil
+}
+
+// RPCTracer traces transactions on any node with debug_traceTransaction, instead of the embedded rpcdaemon.
+type RPCTracer struct {
+	client  *rpc.Client
+	chainID *uint256.Int
+	// Code by address and block, for the push immediates
+	codes map[string][]byte
+}
+
+func NewRPCTracer(client *rpc.Client) *RPCTracer {
+	return &RPCTracer{client: client, codes: make(map[string][]byte)}
+}
+
+// TraceTransaction rebuilds the instructions and execution state the StateTracer would capture for the transaction.
+func (t *RPCTracer) TraceTransaction(ctx context.Context, txHash libcommon.Hash) ([]*EvmInstructionMetadata, *EvmExecutionState, error) {
+	var tx *rpcTransactionInfo
+	if err := t.client.CallContext(ctx, &tx, "eth_getTransactionByHash", txHash); err != nil {
+		return nil, nil, err
+	}
+	if tx == nil || tx.BlockNumber == "" {
+		return nil, nil, fmt.Errorf("transaction %s not found or pending", txHash.Hex())
+	}
+	blockNumber, err := parseQuantity(tx.BlockNumber)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid block number: %w", err)
+	}
+	txIndex, err := parseQuantity(tx.TransactionIndex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid transaction index: %w", err)
+	}
+
+	var block *RPCBlock
+	if err := t.client.CallContext(ctx, &block, "eth_getBlockByNumber", tx.BlockNumber, false); err != nil {
+		return nil, nil, err
+	}
+	if block == nil {
+		return nil, nil, fmt.Errorf("block %d not found", blockNumber)
+	}
+	timestamp, err := parseQuantity(block.Timestamp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid block timestamp: %w", err)
+	}
+	if t.chainID == nil {
+		var chainID string
+		if err := t.client.CallContext(ctx, &chainID, "eth_chainId"); err != nil {
+			return nil, nil, err
+		}
+		if t.chainID, err = parseUint256(chainID); err != nil {
+			return nil, nil, fmt.Errorf("invalid chain id: %w", err)
+		}
+	}
+
+	var trace structLoggerResult
+	config := map[string]interface{}{"disableStorage": true, "enableReturnData": true}
+	if err := t.client.CallContext(ctx, &trace, "debug_traceTransaction", txHash, config); err != nil {
+		return nil, nil, err
+	}
+	if len(trace.StructLogs) == 0 {
+		return nil, nil, nil
+	}
+
+	address := libcommon.Address{}
+	if tx.To != nil {
+		address = *tx.To
+	} else {
+		var receipt struct {
+			ContractAddress *libcommon.Address `json:"contractAddress"`
+		}
+		if err := t.client.CallContext(ctx, &receipt, "eth_getTransactionReceipt", txHash); err != nil {
+			return nil, nil, err
+		}
+		if receipt.ContractAddress != nil {
+			address = *receipt.ContractAddress
+		}
+	}
+
+	// The code before the block, contracts deployed in it get their immediates from the stack
+	codeAt := func(addr libcommon.Address) ([]byte, error) {
+		return t.code(ctx, addr, blockNumber-1)
+	}
+	instructions, err := ReconstructStructLogs(trace.StructLogs, address, tx.To == nil, int(txIndex), codeAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	input, err := hex.DecodeString(strings.TrimPrefix(tx.Input, "0x"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid input: %w", err)
+	}
+	value, err := parseUint256(tx.Value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid value: %w", err)
+	}
+	var code []byte
+	if tx.To != nil {
+		if code, err = codeAt(address); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		code = input
+		input = []byte{}
+	}
+
+	// Like the StateTracer, the gas is the one left before the last opcode
+	last := trace.StructLogs[len(trace.StructLogs)-1]
+	state := &EvmExecutionState{
+		CallValue:   value,
+		CallData:    input,
+		CodeData:    code,
+		Gas:         uint256.NewInt(last.Gas),
+		Address:     address,
+		Caller:      tx.From,
+		Origin:      tx.From,
+		Timestamp:   uint256.NewInt(timestamp),
+		ChainId:     t.chainID,
+		Coinbase:    block.Miner,
+		BlockNumber: uint256.NewInt(blockNumber),
+	}
+	return instructions, state, nil
+}
+
+func parseQuantity(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 64)
+}
+
+func parseUint256(value string) (*uint256.Int, error) {
+	if value == "" || value == "0x" {
+		return uint256.NewInt(0), nil
+	}
+	return uint256.FromHex(value)
+}
+
+// BlockTransactions returns the hashes of the transactions of a block.
+func (t *RPCTracer) BlockTransactions(ctx context.Context, blockNumber uint64) ([]libcommon.Hash, error) {
+	var block *struct {
+		Transactions []libcommon.Hash `json:"transactions"`
+	}
+	if err := t.client.CallContext(ctx, &block, "eth_getBlockByNumber", fmt.Sprintf("0x%x", blockNumber), false); err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", blockNumber)
+	}
+	return block.Transactions, nil
+}
+
+func (t *RPCTracer) code(ctx context.Context, address libcommon.Address, blockNumber uint64) ([]byte, error) {
+	key := fmt.Sprintf("%s@%d", address.Hex(), blockNumber)
+	if code, ok := t.codes[key]; ok {
+		return code, nil
+	}
+	var encoded string
+	if err := t.client.CallContext(ctx, &encoded, "eth_getCode", address, fmt.Sprintf("0x%x", blockNumber)); err != nil {
+		return nil, err
+	}
+	code, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid code of %s: %w", address.Hex(), err)
+	}
+	t.codes[key] = code
+	return code, nil
+}
+
+// =============================================================================
+// STRUCT LOG RECONSTRUCTION
+// =============================================================================
+
+type rpcFrame struct {
+	// The frame whose address the opcodes run at, itself except for DELEGATECALL and CALLCODE
+	owner   *rpcFrame
+	address libcommon.Address
+	code    []byte
+	last    *StructLog
+}
+
+type rpcPendingCall struct {
+	depth       int
+	instruction int
+	opcode      vm.OpCode
+	callee      *rpcFrame
+}
+
+// ReconstructStructLogs turns struct logs into the instructions the StateTracer captures: the push immediates come
+// from the code (or the next stack when the code is unknown) and a stack restore follows every call and create.
+func ReconstructStructLogs(logs []StructLog, address libcommon.Address, isCreate bool, txIndex int, codeAt func(libcommon.Address) ([]byte, error)) ([]*EvmInstructionMetadata, error) {
+	top := &rpcFrame{address: address}
+	top.owner = top
+	if !isCreate {
+		code, err := codeAt(address)
+		if err != nil {
+			return nil, err
+		}
+		top.code = code
+	}
+
+	frames := []*rpcFrame{top}
+	var pending []*rpcPendingCall
+	var instructions []*EvmInstructionMetadata
+	// The frame owning the address of every instruction, resolved at the end for the CREATE frames
+	var owners []*rpcFrame
+
+	for i := range logs {
+		entry := &logs[i]
+		if entry.Depth < 1 || entry.Depth > len(frames)+1 {
+			return nil, fmt.Errorf("struct log %d: unexpected depth %d", i, entry.Depth)
+		}
+		stack, err := parseStructLogStack(entry.Stack)
+		if err != nil {
+			return nil, fmt.Errorf("struct log %d: %w", i, err)
+		}
+		frames = frames[:min(len(frames), entry.Depth)]
+
+		// The first opcode after a call or create resumes the caller
+		if n := len(pending); n > 0 && pending[n-1].depth == entry.Depth {
+			call := pending[n-1]
+			pending = pending[:n-1]
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("struct log %d: empty stack after %s", i, call.opcode)
+			}
+			result := stack[len(stack)-1]
+			if call.callee != nil && (call.opcode == vm.CREATE || call.opcode == vm.CREATE2) && !result.IsZero() {
+				call.callee.address = libcommon.BytesToAddress(result.Bytes())
+			}
+			returnData, err := callReturnData(entry, call, !result.IsZero())
+			if err != nil {
+				return nil, fmt.Errorf("struct log %d: %w", i, err)
+			}
+			caller := instructions[call.instruction]
+			instructions = append(instructions, &EvmInstructionMetadata{
+				Opcode:         vm.STOP,
+				Arguments:      []byte{},
+				StackSnapshot:  []uint256.Int{},
+				Result:         &result,
+				ReturnData:     returnData,
+				IsStackRestore: true,
+				Pc:             caller.Pc,
+				TxIndex:        txIndex,
+			})
+			owners = append(owners, owners[call.instruction])
+		}
+
+		if entry.Depth == len(frames)+1 {
+			n := len(pending)
+			if n == 0 || pending[n-1].depth != entry.Depth-1 {
+				return nil, fmt.Errorf("struct log %d: entered depth %d without a call", i, entry.Depth)
+			}
+			callee, err := enterFrame(pending[n-1], frames[len(frames)-1], instructions, codeAt)
+			if err != nil {
+				return nil, err
+			}
+			frames = append(frames, callee)
+		}
+		frame := frames[len(frames)-1]
+		frame.last = entry
+
+		opcode, err := parseStructLogOp(entry.Op)
+		if err != nil {
+			return nil, fmt.Errorf("struct log %d: %w", i, err)
+		}
+		instructions = append(instructions, &EvmInstructionMetadata{
+			Opcode:        opcode,
+			Arguments:     pushArguments(opcode, entry.Pc, frame.code, logs[i+1:], entry.Depth),
+			StackSnapshot: stack,
+			Pc:            entry.Pc,
+			TxIndex:       txIndex,
+		})
+		owners = append(owners, frame.owner)
+
+		switch opcode {
+		case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+			pending = append(pending, &rpcPendingCall{depth: entry.Depth, instruction: len(instructions) - 1, opcode: opcode})
+		}
+	}
+
+	for i, instruction := range instructions {
+		instruction.Address = owners[i].address
+	}
+	return instructions, nil
+}
+
+// enterFrame creates the frame of the callee of a call, the target is the second stack item of the call.
+func enterFrame(call *rpcPendingCall, caller *rpcFrame, instructions []*EvmInstructionMetadata, codeAt func(libcommon.Address) ([]byte, error)) (*rpcFrame, error) {
+	callee := &rpcFrame{}
+	call.callee = callee
+	if call.opcode == vm.CREATE || call.opcode == vm.CREATE2 {
+		// The address is known when the create returns, the init code is in memory
+		callee.owner = callee
+		return callee, nil
+	}
+
+	stack := instructions[call.instruction].StackSnapshot
+	if len(stack) < 2 {
+		return nil, fmt.Errorf("%s with %d stack items", call.opcode, len(stack))
+	}
+	target := libcommon.BytesToAddress(stack[len(stack)-2].Bytes())
+	code, err := codeAt(target)
+	if err != nil {
+		return nil, err
+	}
+	callee.code = code
+	if call.opcode == vm.DELEGATECALL || call.opcode == vm.CALLCODE {
+		callee.owner = caller.owner
+	} else {
+		callee.address = target
+		callee.owner = callee
+	}
+	return callee, nil
+}
+
+// callReturnData is the output of the callee, from the memory of its RETURN or REVERT or else from the node.
+// A successful create leaves no return data, its output is the deployed code.
+func callReturnData(resumed *StructLog, call *rpcPendingCall, success bool) ([]byte, error) {
+	if success && (call.opcode == vm.CREATE || call.opcode == vm.CREATE2) {
+		return []byte{}, nil
+	}
+	if call.callee != nil && call.callee.last != nil && call.callee.last.Memory != nil {
+		last := call.callee.last
+		if op, _ := parseStructLogOp(last.Op); op == vm.RETURN || op == vm.REVERT {
+			stack, err := parseStructLogStack(last.Stack)
+			if err != nil || len(stack) < 2 {
+				return nil, fmt.Errorf("invalid stack of %s", last.Op)
+			}
+			memory, err := hex.DecodeString(strings.Join(last.Memory, ""))
+			if err != nil {
+				return nil, fmt.Errorf("invalid memory: %w", err)
+			}
+			offset, size := stack[len(stack)-1].Uint64(), stack[len(stack)-2].Uint64()
+			returnData := make([]byte, size)
+			if offset < uint64(len(memory)) {
+				copy(returnData, memory[offset:])
+			}
+			return returnData, nil
+		}
+	}
+	returnData, err := hex.DecodeString(strings.TrimPrefix(resumed.ReturnData, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid return data: %w", err)
+	}
+	return returnData, nil
+}
+
+// pushArguments reads the immediate of a PUSH from the code, or from the stack of the next opcode of the frame
+// when the code is unknown or differs (e.g. init code, contracts deployed earlier in the block).
+func pushArguments(opcode vm.OpCode, pc uint64, code []byte, next []StructLog, depth int) []byte {
+	if !opcode.IsPushWithImmediateArgs() {
+		return []byte{}
+	}
+	size := uint64(opcode) - uint64(vm.PUSH1-1)
+	arguments := make([]byte, size)
+	if pc < uint64(len(code)) && code[pc] == byte(opcode) {
+		for i := uint64(0); i < size; i++ {
+			if pc+1+i < uint64(len(code)) {
+				arguments[i] = code[pc+1+i]
+			}
+		}
+		return arguments
+	}
+	for _, entry := range next {
+		if entry.Depth == depth {
+			stack, err := parseStructLogStack(entry.Stack)
+			if err == nil && len(stack) > 0 {
+				value := stack[len(stack)-1].Bytes32()
+				copy(arguments, value[32-size:])
+			}
+			break
+		}
+	}
+	return arguments
+}
+
+func parseStructLogOp(name string) (vm.OpCode, error) {
+	// Undefined opcodes are named "opcode 0xef not defined"
+	if value, found := strings.CutPrefix(name, "opcode "); found {
+		value, _, _ = strings.Cut(value, " ")
+		op, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 8)
+		if err != nil {
+			return 0, fmt.Errorf("unknown opcode %q", name)
+		}
+		return vm.OpCode(op), nil
+	}
+	op := vm.StringToOp(name)
+	if op == vm.STOP && name != "STOP" {
+		return 0, fmt.Errorf("unknown opcode %q", name)
+	}
+	return op, nil
+}
+
+// parseStructLogStack accepts the 0x prefixed quantities of geth and the padded words of older nodes.
+func parseStructLogStack(items []string) ([]uint256.Int, error) {
+	stack := make([]uint256.Int, len(items))
+	for i, item := range items {
+		digits := strings.TrimPrefix(item, "0x")
+		if len(digits)%2 == 1 {
+			digits = "0" + digits
+		}
+		data, err := hex.DecodeString(digits)
+		if err != nil || len(data) > 32 {
+			return nil, fmt.Errorf("invalid stack item %q", item)
+		}
+		stack[i].SetBytes(data)
+	}
+	return stack, nil
+}
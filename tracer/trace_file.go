@@ -0,0 +1,489 @@
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/holiman/uint256"
+)
+
+// TraceFileVersion is bumped on every incompatible change of the trace file format.
+const TraceFileVersion = 1
+
+// Binary trace files start with the magic and a big endian uint16 version, followed by a gzip stream.
+var traceFileMagic = []byte("EVMTRACE")
+
+// TracedTransaction is the trace of one transaction, the input of Transpiler.ProcessExecution.
+type TracedTransaction struct {
+	Instructions []*EvmInstructionMetadata
+	State        *EvmExecutionState
+}
+
+// WriteTraceFile writes the transactions in the binary format, or in the JSON debug format when the path ends in .json.
+func WriteTraceFile(path string, transactions []TracedTransaction) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if strings.HasSuffix(path, ".json") {
+		err = writeTraceJSON(writer, transactions)
+	} else {
+		err = writeTraceBinary(writer, transactions)
+	}
+	if err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// ReadTraceFile reads a trace file in either format.
+func ReadTraceFile(path string) ([]TracedTransaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, traceFileMagic) {
+		return readTraceBinary(data[len(traceFileMagic):])
+	}
+	return readTraceJSON(data)
+}
+
+// =============================================================================
+// BINARY FORMAT
+// =============================================================================
+
+const (
+	flagStackRestore = 1 << iota
+	flagResult
+	flagReturnData
+	flagSameAddress
+)
+
+type traceEncoder struct {
+	w   io.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *traceEncoder) write(data []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(data)
+	}
+}
+
+func (e *traceEncoder) uvarint(value uint64) {
+	e.write(e.buf[:binary.PutUvarint(e.buf[:], value)])
+}
+
+func (e *traceEncoder) bytes(data []byte) {
+	e.uvarint(uint64(len(data)))
+	e.write(data)
+}
+
+// word writes the value without its leading zero bytes, nil is written like zero.
+func (e *traceEncoder) word(value *uint256.Int) {
+	if value == nil {
+		e.bytes(nil)
+		return
+	}
+	e.bytes(value.Bytes())
+}
+
+func writeTraceBinary(w io.Writer, transactions []TracedTransaction) error {
+	header := binary.BigEndian.AppendUint16(append([]byte{}, traceFileMagic...), TraceFileVersion)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	compressed := gzip.NewWriter(w)
+	e := &traceEncoder{w: compressed}
+	e.uvarint(uint64(len(transactions)))
+	for _, transaction := range transactions {
+		e.state(transaction.State)
+		e.uvarint(uint64(len(transaction.Instructions)))
+		var previous libcommon.Address
+		for i, instruction := range transaction.Instructions {
+			e.instruction(instruction, i > 0 && instruction.Address == previous)
+			previous = instruction.Address
+		}
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return compressed.Close()
+}
+
+func (e *traceEncoder) state(state *EvmExecutionState) {
+	if state == nil {
+		e.uvarint(0)
+		return
+	}
+	e.uvarint(1)
+	e.word(state.CallValue)
+	e.bytes(state.CallData)
+	e.bytes(state.CodeData)
+	e.word(state.Gas)
+	e.write(state.Address[:])
+	e.write(state.Caller[:])
+	e.write(state.Origin[:])
+	e.word(state.Timestamp)
+	e.word(state.ChainId)
+	e.write(state.Coinbase[:])
+	e.word(state.BlockNumber)
+}
+
+func (e *traceEncoder) instruction(instruction *EvmInstructionMetadata, sameAddress bool) {
+	flags := byte(0)
+	if instruction.IsStackRestore {
+		flags |= flagStackRestore
+	}
+	if instruction.Result != nil {
+		flags |= flagResult
+	}
+	if instruction.ReturnData != nil {
+		flags |= flagReturnData
+	}
+	if sameAddress {
+		flags |= flagSameAddress
+	}
+	e.write([]byte{byte(instruction.Opcode), flags})
+
+	e.uvarint(instruction.Pc)
+	if !sameAddress {
+		e.write(instruction.Address[:])
+	}
+	e.uvarint(uint64(instruction.TxIndex))
+	e.bytes(instruction.Arguments)
+	e.uvarint(uint64(len(instruction.StackSnapshot)))
+	for i := range instruction.StackSnapshot {
+		e.word(&instruction.StackSnapshot[i])
+	}
+	if instruction.Result != nil {
+		e.word(instruction.Result)
+	}
+	if instruction.ReturnData != nil {
+		e.bytes(instruction.ReturnData)
+	}
+}
+
+// Maximum depth of the EVM stack
+const maxStackDepth = 1024
+
+// Reads up to this size are allocated at once, larger ones grow with the input, so a corrupt length fails at the
+// end of the file instead of allocating its size
+const maxPreallocatedRead = 1 << 16
+
+type traceDecoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *traceDecoder) read(size int) []byte {
+	if d.err != nil {
+		return make([]byte, min(size, maxPreallocatedRead))
+	}
+	if size <= maxPreallocatedRead {
+		data := make([]byte, size)
+		_, d.err = io.ReadFull(d.r, data)
+		return data
+	}
+	data, err := io.ReadAll(io.LimitReader(d.r, int64(size)))
+	if err == nil && len(data) < size {
+		err = io.ErrUnexpectedEOF
+	}
+	d.err = err
+	return data
+}
+
+func (d *traceDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	value, err := binary.ReadUvarint(d.r)
+	d.err = err
+	return value
+}
+
+// length reads a count, rejecting sizes no trace can have so a corrupt file does not allocate them.
+func (d *traceDecoder) length() int {
+	value := d.uvarint()
+	if d.err == nil && value > 1<<30 {
+		d.err = fmt.Errorf("invalid length %d", value)
+	}
+	return int(value)
+}
+
+func (d *traceDecoder) bytes() []byte {
+	return d.read(d.length())
+}
+
+func (d *traceDecoder) word() *uint256.Int {
+	data := d.bytes()
+	if len(data) > 32 {
+		d.err = fmt.Errorf("word of %d bytes", len(data))
+	}
+	return new(uint256.Int).SetBytes(data)
+}
+
+func (d *traceDecoder) address() libcommon.Address {
+	return libcommon.BytesToAddress(d.read(libcommon.AddressLength))
+}
+
+func readTraceBinary(data []byte) ([]TracedTransaction, error) {
+	if len(data) < 2 {
+		return nil, errors.New("truncated trace file header")
+	}
+	if version := binary.BigEndian.Uint16(data); version != TraceFileVersion {
+		return nil, fmt.Errorf("unsupported trace file version %d, expected %d", version, TraceFileVersion)
+	}
+	compressed, err := gzip.NewReader(bytes.NewReader(data[2:]))
+	if err != nil {
+		return nil, err
+	}
+	defer compressed.Close()
+
+	// The counts are not trusted for allocations, the slices grow as the entries are read
+	d := &traceDecoder{r: bufio.NewReader(compressed)}
+	var transactions []TracedTransaction
+	transactionCount := d.length()
+	for i := 0; i < transactionCount && d.err == nil; i++ {
+		transaction := TracedTransaction{State: d.state()}
+		count := d.length()
+		var previous libcommon.Address
+		for j := 0; j < count && d.err == nil; j++ {
+			instruction := d.instruction(previous)
+			previous = instruction.Address
+			transaction.Instructions = append(transaction.Instructions, instruction)
+		}
+		transactions = append(transactions, transaction)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("corrupt trace file: %w", d.err)
+	}
+	return transactions, nil
+}
+
+func (d *traceDecoder) state() *EvmExecutionState {
+	if d.uvarint() == 0 {
+		return nil
+	}
+	return &EvmExecutionState{
+		CallValue:   d.word(),
+		CallData:    d.bytes(),
+		CodeData:    d.bytes(),
+		Gas:         d.word(),
+		Address:     d.address(),
+		Caller:      d.address(),
+		Origin:      d.address(),
+		Timestamp:   d.word(),
+		ChainId:     d.word(),
+		Coinbase:    d.address(),
+		BlockNumber: d.word(),
+	}
+}
+
+func (d *traceDecoder) instruction(previous libcommon.Address) *EvmInstructionMetadata {
+	header := d.read(2)
+	flags := header[1]
+	instruction := &EvmInstructionMetadata{
+		Opcode:         vm.OpCode(header[0]),
+		IsStackRestore: flags&flagStackRestore != 0,
+		Pc:             d.uvarint(),
+		Address:        previous,
+	}
+	if flags&flagSameAddress == 0 {
+		instruction.Address = d.address()
+	}
+	instruction.TxIndex = int(d.uvarint())
+	instruction.Arguments = d.bytes()
+
+	depth := d.length()
+	if depth > maxStackDepth {
+		d.err = fmt.Errorf("stack of %d entries is deeper than %d", depth, maxStackDepth)
+		return instruction
+	}
+	instruction.StackSnapshot = make([]uint256.Int, depth)
+	for i := range instruction.StackSnapshot {
+		instruction.StackSnapshot[i] = *d.word()
+	}
+	if flags&flagResult != 0 {
+		instruction.Result = d.word()
+	}
+	if flags&flagReturnData != 0 {
+		instruction.ReturnData = d.bytes()
+	}
+	return instruction
+}
+
+// =============================================================================
+// JSON DEBUG FORMAT
+// =============================================================================
+
+type traceFileJSON struct {
+	Version      int                     `json:"version"`
+	Transactions []tracedTransactionJSON `json:"transactions"`
+}
+
+type tracedTransactionJSON struct {
+	State        *executionStateJSON `json:"state"`
+	Instructions []instructionJSON   `json:"instructions"`
+}
+
+type executionStateJSON struct {
+	CallValue   *uint256.Int      `json:"call_value"`
+	CallData    hexBytes          `json:"call_data"`
+	CodeData    hexBytes          `json:"code_data"`
+	Gas         *uint256.Int      `json:"gas"`
+	Address     libcommon.Address `json:"address"`
+	Caller      libcommon.Address `json:"caller"`
+	Origin      libcommon.Address `json:"origin"`
+	Timestamp   *uint256.Int      `json:"timestamp"`
+	ChainId     *uint256.Int      `json:"chain_id"`
+	Coinbase    libcommon.Address `json:"coinbase"`
+	BlockNumber *uint256.Int      `json:"block_number"`
+}
+
+type instructionJSON struct {
+	Opcode byte `json:"opcode"`
+	// Only for reading the file, the opcode is used when loading
+	Name           string            `json:"name"`
+	Pc             uint64            `json:"pc"`
+	Address        libcommon.Address `json:"address"`
+	TxIndex        int               `json:"tx_index"`
+	Arguments      hexBytes          `json:"arguments,omitempty"`
+	Stack          []uint256.Int     `json:"stack"`
+	Result         *uint256.Int      `json:"result,omitempty"`
+	ReturnData     *hexBytes         `json:"return_data,omitempty"`
+	IsStackRestore bool              `json:"is_stack_restore,omitempty"`
+}
+
+type hexBytes []byte
+
+func (b hexBytes) MarshalText() ([]byte, error) {
+	return []byte("0x" + hex.EncodeToString(b)), nil
+}
+
+func (b *hexBytes) UnmarshalText(text []byte) error {
+	data, err := hex.DecodeString(strings.TrimPrefix(string(text), "0x"))
+	if err != nil {
+		return err
+	}
+	*b = data
+	return nil
+}
+
+func writeTraceJSON(w io.Writer, transactions []TracedTransaction) error {
+	file := traceFileJSON{Version: TraceFileVersion}
+	for _, transaction := range transactions {
+		encoded := tracedTransactionJSON{Instructions: make([]instructionJSON, 0, len(transaction.Instructions))}
+		if state := transaction.State; state != nil {
+			encoded.State = &executionStateJSON{
+				CallValue:   state.CallValue,
+				CallData:    state.CallData,
+				CodeData:    state.CodeData,
+				Gas:         state.Gas,
+				Address:     state.Address,
+				Caller:      state.Caller,
+				Origin:      state.Origin,
+				Timestamp:   state.Timestamp,
+				ChainId:     state.ChainId,
+				Coinbase:    state.Coinbase,
+				BlockNumber: state.BlockNumber,
+			}
+		}
+		for _, instruction := range transaction.Instructions {
+			item := instructionJSON{
+				Opcode:         byte(instruction.Opcode),
+				Name:           instruction.Opcode.String(),
+				Pc:             instruction.Pc,
+				Address:        instruction.Address,
+				TxIndex:        instruction.TxIndex,
+				Arguments:      instruction.Arguments,
+				Stack:          instruction.StackSnapshot,
+				Result:         instruction.Result,
+				IsStackRestore: instruction.IsStackRestore,
+			}
+			if instruction.ReturnData != nil {
+				returnData := hexBytes(instruction.ReturnData)
+				item.ReturnData = &returnData
+			}
+			encoded.Instructions = append(encoded.Instructions, item)
+		}
+		file.Transactions = append(file.Transactions, encoded)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(file)
+}
+
+func readTraceJSON(data []byte) ([]TracedTransaction, error) {
+	var file traceFileJSON
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse trace file: %w", err)
+	}
+	if file.Version != TraceFileVersion {
+		return nil, fmt.Errorf("unsupported trace file version %d, expected %d", file.Version, TraceFileVersion)
+	}
+
+	transactions := make([]TracedTransaction, 0, len(file.Transactions))
+	for _, encoded := range file.Transactions {
+		transaction := TracedTransaction{Instructions: make([]*EvmInstructionMetadata, 0, len(encoded.Instructions))}
+		if state := encoded.State; state != nil {
+			transaction.State = &EvmExecutionState{
+				CallValue:   state.CallValue,
+				CallData:    state.CallData,
+				CodeData:    state.CodeData,
+				Gas:         state.Gas,
+				Address:     state.Address,
+				Caller:      state.Caller,
+				Origin:      state.Origin,
+				Timestamp:   state.Timestamp,
+				ChainId:     state.ChainId,
+				Coinbase:    state.Coinbase,
+				BlockNumber: state.BlockNumber,
+			}
+		}
+		for _, item := range encoded.Instructions {
+			instruction := &EvmInstructionMetadata{
+				Opcode:         vm.OpCode(item.Opcode),
+				Arguments:      item.Arguments,
+				StackSnapshot:  item.Stack,
+				Result:         item.Result,
+				IsStackRestore: item.IsStackRestore,
+				Pc:             item.Pc,
+				Address:        item.Address,
+				TxIndex:        item.TxIndex,
+			}
+			if instruction.Arguments == nil {
+				instruction.Arguments = []byte{}
+			}
+			if instruction.StackSnapshot == nil {
+				instruction.StackSnapshot = []uint256.Int{}
+			}
+			if item.ReturnData != nil {
+				instruction.ReturnData = *item.ReturnData
+			}
+			transaction.Instructions = append(transaction.Instructions, instruction)
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
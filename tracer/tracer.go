@@ -3,6 +3,7 @@ package tracer
 import (
 	"encoding/json"
 	"erigon-transpiler-risc-v/prover"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -39,7 +40,12 @@ type EvmInstructionMetadata struct {
 	Arguments      []byte
 	StackSnapshot  []uint256.Int
 	Result         *uint256.Int
+	ReturnData     []byte
 	IsStackRestore bool
+	// Where the opcode comes from, for a stack restore the CALL that returns
+	Pc      uint64
+	Address libcommon.Address
+	TxIndex int
 }
 
 // =============================================================================
@@ -89,6 +95,16 @@ type StateTracer struct {
 	coinbase        libcommon.Address
 	origin          libcommon.Address
 	blockNumber     *uint256.Int
+	callFrames      []callFrame
+	txIndex         int
+}
+
+type callFrame struct {
+	typ vm.OpCode
+	to  libcommon.Address
+	// The CALL or CREATE instruction that entered the frame
+	callerPc      uint64
+	callerAddress libcommon.Address
 }
 
 func NewStateTracer() *StateTracer {
@@ -104,6 +120,11 @@ func (t *StateTracer) setJumpTable(jt *vm.JumpTable) {
 	t.jumpTable = jt
 }
 
+// SetTxIndex sets the index in the block of the traced transaction, it is recorded with every instruction.
+func (t *StateTracer) SetTxIndex(txIndex int) {
+	t.txIndex = txIndex
+}
+
 func (t *StateTracer) CaptureTxStart(vm *tracing.VMContext, tx types.Transaction, from libcommon.Address) {
 	t.blockTime = vm.Time
 	t.chainId = new(uint256.Int)
@@ -114,22 +135,56 @@ func (t *StateTracer) CaptureTxStart(vm *tracing.VMContext, tx types.Transaction
 }
 func (t *StateTracer) CaptureTxEnd(receipt *types.Receipt, err error) {}
 func (t *StateTracer) CaptureEnter(depth int, typ byte, from libcommon.Address, to libcommon.Address, precompile bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	frame := callFrame{
+		typ: vm.OpCode(typ),
+		to:  to,
+	}
+	// The calling instruction was captured right before entering
+	if len(t.evmInstructions) > 0 {
+		caller := t.evmInstructions[len(t.evmInstructions)-1]
+		frame.callerPc = caller.Pc
+		frame.callerAddress = caller.Address
+	}
+	t.callFrames = append(t.callFrames, frame)
 }
 func (t *StateTracer) CaptureExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	var frame callFrame
+	if len(t.callFrames) > 0 {
+		frame = t.callFrames[len(t.callFrames)-1]
+		t.callFrames = t.callFrames[:len(t.callFrames)-1]
+	}
+
 	if depth > 0 {
+		success := err == nil && !reverted
 		var result *uint256.Int
-		if err == nil && !reverted {
+		if success && (frame.typ == vm.CREATE || frame.typ == vm.CREATE2) {
+			// CREATE and CREATE2 push the address of the new contract
+			result = new(uint256.Int).SetBytes(frame.to.Bytes())
+		} else if success {
 			result = uint256.NewInt(1)
 		} else {
 			result = uint256.NewInt(0)
 		}
 
+		// Return data is only kept for successful calls and reverts, not exceptional halts. A successful
+		// create clears it, its output is the deployed code.
+		returnData := []byte{}
+		isCreate := frame.typ == vm.CREATE || frame.typ == vm.CREATE2
+		if (err == nil && !isCreate) || errors.Is(err, vm.ErrExecutionReverted) {
+			returnData = make([]byte, len(output))
+			copy(returnData, output)
+		}
+
 		t.evmInstructions = append(t.evmInstructions, &EvmInstructionMetadata{
 			Opcode:         vm.STOP,
 			Arguments:      []byte{},
 			StackSnapshot:  []uint256.Int{},
 			Result:         result,
+			ReturnData:     returnData,
 			IsStackRestore: true,
+			Pc:             frame.callerPc,
+			Address:        frame.callerAddress,
+			TxIndex:        t.txIndex,
 		})
 	}
 }
@@ -173,6 +228,9 @@ func (t *StateTracer) CaptureState(pc uint64, op byte, gas, cost uint64, scope t
 		Opcode:        vm.OpCode(op),
 		Arguments:     arguments,
 		StackSnapshot: snapshot,
+		Pc:            pc,
+		Address:       scope.Address(),
+		TxIndex:       t.txIndex,
 	})
 }
 
@@ -206,8 +264,49 @@ type SimpleTracer struct {
 	evm    *vm.EVM
 }
 
+// TracerEnv is the block and transaction context the SimpleTracer executes in.
+type TracerEnv struct {
+	ChainConfig *chain.Config
+	Coinbase    libcommon.Address
+	GasLimit    uint64
+	BlockNumber uint64
+	Time        uint64
+	Difficulty  *big.Int
+	// Optional, only used after London and the merge
+	BaseFee    *uint256.Int
+	PrevRanDao *libcommon.Hash
+	Origin     libcommon.Address
+	GasPrice   *uint256.Int
+	// Optional, the pre-state the execution reads, all accounts are empty when nil
+	StateReader state.StateReader
+}
+
+func DefaultTracerEnv() TracerEnv {
+	chainConfig := chain.AllProtocolChanges
+	chainConfig.ChainID = big.NewInt(1337)
+
+	return TracerEnv{
+		ChainConfig: chainConfig,
+		Coinbase:    libcommon.Address{},
+		GasLimit:    1000000,
+		BlockNumber: 23041867,
+		Time:        1,
+		Difficulty:  big.NewInt(1),
+		Origin:      libcommon.HexToAddress("0xabcd"),
+		GasPrice:    uint256.NewInt(1),
+	}
+}
+
 func NewSimpleTracer() *SimpleTracer {
-	statedbInMemory := state.New(&MockStateReader{})
+	return NewSimpleTracerWithEnv(DefaultTracerEnv())
+}
+
+func NewSimpleTracerWithEnv(env TracerEnv) *SimpleTracer {
+	var stateReader state.StateReader = &MockStateReader{}
+	if env.StateReader != nil {
+		stateReader = env.StateReader
+	}
+	statedbInMemory := state.New(stateReader)
 
 	blockCtx := evmtypes.BlockContext{
 		CanTransfer: func(db evmtypes.IntraBlockState, addr libcommon.Address, amount *uint256.Int) (bool, error) {
@@ -218,19 +317,18 @@ func NewSimpleTracer() *SimpleTracer {
 			return nil
 		},
 		GetHash:     func(uint64) (libcommon.Hash, error) { return libcommon.Hash{}, nil },
-		Coinbase:    libcommon.Address{},
-		GasLimit:    1000000,
-		BlockNumber: 23041867,
-		Time:        1,
-		Difficulty:  big.NewInt(1),
+		Coinbase:    env.Coinbase,
+		GasLimit:    env.GasLimit,
+		BlockNumber: env.BlockNumber,
+		Time:        env.Time,
+		Difficulty:  env.Difficulty,
+		BaseFee:     env.BaseFee,
+		PrevRanDao:  env.PrevRanDao,
 	}
 
-	chainConfig := chain.AllProtocolChanges
-	chainConfig.ChainID = big.NewInt(1337)
-
 	txCtx := evmtypes.TxContext{
-		Origin:   libcommon.HexToAddress("0xabcd"),
-		GasPrice: uint256.NewInt(1),
+		Origin:   env.Origin,
+		GasPrice: env.GasPrice,
 	}
 
 	tracer := NewStateTracer()
@@ -238,14 +336,17 @@ func NewSimpleTracer() *SimpleTracer {
 	// TODO: for some reason capture tx start does not trigger.
 	tracer.blockTime = blockCtx.Time
 	tracer.chainId = new(uint256.Int)
-	tracer.chainId.SetFromBig(chainConfig.ChainID)
+	tracer.chainId.SetFromBig(env.ChainConfig.ChainID)
+	tracer.coinbase = env.Coinbase
+	tracer.origin = env.Origin
+	tracer.blockNumber = uint256.NewInt(env.BlockNumber)
 
 	hooks := tracer.Hooks()
 	vmConfig := vm.Config{
 		Tracer: hooks,
 	}
 
-	evm := vm.NewEVM(blockCtx, txCtx, statedbInMemory, chainConfig, vmConfig)
+	evm := vm.NewEVM(blockCtx, txCtx, statedbInMemory, env.ChainConfig, vmConfig)
 	in := vm.NewEVMInterpreter(evm, vmConfig)
 	tracer.setJumpTable(in.JT())
 
@@ -264,24 +365,65 @@ func (tr *SimpleTracer) DeployContract(addr libcommon.Address, bytecode []byte,
 	}
 	return nil
 }
+
+// SetupAccount seeds the pre-state of an account, in the EVM state and in the MockState.
+func (tr *SimpleTracer) SetupAccount(addr libcommon.Address, balance *uint256.Int, nonce uint64, code []byte, storage map[libcommon.Hash]uint256.Int) error {
+	ibs := tr.evm.IntraBlockState()
+	if err := ibs.CreateAccount(addr, len(code) > 0); err != nil {
+		return err
+	}
+	if err := ibs.SetCode(addr, code); err != nil {
+		return err
+	}
+	if err := ibs.SetNonce(addr, nonce); err != nil {
+		return err
+	}
+	if err := ibs.SetBalance(addr, *balance, tracing.BalanceChangeUnspecified); err != nil {
+		return err
+	}
+
+	if err := tr.state.SetupContract(addr, code, balance); err != nil {
+		return err
+	}
+	if err := tr.state.SetNonce(addr, nonce); err != nil {
+		return err
+	}
+	for key, value := range storage {
+		if err := ibs.SetState(addr, &key, value); err != nil {
+			return err
+		}
+		if err := tr.state.SetState(addr, &key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (tr *SimpleTracer) ExecuteContract(contractAddr libcommon.Address, input []byte, gasLimit uint64, callValue *uint256.Int) ([]*EvmInstructionMetadata, *EvmExecutionState, uint64, error) {
 	if callValue == nil {
 		callValue = uint256.NewInt(0)
 	}
 	callerAddr := libcommon.HexToAddress("0xabcd")
-	caller := vm.AccountRef(callerAddr)
 
 	neededBalance := new(uint256.Int).Add(callValue, uint256.NewInt(1000000))
 	tr.evm.IntraBlockState().SetBalance(callerAddr, *neededBalance, tracing.BalanceChangeUnspecified)
 
-	tr.evm.IntraBlockState().SetHooks(tr.evm.Config().Tracer)
-	_, gasLeft, err := tr.evm.Call(caller, contractAddr, input, gasLimit, callValue, false)
+	instructions, executionState, gasUsed, err := tr.ExecuteCall(callerAddr, contractAddr, input, gasLimit, callValue)
 
 	// We don't want these errors to propagate
 	if err == vm.ErrExecutionReverted || (err != nil && strings.Contains(err.Error(), "invalid opcode:")) {
 		log.Warn("vm error: %w", err)
 		err = nil
 	}
+	return instructions, executionState, gasUsed, err
+}
+
+// ExecuteCall runs a message call from the caller, which has to be funded already.
+func (tr *SimpleTracer) ExecuteCall(callerAddr libcommon.Address, contractAddr libcommon.Address, input []byte, gasLimit uint64, callValue *uint256.Int) ([]*EvmInstructionMetadata, *EvmExecutionState, uint64, error) {
+	caller := vm.AccountRef(callerAddr)
+
+	tr.evm.IntraBlockState().SetHooks(tr.evm.Config().Tracer)
+	_, gasLeft, err := tr.evm.Call(caller, contractAddr, input, gasLimit, callValue, false)
 	return tr.tracer.evmInstructions, tr.tracer.executionState, gasLimit - gasLeft, err
 }
 
@@ -295,6 +437,9 @@ func (tr *SimpleTracer) GetStorageAt(addr libcommon.Address, key libcommon.Hash)
 func NewTracerHooks(createResults func(newTracer *StateTracer) (*prover.ResultsFile, error)) func(code string, ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
 	return func(code string, ctx *tracers.Context, cfg json.RawMessage) (*tracers.Tracer, error) {
 		newTracer := NewStateTracer()
+		if ctx != nil {
+			newTracer.SetTxIndex(ctx.TxIndex)
+		}
 		return &tracers.Tracer{
 			Hooks: newTracer.Hooks(),
 			Stop: func(err error) {
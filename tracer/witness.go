@@ -0,0 +1,378 @@
+package tracer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/rlp"
+	"github.com/erigontech/erigon-lib/types"
+	"github.com/erigontech/erigon-lib/types/accounts"
+	"github.com/holiman/uint256"
+)
+
+// =============================================================================
+// EXECUTION WITNESS
+// =============================================================================
+
+var (
+	emptyRootHash = libcommon.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	emptyCodeHash = libcommon.HexToHash("0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470")
+)
+
+// ExecutionWitness is the debug_executionWitness of a block: the trie nodes, codes and ancestor headers it reads.
+type ExecutionWitness struct {
+	// Trie nodes by their keccak hash
+	Nodes map[libcommon.Hash][]byte
+	// Contract codes by their keccak hash
+	Codes   map[libcommon.Hash][]byte
+	Headers []*types.Header
+}
+
+type executionWitnessJSON struct {
+	State   json.RawMessage   `json:"state"`
+	Codes   json.RawMessage   `json:"codes"`
+	Headers []json.RawMessage `json:"headers"`
+}
+
+// LoadExecutionWitness reads a debug_executionWitness response, with or without the JSON-RPC envelope.
+func LoadExecutionWitness(path string) (*ExecutionWitness, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = unwrapRPCResult(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw executionWitnessJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse execution witness: %w", err)
+	}
+	nodes, err := hashedBlobs(raw.State)
+	if err != nil {
+		return nil, fmt.Errorf("invalid witness state: %w", err)
+	}
+	codes, err := hashedBlobs(raw.Codes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid witness codes: %w", err)
+	}
+	witness := &ExecutionWitness{Nodes: nodes, Codes: codes}
+	for _, encoded := range raw.Headers {
+		header, err := parseWitnessHeader(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid witness header: %w", err)
+		}
+		witness.Headers = append(witness.Headers, header)
+	}
+	return witness, nil
+}
+
+// unwrapRPCResult returns the result of a JSON-RPC response, or the data itself when it is not one.
+func unwrapRPCResult(data []byte) ([]byte, error) {
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Error != nil {
+		return nil, fmt.Errorf("JSON-RPC error: %s", envelope.Error.Message)
+	}
+	if envelope.Result != nil {
+		return envelope.Result, nil
+	}
+	return data, nil
+}
+
+// hashedBlobs indexes hex blobs by their keccak hash, given as a list or as a hash to blob object.
+func hashedBlobs(raw json.RawMessage) (map[libcommon.Hash][]byte, error) {
+	blobs := make(map[libcommon.Hash][]byte)
+	if len(raw) == 0 || string(raw) == "null" {
+		return blobs, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var object map[string]string
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, err
+		}
+		for _, value := range object {
+			list = append(list, value)
+		}
+	}
+	for _, value := range list {
+		blob, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+		if err != nil {
+			return nil, err
+		}
+		blobs[libcommon.BytesToHash(crypto.Keccak256(blob))] = blob
+	}
+	return blobs, nil
+}
+
+// parseWitnessHeader accepts an RLP encoded header (reth, erigon) or a header object (geth).
+func parseWitnessHeader(raw json.RawMessage) (*types.Header, error) {
+	header := new(types.Header)
+	var encoded hexutil.Bytes
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return header, json.Unmarshal(raw, header)
+	}
+	return header, rlp.DecodeBytes(encoded, header)
+}
+
+// Header returns the ancestor header with the given number.
+func (w *ExecutionWitness) Header(number uint64) (*types.Header, bool) {
+	for _, header := range w.Headers {
+		if header.Number.Uint64() == number {
+			return header, true
+		}
+	}
+	return nil, false
+}
+
+// =============================================================================
+// WITNESS STATE READER
+// =============================================================================
+
+// WitnessStateReader reads accounts, storage and code from the state trie of an execution witness.
+type WitnessStateReader struct {
+	witness   *ExecutionWitness
+	stateRoot libcommon.Hash
+	// Decoded accounts, nil for the ones proven absent
+	accounts map[libcommon.Address]*accounts.Account
+}
+
+func NewWitnessStateReader(witness *ExecutionWitness, stateRoot libcommon.Hash) *WitnessStateReader {
+	return &WitnessStateReader{
+		witness:   witness,
+		stateRoot: stateRoot,
+		accounts:  make(map[libcommon.Address]*accounts.Account),
+	}
+}
+
+func (r *WitnessStateReader) ReadAccountData(address libcommon.Address) (*accounts.Account, error) {
+	if account, ok := r.accounts[address]; ok {
+		return account, nil
+	}
+
+	value, err := r.witness.trieGet(r.stateRoot, crypto.Keccak256(address[:]))
+	if err != nil {
+		return nil, fmt.Errorf("account %s: %w", address.Hex(), err)
+	}
+	var account *accounts.Account
+	if value != nil {
+		account, err = decodeTrieAccount(value)
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %w", address.Hex(), err)
+		}
+	}
+	r.accounts[address] = account
+	return account, nil
+}
+
+func (r *WitnessStateReader) ReadAccountDataForDebug(address libcommon.Address) (*accounts.Account, error) {
+	return r.ReadAccountData(address)
+}
+
+func (r *WitnessStateReader) ReadAccountStorage(address libcommon.Address, key libcommon.Hash) (uint256.Int, bool, error) {
+	account, err := r.ReadAccountData(address)
+	if err != nil || account == nil || account.Root == emptyRootHash {
+		return uint256.Int{}, false, err
+	}
+	value, err := r.witness.trieGet(account.Root, crypto.Keccak256(key[:]))
+	if err != nil {
+		return uint256.Int{}, false, fmt.Errorf("storage %s of %s: %w", key.Hex(), address.Hex(), err)
+	}
+	if value == nil {
+		return uint256.Int{}, false, nil
+	}
+	content, _, err := rlp.SplitString(value)
+	if err != nil || len(content) > 32 {
+		return uint256.Int{}, false, fmt.Errorf("storage %s of %s: invalid value", key.Hex(), address.Hex())
+	}
+	return *new(uint256.Int).SetBytes(content), true, nil
+}
+
+func (r *WitnessStateReader) HasStorage(address libcommon.Address) (bool, error) {
+	account, err := r.ReadAccountData(address)
+	if err != nil || account == nil {
+		return false, err
+	}
+	return account.Root != emptyRootHash, nil
+}
+
+func (r *WitnessStateReader) ReadAccountCode(address libcommon.Address) ([]byte, error) {
+	account, err := r.ReadAccountData(address)
+	if err != nil || account == nil || account.CodeHash == emptyCodeHash {
+		return nil, err
+	}
+	code, ok := r.witness.Codes[account.CodeHash]
+	if !ok {
+		return nil, fmt.Errorf("witness has no code %s for %s", account.CodeHash.Hex(), address.Hex())
+	}
+	return code, nil
+}
+
+func (r *WitnessStateReader) ReadAccountCodeSize(address libcommon.Address) (int, error) {
+	code, err := r.ReadAccountCode(address)
+	return len(code), err
+}
+
+func (r *WitnessStateReader) ReadAccountIncarnation(address libcommon.Address) (uint64, error) {
+	account, err := r.ReadAccountData(address)
+	if err != nil || account == nil {
+		return 0, err
+	}
+	return account.Incarnation, nil
+}
+
+// decodeTrieAccount decodes the [nonce, balance, storageRoot, codeHash] leaf of the state trie.
+func decodeTrieAccount(value []byte) (*accounts.Account, error) {
+	var leaf struct {
+		Nonce    uint64
+		Balance  *big.Int
+		Root     libcommon.Hash
+		CodeHash libcommon.Hash
+	}
+	if err := rlp.DecodeBytes(value, &leaf); err != nil {
+		return nil, err
+	}
+
+	account := &accounts.Account{
+		Nonce:    leaf.Nonce,
+		Root:     leaf.Root,
+		CodeHash: leaf.CodeHash,
+	}
+	if account.Balance.SetFromBig(leaf.Balance) {
+		return nil, errors.New("invalid account balance")
+	}
+	// Erigon gives contracts an incarnation, storage is only read for those
+	if account.CodeHash != emptyCodeHash {
+		account.Incarnation = 1
+	}
+	return account, nil
+}
+
+// trieGet looks up a key in the trie with the given root, nil when the witness proves it absent.
+func (w *ExecutionWitness) trieGet(root libcommon.Hash, key []byte) ([]byte, error) {
+	if root == emptyRootHash {
+		return nil, nil
+	}
+	path := make([]byte, 0, len(key)*2)
+	for _, b := range key {
+		path = append(path, b>>4, b&0x0f)
+	}
+
+	node, err := w.resolve(root[:])
+	if err != nil {
+		return nil, err
+	}
+	for {
+		items, err := trieNodeItems(node)
+		if err != nil {
+			return nil, err
+		}
+
+		var child []byte
+		switch len(items) {
+		case 17:
+			if len(path) == 0 {
+				value, _, err := rlp.SplitString(items[16])
+				if err != nil || len(value) == 0 {
+					return nil, err
+				}
+				return value, nil
+			}
+			child = items[path[0]]
+			path = path[1:]
+		case 2:
+			encodedPath, _, err := rlp.SplitString(items[0])
+			if err != nil || len(encodedPath) == 0 {
+				return nil, errors.New("invalid trie node path")
+			}
+			nibbles, leaf := decodeHexPrefix(encodedPath)
+			if !bytes.HasPrefix(path, nibbles) {
+				return nil, nil
+			}
+			path = path[len(nibbles):]
+			if leaf {
+				if len(path) != 0 {
+					return nil, nil
+				}
+				value, _, err := rlp.SplitString(items[1])
+				return value, err
+			}
+			child = items[1]
+		default:
+			return nil, fmt.Errorf("trie node with %d items", len(items))
+		}
+
+		// Children are referenced by hash, or embedded when their encoding is shorter than 32 bytes
+		kind, reference, _, err := rlp.Split(child)
+		if err != nil {
+			return nil, err
+		}
+		if kind == rlp.List {
+			node = child
+			continue
+		}
+		if len(reference) == 0 {
+			return nil, nil
+		}
+		if node, err = w.resolve(reference); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (w *ExecutionWitness) resolve(hash []byte) ([]byte, error) {
+	node, ok := w.Nodes[libcommon.BytesToHash(hash)]
+	if !ok {
+		return nil, fmt.Errorf("witness has no trie node %x", hash)
+	}
+	return node, nil
+}
+
+// decodeHexPrefix returns the nibbles of a compact encoded path and whether it ends in a leaf.
+func decodeHexPrefix(encoded []byte) ([]byte, bool) {
+	flag := encoded[0] >> 4
+	nibbles := make([]byte, 0, len(encoded)*2)
+	if flag&1 != 0 {
+		nibbles = append(nibbles, encoded[0]&0x0f)
+	}
+	for _, b := range encoded[1:] {
+		nibbles = append(nibbles, b>>4, b&0x0f)
+	}
+	return nibbles, flag&2 != 0
+}
+
+// trieNodeItems splits a branch or short node into its encoded items.
+func trieNodeItems(node []byte) ([][]byte, error) {
+	content, _, err := rlp.SplitList(node)
+	if err != nil {
+		return nil, err
+	}
+	var items [][]byte
+	for len(content) > 0 {
+		_, _, rest, err := rlp.Split(content)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, content[:len(content)-len(rest)])
+		content = rest
+	}
+	return items, nil
+}
@@ -0,0 +1,158 @@
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/erigontech/erigon-lib/chain"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/types"
+	"github.com/erigontech/erigon/consensus"
+	"github.com/erigontech/erigon/consensus/ethash"
+	"github.com/erigontech/erigon/consensus/merge"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/state"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/params"
+)
+
+// =============================================================================
+// BLOCK FILE
+// =============================================================================
+
+// WitnessBlock is the header and transactions of a block to re-execute on its execution witness.
+type WitnessBlock struct {
+	Header       *types.Header
+	Transactions types.Transactions
+}
+
+// LoadWitnessBlock reads an eth_getBlockByNumber response with full transactions, with or without the JSON-RPC
+// envelope.
+func LoadWitnessBlock(path string) (*WitnessBlock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = unwrapRPCResult(data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := new(types.Header)
+	if err := json.Unmarshal(data, header); err != nil {
+		return nil, fmt.Errorf("failed to parse block header: %w", err)
+	}
+	var body struct {
+		Transactions []json.RawMessage `json:"transactions"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse block transactions: %w", err)
+	}
+	block := &WitnessBlock{Header: header}
+	for i, encoded := range body.Transactions {
+		txn, err := types.UnmarshalTransactionFromJSON(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d, was the block fetched with full transactions? %w", i, err)
+		}
+		block.Transactions = append(block.Transactions, txn)
+	}
+	return block, nil
+}
+
+// =============================================================================
+// STATELESS BLOCK EXECUTION
+// =============================================================================
+
+// TraceWitnessBlock re-executes the transactions of a block on the pre-state of its execution witness and returns
+// their traces. At most maxTxs transactions are executed when it is positive.
+func TraceWitnessBlock(block *WitnessBlock, witness *ExecutionWitness, chainConfig *chain.Config, maxTxs int) ([]TracedTransaction, error) {
+	header := block.Header
+	number := header.Number.Uint64()
+	parent, ok := witness.Header(number - 1)
+	if !ok {
+		return nil, fmt.Errorf("witness has no header of the parent block %d", number-1)
+	}
+	// The pre-state is the parent's root, a witness of another chain or fork would execute the block on its state
+	if parent.Hash() != header.ParentHash {
+		return nil, fmt.Errorf("witness parent header %s is not the parent %s of block %d", parent.Hash().Hex(), header.ParentHash.Hex(), number)
+	}
+	ibs := state.New(NewWitnessStateReader(witness, parent.Root))
+	engine := merge.New(ethash.NewFaker())
+	chainReader := &witnessChain{config: chainConfig, witness: witness}
+
+	// The system calls (EIP-4788 beacon root, EIP-2935 block hashes) run before the transactions and aren't traced
+	if err := core.InitializeBlockExecution(engine, chainReader, header, chainConfig, ibs, state.NewNoopWriter(), log.Root(), nil); err != nil {
+		return nil, fmt.Errorf("failed to initialize the block: %w", err)
+	}
+
+	getHash := func(n uint64) (libcommon.Hash, error) {
+		// The witness contains the ancestors read by BLOCKHASH
+		ancestor, ok := witness.Header(n)
+		if !ok {
+			return libcommon.Hash{}, fmt.Errorf("witness has no header of block %d", n)
+		}
+		return ancestor.Hash(), nil
+	}
+	gasPool := new(core.GasPool).AddGas(header.GasLimit)
+	if header.BlobGasUsed != nil {
+		gasPool.AddBlobGas(*header.BlobGasUsed)
+	}
+	var gasUsed, blobGasUsed uint64
+
+	var traced []TracedTransaction
+	for i, txn := range block.Transactions {
+		if maxTxs > 0 && i >= maxTxs {
+			break
+		}
+		stateTracer := NewStateTracer()
+		stateTracer.SetTxIndex(i)
+		hooks := stateTracer.Hooks()
+		ibs.SetTxContext(number, i)
+		ibs.SetHooks(hooks)
+
+		// Reverted and failed transactions are traced up to where they stop, only invalid ones are errors
+		_, _, err := core.ApplyTransaction(chainConfig, getHash, engine, &header.Coinbase, gasPool, ibs, state.NewNoopWriter(),
+			header, txn, &gasUsed, &blobGasUsed, vm.Config{Tracer: hooks})
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%s): %w", i, txn.Hash().Hex(), err)
+		}
+		traced = append(traced, TracedTransaction{Instructions: stateTracer.GetInstructions(), State: stateTracer.GetExecutionState()})
+	}
+	return traced, nil
+}
+
+// witnessChain gives the consensus engine the config and the ancestor headers of the witness.
+type witnessChain struct {
+	consensus.ChainHeaderReader
+	config  *chain.Config
+	witness *ExecutionWitness
+}
+
+func (c *witnessChain) Config() *chain.Config {
+	return c.config
+}
+
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	header, _ := c.witness.Header(number)
+	return header
+}
+
+func (c *witnessChain) GetHeader(hash libcommon.Hash, number uint64) *types.Header {
+	header, ok := c.witness.Header(number)
+	if !ok || header.Hash() != hash {
+		return nil
+	}
+	return header
+}
+
+// ChainConfig returns the config of the chain with the given ID, its forks decide how the blocks execute.
+func ChainConfig(chainID uint64) (*chain.Config, error) {
+	for _, chainConfig := range []*chain.Config{params.MainnetChainConfig, params.SepoliaChainConfig} {
+		if chainConfig.ChainID.Uint64() == chainID {
+			return chainConfig, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported chain id %d, witness blocks can be executed on mainnet (1) and sepolia (11155111)", chainID)
+}
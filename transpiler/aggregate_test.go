@@ -0,0 +1,33 @@
+package transpiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"erigon-transpiler-risc-v/prover"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAggregatePublicValues(t *testing.T) {
+	publicValues := binary.LittleEndian.AppendUint32(nil, 2)
+	for _, fill := range []byte{0x11, 0x22} {
+		publicValues = append(publicValues, bytes.Repeat([]byte{fill}, 32)...)
+		publicValues = append(publicValues, bytes.Repeat([]byte{fill + 1}, 32)...)
+		publicValues = append(publicValues, bytes.Repeat([]byte{fill + 2}, 32)...)
+		publicValues = append(publicValues, bytes.Repeat([]byte{fill + 3}, 32)...)
+	}
+
+	transactions, err := prover.ParseAggregatePublicValues(publicValues)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, "0x"+string(bytes.Repeat([]byte("22"), 32)), transactions[1].TxHash)
+	assert.Equal(t, "0x"+string(bytes.Repeat([]byte("23"), 32)), transactions[1].AppExeCommit)
+	assert.Equal(t, "0x"+string(bytes.Repeat([]byte("24"), 32)), transactions[1].AppVmCommit)
+	assert.Equal(t, "0x"+string(bytes.Repeat([]byte("14"), 32)), transactions[0].PublicValues)
+
+	// The count has to fit in the public values
+	_, err = prover.ParseAggregatePublicValues(publicValues[:200])
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package transpiler
+
+import (
+	"encoding/binary"
+	"erigon-transpiler-risc-v/prover"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssemblerEncoding(t *testing.T) {
+	// Expected encodings are from the GNU / LLVM assembler
+	tests := []struct {
+		source   string
+		expected []uint32
+	}{
+		{"addi sp, sp, -32", []uint32{0xfe010113}},
+		{"li t0, 7", []uint32{0x00700293}},
+		{"li t0, 0x12345678", []uint32{0x123452b7, 0x67828293}},
+		{"li t1, 0xFFFFFFFF", []uint32{0xfff00313}},
+		{"sw t0, 0(sp)", []uint32{0x00512023}},
+		{"lw t1, 28(sp)", []uint32{0x01c12303}},
+		{"lb t1, 0(t0)", []uint32{0x00028303}},
+		{"sb t1, -1(t2)", []uint32{0xfe638fa3}},
+		{"add t2, t3, t4", []uint32{0x01de03b3}},
+		{"sub t0, t1, t2", []uint32{0x407302b3}},
+		{"mulhu t5, t3, t4", []uint32{0x03de3f33}},
+		{"divu a0, a1, a2", []uint32{0x02c5d533}},
+		{"remu a0, a1, a2", []uint32{0x02c5f533}},
+		{"slli t1, t0, 2", []uint32{0x00229313}},
+		{"srai t1, t0, 31", []uint32{0x41f2d313}},
+		{"sltu t3, t2, t3", []uint32{0x01c3be33}},
+		{"EBREAK", []uint32{prover.EbreakInstr}},
+		{"ret", []uint32{0x00008067}},
+		{"jr x0", []uint32{0x00000067}},
+		{"mv s3, sp", []uint32{0x00010993}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			program, err := prover.Assemble("execute:\n"+test.source, prover.DefaultElfLayout)
+			assert.NoError(t, err)
+
+			words := make([]uint32, len(program.Text)/4)
+			for i := range words {
+				words[i] = binary.LittleEndian.Uint32(program.Text[i*4:])
+			}
+			assert.Equal(t, test.expected, words)
+		})
+	}
+}
+
+func TestAssemblerResolvesLabels(t *testing.T) {
+	source := strings.Join([]string{
+		".section .data",
+		"value:",
+		"    .word 0x11223344",
+		".section .text",
+		"execute:",
+		"    la t0, value",
+		"    beqz t0, execute",
+		"    call execute",
+	}, "\n")
+
+	program, err := prover.Assemble(source, prover.ElfLayout{TextAddr: 0x1000, DataAddr: 0x12000, Entry: "execute"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0x1000), program.Entry)
+	assert.Equal(t, []byte{0x44, 0x33, 0x22, 0x11}, program.Data)
+
+	words := make([]uint32, len(program.Text)/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(program.Text[i*4:])
+	}
+	// auipc t0, 0x11 / addi t0, t0, 0 => 0x12000
+	assert.Equal(t, uint32(0x00011297), words[0])
+	assert.Equal(t, uint32(0x00028293), words[1])
+	// beqz t0, -8
+	assert.Equal(t, uint32(0xfe028ce3), words[2])
+	// auipc ra, 0 / jalr ra, -12(ra)
+	assert.Equal(t, uint32(0x00000097), words[3])
+	assert.Equal(t, uint32(0xff4080e7), words[4])
+
+	_, err = prover.Assemble("execute:\n    call missing", prover.DefaultElfLayout)
+	assert.ErrorIs(t, err, prover.ErrUndefinedSymbol)
+
+	// Other errors are not mistaken for a missing label
+	_, err = prover.Assemble("execute:\n    addi t0, t0, 0x100000", prover.DefaultElfLayout)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, prover.ErrUndefinedSymbol)
+}
+
+func TestAssemblerLargeProgram(t *testing.T) {
+	// Larger than the 0x11000 bytes of text the fixed layout had room for
+	lines := []string{".section .data", "value:", "    .word 7", "    .space 0x20000", ".section .text", "execute:"}
+	for range 40000 {
+		lines = append(lines, "    addi t0, t0, 1")
+	}
+	lines = append(lines, "    la t1, value", "    lw t1, 0(t1)", "    EBREAK", "    ret")
+
+	bytecode, err := prover.AssembleElf(strings.Join(lines, "\n"), prover.DefaultElfLayout)
+	assert.NoError(t, err)
+
+	execution, err := prover.NewRunner()
+	assert.NoError(t, err)
+	result, err := execution.Execute(bytecode)
+	assert.NoError(t, err)
+	assert.Len(t, *result.StackSnapshots, 1)
+}
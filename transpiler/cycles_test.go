@@ -0,0 +1,52 @@
+package transpiler
+
+import (
+	"erigon-transpiler-risc-v/prover"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountCycles(t *testing.T) {
+	file := prover.AssemblyFile{
+		Instructions: []prover.Instruction{
+			{Name: "mv", Operands: []string{"s2", "sp"}},
+			{Name: "addi", Operands: []string{"sp", "sp", "-64"}},
+			{Name: "li", Operands: []string{"t0", "0x12345678"}},
+			{Name: "sw", Operands: []string{"t0", "0(sp)"}},
+			{Name: "sw", Operands: []string{"t0", "32(sp)"}},
+			{Name: "EBREAK", Operands: []string{}},
+
+			{Name: "addi", Operands: []string{"a0", "sp", "0"}},
+			{Name: "addi", Operands: []string{"a1", "sp", "32"}},
+			{Name: "addi", Operands: []string{"a2", "sp", "32"}},
+			{Name: "call", Operands: []string{"add256_stack_scratch"}},
+			{Name: "EBREAK", Operands: []string{}},
+
+			{Name: "mv", Operands: []string{"sp", "s2"}},
+		},
+		Sections: []prover.InstructionSection{
+			{Name: "PUSH32", Start: 1},
+			{Name: "ADD", Start: 6},
+			{Name: "PUSH32", Start: 11},
+		},
+	}
+
+	report, err := file.CountCycles()
+	assert.NoError(t, err)
+
+	// li expands to lui + addi, call to auipc + jalr and the program ends with a jr x0
+	assert.Equal(t, uint64(1), report.Sections["execute"])
+	assert.Equal(t, uint64(5+2), report.Sections["PUSH32"])
+	assert.Equal(t, uint64(5), report.Sections["ADD"])
+	assert.Greater(t, report.Routines["add256_stack_scratch"], uint64(0))
+
+	total := uint64(0)
+	for _, count := range report.Sections {
+		total += count
+	}
+	for _, count := range report.Routines {
+		total += count
+	}
+	assert.Equal(t, report.Total, total)
+}
@@ -0,0 +1,53 @@
+package transpiler
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// StackDivergence is the first point where the RISC-V stack snapshots differ from the EVM ones.
+type StackDivergence struct {
+	// Index into the snapshots, snapshot i is the stack after the EVM instruction i
+	Snapshot int
+	// Counted from the top of the stack, -1 when one side has no snapshot at this index
+	Slot     int
+	Expected []uint256.Int
+	Actual   []uint256.Int
+}
+
+// FirstStackDivergence compares the EVM snapshots to the RISC-V snapshots, returns nil when they are equal.
+func FirstStackDivergence(expected, actual [][]uint256.Int) *StackDivergence {
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		if i >= len(expected) || i >= len(actual) {
+			divergence := &StackDivergence{Snapshot: i, Slot: -1}
+			if i < len(expected) {
+				divergence.Expected = expected[i]
+			} else {
+				divergence.Actual = actual[i]
+			}
+			return divergence
+		}
+
+		slot := firstSlotDivergence(expected[i], actual[i])
+		if slot >= 0 {
+			return &StackDivergence{
+				Snapshot: i,
+				Slot:     slot,
+				Expected: expected[i],
+				Actual:   actual[i],
+			}
+		}
+	}
+	return nil
+}
+
+func firstSlotDivergence(expected, actual []uint256.Int) int {
+	for slot := 0; slot < len(expected) || slot < len(actual); slot++ {
+		if slot >= len(expected) || slot >= len(actual) {
+			return slot
+		}
+		if !expected[len(expected)-1-slot].Eq(&actual[len(actual)-1-slot]) {
+			return slot
+		}
+	}
+	return -1
+}
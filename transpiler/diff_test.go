@@ -0,0 +1,36 @@
+package transpiler
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirstStackDivergence(t *testing.T) {
+	stack := func(values ...uint64) []uint256.Int {
+		result := make([]uint256.Int, len(values))
+		for i, value := range values {
+			result[i] = *uint256.NewInt(value)
+		}
+		return result
+	}
+	expected := [][]uint256.Int{stack(1), stack(1, 2), stack(3, 4, 5)}
+
+	assert.Nil(t, FirstStackDivergence(expected, [][]uint256.Int{stack(1), stack(1, 2), stack(3, 4, 5)}))
+
+	// Slots are counted from the top of the stack
+	divergence := FirstStackDivergence(expected, [][]uint256.Int{stack(1), stack(1, 2), stack(3, 0, 5)})
+	assert.Equal(t, 2, divergence.Snapshot)
+	assert.Equal(t, 1, divergence.Slot)
+
+	// The top entries match, the stack is too short
+	divergence = FirstStackDivergence(expected, [][]uint256.Int{stack(1), stack(2)})
+	assert.Equal(t, 1, divergence.Snapshot)
+	assert.Equal(t, 1, divergence.Slot)
+
+	divergence = FirstStackDivergence(expected, [][]uint256.Int{stack(1), stack(1, 2)})
+	assert.Equal(t, 2, divergence.Snapshot)
+	assert.Equal(t, -1, divergence.Slot)
+	assert.Equal(t, expected[2], divergence.Expected)
+}
@@ -0,0 +1,133 @@
+package transpiler
+
+import (
+	"fmt"
+	"testing"
+
+	"erigon-transpiler-risc-v/prover"
+
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fuzzMaxOpcodes   = 64
+	fuzzMaxStackSize = 32
+)
+
+type fuzzOpcode struct {
+	opcode vm.OpCode
+	pops   int
+	pushes int
+}
+
+// Opcodes that only depend on the stack, DUP and SWAP are added below
+var fuzzOpcodes = []fuzzOpcode{
+	{vm.ADD, 2, 1}, {vm.MUL, 2, 1}, {vm.SUB, 2, 1}, {vm.DIV, 2, 1},
+	{vm.SDIV, 2, 1}, {vm.MOD, 2, 1}, {vm.SMOD, 2, 1}, {vm.ADDMOD, 3, 1},
+	{vm.MULMOD, 3, 1}, {vm.EXP, 2, 1}, {vm.SIGNEXTEND, 2, 1},
+	{vm.LT, 2, 1}, {vm.GT, 2, 1}, {vm.SLT, 2, 1}, {vm.SGT, 2, 1},
+	{vm.EQ, 2, 1}, {vm.ISZERO, 1, 1}, {vm.AND, 2, 1}, {vm.OR, 2, 1},
+	{vm.XOR, 2, 1}, {vm.NOT, 1, 1}, {vm.BYTE, 2, 1}, {vm.SHL, 2, 1},
+	{vm.SHR, 2, 1}, {vm.SAR, 2, 1}, {vm.POP, 1, 0},
+	{vm.PUSH0, 0, 1}, {vm.PUSH1, 0, 1}, {vm.PUSH2, 0, 1}, {vm.PUSH4, 0, 1},
+	{vm.PUSH8, 0, 1}, {vm.PUSH20, 0, 1}, {vm.PUSH32, 0, 1},
+}
+
+// Values around the word and sign boundaries, pushed with PUSH32
+var fuzzEdgeValues = [][]byte{
+	{0x00},
+	{0x01},
+	{0xff},
+	{0x01, 0x00},
+	{0x7f, 0xff, 0xff, 0xff},
+	{0x80, 0x00, 0x00, 0x00},
+	{0xff, 0xff, 0xff, 0xff},
+	{0x01, 0x00, 0x00, 0x00, 0x00},
+	{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+}
+
+func init() {
+	for i := 0; i < 16; i++ {
+		fuzzOpcodes = append(fuzzOpcodes,
+			fuzzOpcode{vm.DUP1 + vm.OpCode(i), i + 1, i + 2},
+			fuzzOpcode{vm.SWAP1 + vm.OpCode(i), i + 2, i + 2},
+		)
+	}
+}
+
+// fuzzProgram decodes the fuzzer input into EVM bytecode that never underflows or overflows the stack.
+func fuzzProgram(data []byte) []byte {
+	var program []byte
+	depth := 0
+	next := func() byte {
+		if len(data) == 0 {
+			return 0
+		}
+		value := data[0]
+		data = data[1:]
+		return value
+	}
+
+	for opcodes := 0; opcodes < fuzzMaxOpcodes && len(data) > 0; opcodes++ {
+		selector := next()
+
+		// Every 8th selector pushes an edge value
+		if selector%8 == 7 {
+			value := fuzzEdgeValues[int(next())%len(fuzzEdgeValues)]
+			program = append(program, byte(vm.PUSH32))
+			program = append(program, make([]byte, 32-len(value))...)
+			program = append(program, value...)
+			depth++
+			continue
+		}
+
+		op := fuzzOpcodes[int(selector)%len(fuzzOpcodes)]
+		if depth < op.pops || depth-op.pops+op.pushes > fuzzMaxStackSize {
+			continue
+		}
+		program = append(program, byte(op.opcode))
+		if op.opcode >= vm.PUSH1 && op.opcode <= vm.PUSH32 {
+			for i := 0; i < int(op.opcode-vm.PUSH0); i++ {
+				program = append(program, next())
+			}
+		}
+		depth += op.pushes - op.pops
+	}
+
+	return append(program, byte(vm.STOP))
+}
+
+func FuzzTranspiler(f *testing.F) {
+	f.Add([]byte{})
+	// PUSH1 5, PUSH1 3, ADD
+	f.Add([]byte{27, 0x05, 27, 0x03, 0})
+	// Max word plus one
+	f.Add([]byte{7, 10, 7, 1, 0})
+
+	// The Go emulator, or Unicorn with -tags unicorn (make fuzz-unicorn)
+	execution, err := prover.NewRunner()
+	require.NoError(f, err)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		program := fuzzProgram(data)
+
+		assembly, evmSnapshot, err := NewTestRunner(program).Execute()
+		require.NoError(t, err, "Failed to execute bytecode %x", program)
+
+		bytecode, err := assembly.ToBytecode()
+		require.NoError(t, err)
+
+		snapshot, err := execution.Execute(bytecode)
+		require.NoError(t, err, "Failed to execute %x", program)
+
+		divergence := FirstStackDivergence(evmSnapshot.Snapshots, *snapshot.StackSnapshots)
+		assert.Nil(t, divergence, fmt.Sprintf("Stack mismatch for %x", program))
+	})
+}
@@ -33,7 +33,7 @@ func TestAddOpcode(t *testing.T) {
 	bytecode, err = assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 	snapshot, err := execution.Execute(bytecode)
 	assert.NoError(t, err)
@@ -293,7 +293,7 @@ func TestSimpleOpcodes(t *testing.T) {
 		bytecode, err = assembly.ToBytecode()
 		assert.NoError(t, err)
 
-		execution, err := prover.NewUnicornRunner()
+		execution, err := prover.NewRunner()
 		assert.NoError(t, err)
 		snapshot, err := execution.Execute(bytecode)
 		assert.NoError(t, err)
@@ -335,7 +335,7 @@ func TestCallValue(t *testing.T) {
 	bytecodeResult, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 	snapshot, err := execution.Execute(bytecodeResult)
 	assert.NoError(t, err)
@@ -363,7 +363,7 @@ func TestCallDataSize(t *testing.T) {
 	bytecodeResult, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 	snapshot, err := execution.Execute(bytecodeResult)
 	assert.NoError(t, err)
@@ -405,7 +405,7 @@ func TestCallDataLoad(t *testing.T) {
 			bytecodeResult, err := assembly.ToBytecode()
 			assert.NoError(t, err)
 
-			execution, err := prover.NewUnicornRunner()
+			execution, err := prover.NewRunner()
 			assert.NoError(t, err)
 			snapshot, err := execution.Execute(bytecodeResult)
 			assert.NoError(t, err)
@@ -439,7 +439,7 @@ func TestCodeCopy(t *testing.T) {
 	bytecodeResult, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 	snapshot, err := execution.Execute(bytecodeResult)
 	assert.NoError(t, err)
@@ -480,7 +480,7 @@ func TestHaltingOpcodes(t *testing.T) {
 			bytecode, err := assembly.ToBytecode()
 			assert.NoError(t, err)
 
-			execution, err := prover.NewUnicornRunner()
+			execution, err := prover.NewRunner()
 			assert.NoError(t, err)
 			snapshot, err := execution.Execute(bytecode)
 			assert.NoError(t, err)
@@ -522,7 +522,7 @@ func TestPushOpcodes(t *testing.T) {
 			bytecode, err = assembly.ToBytecode()
 			assert.NoError(t, err, "Failed to convert to bytecode for PUSH%d", i)
 
-			execution, err := prover.NewUnicornRunner()
+			execution, err := prover.NewRunner()
 			assert.NoError(t, err)
 			snapshot, err := execution.Execute(bytecode)
 			assert.NoError(t, err, "Failed to execute in prover for PUSH%d", i)
@@ -559,7 +559,7 @@ func TestSwapOpcodes(t *testing.T) {
 			bytecode, err = assembly.ToBytecode()
 			assert.NoError(t, err, "Failed to convert to bytecode for SWAP%d", swapIndex)
 
-			execution, err := prover.NewUnicornRunner()
+			execution, err := prover.NewRunner()
 			assert.NoError(t, err)
 			snapshot, err := execution.Execute(bytecode)
 			assert.NoError(t, err, "Failed to execute in prover for SWAP%d", swapIndex)
@@ -597,7 +597,7 @@ func TestDupOpcodes(t *testing.T) {
 			bytecode, err = assembly.ToBytecode()
 			assert.NoError(t, err, "Failed to convert to bytecode for DUP%d", dupIndex)
 
-			execution, err := prover.NewUnicornRunner()
+			execution, err := prover.NewRunner()
 			assert.NoError(t, err)
 			snapshot, err := execution.Execute(bytecode)
 			assert.NoError(t, err, "Failed to execute in prover for DUP%d", dupIndex)
@@ -743,7 +743,7 @@ func TestArithmeticOpcodes(t *testing.T) {
 			bytecode, err := assembly.ToBytecode()
 			assert.NoError(t, err, "Failed to convert to bytecode for %s", tc.name)
 
-			execution, err := prover.NewUnicornRunner()
+			execution, err := prover.NewRunner()
 			assert.NoError(t, err)
 			snapshot, err := execution.Execute(bytecode)
 			assert.NoError(t, err, "Failed to execute in prover for %s", tc.name)
@@ -833,7 +833,7 @@ func TestLogOpcodes(t *testing.T) {
 			bytecode, err := assembly.ToBytecode()
 			assert.NoError(t, err, "Failed to convert to bytecode for %s", tc.name)
 
-			execution, err := prover.NewUnicornRunner()
+			execution, err := prover.NewRunner()
 			assert.NoError(t, err)
 			snapshot, err := execution.Execute(bytecode)
 			assert.NoError(t, err, "Failed to execute in prover for %s", tc.name)
@@ -899,7 +899,7 @@ func TestMCopyOpcode(t *testing.T) {
 			bytecode, err := assembly.ToBytecode()
 			assert.NoError(t, err, "Failed to convert to bytecode for %s", tc.name)
 
-			execution, err := prover.NewUnicornRunner()
+			execution, err := prover.NewRunner()
 			assert.NoError(t, err)
 			snapshot, err := execution.Execute(bytecode)
 			assert.NoError(t, err, "Failed to execute in prover for %s", tc.name)
@@ -934,7 +934,7 @@ func TestCreate2Opcode(t *testing.T) {
 		bytecodeResult, err := assembly.ToBytecode()
 		assert.NoError(t, err)
 
-		execution, err := prover.NewUnicornRunner()
+		execution, err := prover.NewRunner()
 		assert.NoError(t, err)
 		snapshot, err := execution.Execute(bytecodeResult)
 		assert.NoError(t, err)
@@ -966,7 +966,7 @@ func TestCreateOpcode(t *testing.T) {
 		bytecodeResult, err := assembly.ToBytecode()
 		assert.NoError(t, err)
 
-		execution, err := prover.NewUnicornRunner()
+		execution, err := prover.NewRunner()
 		assert.NoError(t, err)
 		snapshot, err := execution.Execute(bytecodeResult)
 		assert.NoError(t, err)
@@ -0,0 +1,97 @@
+package transpiler
+
+import (
+	"erigon-transpiler-risc-v/prover"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/holiman/uint256"
+)
+
+// ErrMappingsAssembly is returned when the program of the mappings can't be assembled, e.g. a subset that lost a label.
+var ErrMappingsAssembly = errors.New("assembly generation")
+
+// MappingsAssembly concatenates the instructions and data variables of the mappings into one program.
+func MappingsAssembly(mappings []EvmToRiscVMapping) *prover.AssemblyFile {
+	var allInstructions []prover.Instruction
+	var sections []prover.InstructionSection
+	dataVarMap := make(map[string]prover.DataVariable)
+
+	for _, mapping := range mappings {
+		sections = append(sections, prover.InstructionSection{
+			Name:   mapping.EvmOpcode,
+			Start:  len(allInstructions),
+			Source: mapping.Source,
+		})
+		allInstructions = append(allInstructions, mapping.RiscVInstructions...)
+
+		for _, dataVar := range mapping.DataVariables {
+			dataVarMap[dataVar.Name] = dataVar
+		}
+	}
+
+	allDataVars := make([]prover.DataVariable, 0, len(dataVarMap))
+	for _, dataVar := range dataVarMap {
+		allDataVars = append(allDataVars, dataVar)
+	}
+	sort.Slice(allDataVars, func(i, j int) bool {
+		return allDataVars[i].Name < allDataVars[j].Name
+	})
+
+	return &prover.AssemblyFile{
+		Instructions: allInstructions,
+		DataSection:  allDataVars,
+		Sections:     sections,
+	}
+}
+
+// CheckMappings runs the program of the mappings and compares its snapshots to the EVM stacks recorded in them,
+// nil when they match.
+func CheckMappings(mappings []EvmToRiscVMapping, runner prover.Runner) error {
+	bytecode, err := MappingsAssembly(mappings).ToBytecode()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMappingsAssembly, err)
+	}
+
+	result, err := runner.Execute(bytecode)
+	if err != nil {
+		return err
+	}
+
+	expected := expectedSnapshots(mappings, *result.StackSnapshots)
+	divergence := FirstStackDivergence(expected, *result.StackSnapshots)
+	if divergence != nil {
+		return fmt.Errorf("stack diverged at snapshot %d, slot %d", divergence.Snapshot, divergence.Slot)
+	}
+	return nil
+}
+
+// expectedSnapshots returns the EVM stack for each EBREAK of the mappings.
+// The stacks that weren't recorded are taken from the emulator, so they always match.
+func expectedSnapshots(mappings []EvmToRiscVMapping, actual [][]uint256.Int) [][]uint256.Int {
+	var expected [][]uint256.Int
+	for _, mapping := range mappings {
+		for _, instruction := range mapping.RiscVInstructions {
+			if instruction.Name != prover.InstructionEBREAK {
+				continue
+			}
+			stack := mapping.EvmStack
+			if stack == nil && len(expected) < len(actual) {
+				stack = actual[len(expected)]
+			}
+			expected = append(expected, stack)
+		}
+	}
+	return expected
+}
+
+// HasEvmStacks reports whether any of the mappings recorded the EVM stack.
+func HasEvmStacks(mappings []EvmToRiscVMapping) bool {
+	for _, mapping := range mappings {
+		if mapping.EvmStack != nil {
+			return true
+		}
+	}
+	return false
+}
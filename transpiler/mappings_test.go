@@ -0,0 +1,58 @@
+package transpiler
+
+import (
+	"testing"
+
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMappingsWithDummyZeros(t *testing.T) {
+	stack := func(values ...uint64) []uint256.Int {
+		result := make([]uint256.Int, len(values))
+		for i, value := range values {
+			result[i] = *uint256.NewInt(value)
+		}
+		return result
+	}
+	instructions := []*tracer.EvmInstructionMetadata{
+		{Opcode: vm.PUSH1, Arguments: []byte{0x05}, StackSnapshot: stack()},
+		{Opcode: vm.PUSH1, Arguments: []byte{0x03}, StackSnapshot: stack(5)},
+		{Opcode: vm.ADD, StackSnapshot: stack(5, 3)},
+		{Opcode: vm.PUSH1, Arguments: []byte{0x01}, StackSnapshot: stack(8)},
+		{Opcode: vm.SWAP1, StackSnapshot: stack(8, 1)},
+		{Opcode: vm.POP, StackSnapshot: stack(1, 8)},
+		{Opcode: vm.STOP, StackSnapshot: stack(1)},
+	}
+	state := &tracer.EvmExecutionState{CallValue: uint256.NewInt(0)}
+
+	// The default transpiler pushes a zero for the ADD, the EVM stack has 8
+	transpiler := NewTranspiler()
+	_, err := transpiler.ProcessExecution(instructions, state)
+	require.NoError(t, err)
+	mappings := transpiler.GetDebugMappings()
+	require.Equal(t, "ADD", mappings[2].EvmOpcode)
+	assert.Equal(t, stack(5, 3), mappings[1].EvmStack)
+	for _, mapping := range mappings[2:] {
+		assert.Nil(t, mapping.EvmStack, mapping.EvmOpcode)
+	}
+
+	runner, err := prover.NewRunner()
+	require.NoError(t, err)
+	for end := range mappings {
+		assert.NoError(t, CheckMappings(mappings[:end+1], runner), "prefix up to %s", mappings[end].EvmOpcode)
+	}
+
+	// With the host-optimized opcodes every stack is recorded and matches
+	transpiler = NewTestTranspiler()
+	_, err = transpiler.ProcessExecution(instructions, state)
+	require.NoError(t, err)
+	mappings = transpiler.GetDebugMappings()
+	assert.Equal(t, stack(8), mappings[2].EvmStack)
+	assert.NoError(t, CheckMappings(mappings, runner))
+}
@@ -0,0 +1,61 @@
+package transpiler
+
+// MinimizeMappings shrinks a failing list of mappings with delta debugging (ddmin).
+// fails must be true for the input, the result is a subsequence for which it is still true
+// and removing any single mapping makes it false.
+func MinimizeMappings(mappings []EvmToRiscVMapping, fails func([]EvmToRiscVMapping) bool) []EvmToRiscVMapping {
+	current := mappings
+	granularity := 2
+
+	for len(current) >= 2 {
+		chunks := splitMappings(current, granularity)
+		reduced := false
+
+		for _, chunk := range chunks {
+			if fails(chunk) {
+				current = chunk
+				granularity = 2
+				reduced = true
+				break
+			}
+		}
+
+		// With two chunks the complements are the chunks themselves
+		if !reduced && granularity > 2 {
+			for i := range chunks {
+				complement := make([]EvmToRiscVMapping, 0, len(current)-len(chunks[i]))
+				for j, chunk := range chunks {
+					if j != i {
+						complement = append(complement, chunk...)
+					}
+				}
+				if fails(complement) {
+					current = complement
+					granularity = max(granularity-1, 2)
+					reduced = true
+					break
+				}
+			}
+		}
+
+		if !reduced {
+			if granularity >= len(current) {
+				break
+			}
+			granularity = min(granularity*2, len(current))
+		}
+	}
+	return current
+}
+
+// splitMappings splits the mappings into n contiguous chunks of almost equal size.
+func splitMappings(mappings []EvmToRiscVMapping, n int) [][]EvmToRiscVMapping {
+	chunks := make([][]EvmToRiscVMapping, 0, n)
+	start := 0
+	for i := 0; i < n; i++ {
+		end := start + (len(mappings)-start)/(n-i)
+		chunks = append(chunks, mappings[start:end])
+		start = end
+	}
+	return chunks
+}
@@ -0,0 +1,41 @@
+package transpiler
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinimizeMappings(t *testing.T) {
+	var mappings []EvmToRiscVMapping
+	for _, opcode := range []string{"PUSH1", "PUSH1", "ADD", "CALL", "PUSH1", "MSTORE", "SLOAD", "POP", "STACK_RESTORE", "RETURN"} {
+		mappings = append(mappings, EvmToRiscVMapping{EvmOpcode: opcode})
+	}
+	opcodes := func(mappings []EvmToRiscVMapping) []string {
+		var result []string
+		for _, mapping := range mappings {
+			result = append(result, mapping.EvmOpcode)
+		}
+		return result
+	}
+
+	// Fails when a CALL is followed by an SLOAD
+	checks := 0
+	fails := func(subset []EvmToRiscVMapping) bool {
+		checks++
+		names := opcodes(subset)
+		call := slices.Index(names, "CALL")
+		return call >= 0 && slices.Contains(names[call:], "SLOAD")
+	}
+
+	minimized := MinimizeMappings(mappings, fails)
+	assert.Equal(t, []string{"CALL", "SLOAD"}, opcodes(minimized))
+	assert.Less(t, checks, len(mappings)*len(mappings))
+
+	// Fails on its own
+	minimized = MinimizeMappings(mappings, func(subset []EvmToRiscVMapping) bool {
+		return slices.Contains(opcodes(subset), "MSTORE")
+	})
+	assert.Equal(t, []string{"MSTORE"}, opcodes(minimized))
+}
@@ -75,7 +75,7 @@ func TestNestedCallABC(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := execution.Execute(riscvBytecode)
@@ -149,7 +149,7 @@ func TestNestedCallDepth4(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := execution.Execute(riscvBytecode)
@@ -202,7 +202,7 @@ func TestDelegateCall(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := execution.Execute(riscvBytecode)
@@ -255,7 +255,7 @@ func TestStaticCall(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := execution.Execute(riscvBytecode)
@@ -309,7 +309,7 @@ func TestCallCode(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := execution.Execute(riscvBytecode)
@@ -363,7 +363,7 @@ func TestNestedCallWithRevert(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := execution.Execute(riscvBytecode)
@@ -422,7 +422,7 @@ func TestNestedCallWithInvalid(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := execution.Execute(riscvBytecode)
@@ -482,7 +482,7 @@ func TestDelegateCallWithRevert(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := execution.Execute(riscvBytecode)
@@ -539,7 +539,7 @@ func TestCallOpcodeWithStackSeparation(t *testing.T) {
 	riscvBytecode, err := assembly.ToBytecode()
 	assert.NoError(t, err)
 
-	execution, err := prover.NewUnicornRunner()
+	execution, err := prover.NewRunner()
 	assert.NoError(t, err)
 	snapshot, err := execution.Execute(riscvBytecode)
 	assert.NoError(t, err)
@@ -551,3 +551,205 @@ func TestCallOpcodeWithStackSeparation(t *testing.T) {
 		assertStackEqual(t, evmSnapshot.Snapshots[i], snapShot[i], fmt.Sprintf("Stack mismatch at instruction %d", i))
 	}
 }
+
+func TestNestedCallReturnDataSize(t *testing.T) {
+	contractA := []byte{
+		byte(vm.RETURNDATASIZE),
+		byte(vm.PUSH1), 0x20,
+		byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH20), 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22,
+		0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22,
+		byte(vm.PUSH2), 0x27, 0x10,
+		byte(vm.CALL),
+		byte(vm.RETURNDATASIZE),
+		byte(vm.STOP),
+	}
+
+	contractB := []byte{
+		byte(vm.RETURNDATASIZE),
+		byte(vm.PUSH1), 0x42,
+		byte(vm.PUSH0),
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x24,
+		byte(vm.PUSH0),
+		byte(vm.RETURN),
+	}
+
+	testRunner := NewTestRunnerWithConfig(contractA, TestConfig{
+		CallValue: uint256.NewInt(0),
+		CallData:  []byte{},
+	})
+
+	addrB := libcommon.HexToAddress("0x2222222222222222222222222222222222222222")
+	err := testRunner.DeployContract(addrB, contractB)
+	assert.NoError(t, err)
+
+	assembly, evmSnapshot, err := testRunner.Execute()
+	assert.NoError(t, err)
+
+	riscvBytecode, err := assembly.ToBytecode()
+	assert.NoError(t, err)
+
+	execution, err := prover.NewRunner()
+	assert.NoError(t, err)
+
+	snapshot, err := execution.Execute(riscvBytecode)
+	assert.NoError(t, err)
+
+	snapShot := *snapshot.StackSnapshots
+	assert.Len(t, snapShot, len(evmSnapshot.Snapshots), "Snapshot length should match")
+
+	finalStack := snapShot[len(snapShot)-1]
+
+	assert.Len(t, finalStack, 3, "Final stack should have 3 elements")
+	assert.Equal(t, uint64(0), finalStack[0].Uint64(), "First element should be the empty return data size")
+	assert.Equal(t, uint64(1), finalStack[1].Uint64(), "Second element should be success flag (1)")
+	assert.Equal(t, uint64(0x24), finalStack[2].Uint64(), "Third element should be the return data size of B")
+
+	for i := range evmSnapshot.Snapshots {
+		assertStackEqual(t, evmSnapshot.Snapshots[i], snapShot[i], fmt.Sprintf("Stack mismatch at instruction %d", i))
+	}
+}
+
+func TestCallFrameStartsAtCallerStack(t *testing.T) {
+	contractA := []byte{
+		byte(vm.PUSH1), 0xAA,
+		byte(vm.PUSH0),
+		byte(vm.PUSH0),
+		byte(vm.PUSH0),
+		byte(vm.PUSH0),
+		byte(vm.PUSH0),
+		byte(vm.PUSH20), 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22,
+		0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22,
+		byte(vm.GAS),
+		byte(vm.CALL),
+		byte(vm.STOP),
+	}
+
+	contractB := []byte{
+		byte(vm.PUSH1), 0xBB,
+		byte(vm.PUSH1), 0xBB,
+		byte(vm.STOP),
+	}
+
+	testRunner := NewTestRunnerWithConfig(contractA, TestConfig{
+		CallValue: uint256.NewInt(0),
+		CallData:  []byte{},
+	})
+	err := testRunner.DeployContract(libcommon.HexToAddress("0x2222222222222222222222222222222222222222"), contractB)
+	assert.NoError(t, err)
+
+	assembly, evmSnapshot, err := testRunner.Execute()
+	assert.NoError(t, err)
+	riscvBytecode, err := assembly.ToBytecode()
+	assert.NoError(t, err)
+
+	stepper, err := prover.NewStepper(riscvBytecode)
+	assert.NoError(t, err)
+	emu := stepper.Emulator()
+	contextStackTop := emu.Registers[prover.RegS1]
+	calleeSnapshots := 0
+	for range evmSnapshot.Snapshots {
+		_, err := stepper.Next()
+		assert.NoError(t, err)
+		if emu.Registers[prover.RegS1] == contextStackTop {
+			continue
+		}
+		// The callee's stack starts at the caller's sp, saved above its s3 on the call context stack
+		callerSP, err := emu.ReadWord(emu.Registers[prover.RegS1] + 4)
+		assert.NoError(t, err)
+		assert.Equal(t, callerSP, emu.Registers[prover.RegS3], "callee stack should start at the caller's sp")
+		calleeSnapshots++
+	}
+	assert.Positive(t, calleeSnapshots, "B should have been stepped through")
+}
+
+func TestCreateClearsReturnData(t *testing.T) {
+	// The init code returns 32 bytes of runtime code: PUSH1 0x20, PUSH0, RETURN
+	contractA := []byte{
+		byte(vm.PUSH4), 0x60, 0x20, 0x5f, 0xf3,
+		byte(vm.PUSH0),
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x04,
+		byte(vm.PUSH1), 0x1c,
+		byte(vm.PUSH0),
+		byte(vm.CREATE),
+		byte(vm.RETURNDATASIZE),
+		byte(vm.STOP),
+	}
+
+	assembly, evmSnapshot, err := NewTestRunnerWithConfig(contractA, TestConfig{
+		CallValue: uint256.NewInt(0),
+		CallData:  []byte{},
+	}).Execute()
+	assert.NoError(t, err)
+
+	riscvBytecode, err := assembly.ToBytecode()
+	assert.NoError(t, err)
+
+	execution, err := prover.NewRunner()
+	assert.NoError(t, err)
+
+	snapshot, err := execution.Execute(riscvBytecode)
+	assert.NoError(t, err)
+
+	snapShot := *snapshot.StackSnapshots
+	assert.Len(t, snapShot, len(evmSnapshot.Snapshots), "Snapshot length should match")
+
+	finalStack := snapShot[len(snapShot)-1]
+	assert.Len(t, finalStack, 2, "Final stack should have 2 elements")
+	assert.False(t, finalStack[0].IsZero(), "First element should be the created address")
+	assert.Equal(t, uint64(0), finalStack[1].Uint64(), "Second element should be the empty return data size")
+
+	for i := range evmSnapshot.Snapshots {
+		assertStackEqual(t, evmSnapshot.Snapshots[i], snapShot[i], fmt.Sprintf("Stack mismatch at instruction %d", i))
+	}
+}
+
+// A callee that ends in STOP, like a Solidity function without a return value, keeps the snapshots in line
+func TestNestedCallCalleeStop(t *testing.T) {
+	contractA := []byte{
+		byte(vm.PUSH1), 0xAA,
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH20), 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22,
+		byte(vm.PUSH2), 0x27, 0x10,
+		byte(vm.CALL),
+		byte(vm.PUSH1), 0xDD,
+		byte(vm.PUSH1), 0xEE,
+		byte(vm.STOP),
+	}
+
+	contractB := []byte{
+		byte(vm.PUSH1), 0xBB,
+		byte(vm.PUSH1), 0xBC,
+		byte(vm.STOP),
+	}
+
+	testRunner := NewTestRunnerWithConfig(contractA, TestConfig{
+		CallValue: uint256.NewInt(0),
+		CallData:  []byte{},
+	})
+	err := testRunner.DeployContract(libcommon.HexToAddress("0x2222222222222222222222222222222222222222"), contractB)
+	assert.NoError(t, err)
+
+	assembly, evmSnapshot, err := testRunner.Execute()
+	assert.NoError(t, err)
+	riscvBytecode, err := assembly.ToBytecode()
+	assert.NoError(t, err)
+	execution, err := prover.NewRunner()
+	assert.NoError(t, err)
+	snapshot, err := execution.Execute(riscvBytecode)
+	assert.NoError(t, err)
+
+	snapShot := *snapshot.StackSnapshots
+	assert.Len(t, snapShot, len(evmSnapshot.Snapshots))
+	for i := range min(len(snapShot), len(evmSnapshot.Snapshots)) {
+		assertStackEqual(t, evmSnapshot.Snapshots[i], snapShot[i], fmt.Sprintf("Stack mismatch at instruction %d", i))
+	}
+	finalStack := snapShot[len(snapShot)-1]
+	assert.Len(t, finalStack, 4)
+	assert.Equal(t, uint64(0xEE), finalStack[3].Uint64())
+}
@@ -0,0 +1,94 @@
+package transpiler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrestateStateReader(t *testing.T) {
+	addrA := libcommon.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	// Loads its slot 1, calls B and loads the 32 bytes B returns
+	contractA := []byte{
+		byte(vm.PUSH1), 0x01,
+		byte(vm.SLOAD),
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.PUSH0), byte(vm.PUSH0),
+		byte(vm.PUSH20), 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22,
+		byte(vm.GAS),
+		byte(vm.CALL),
+		byte(vm.PUSH0),
+		byte(vm.MLOAD),
+		byte(vm.STOP),
+	}
+
+	// Returns its slot 1
+	contractB := []byte{
+		byte(vm.PUSH1), 0x01,
+		byte(vm.SLOAD),
+		byte(vm.PUSH0),
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH0),
+		byte(vm.RETURN),
+	}
+
+	// A diffMode prestateTracer result, the post state is ignored
+	prestate := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":{
+		"pre": {
+			"0x1111111111111111111111111111111111111111": {"balance": "0x0", "nonce": 1, "code": "0x%x", "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x000000000000000000000000000000000000000000000000000000000000002a"
+			}},
+			"0x2222222222222222222222222222222222222222": {"balance": "0x10", "nonce": "0x1", "code": "0x%x", "storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x07"
+			}}
+		},
+		"post": {
+			"0x1111111111111111111111111111111111111111": {"nonce": 2}
+		}
+	}}`, contractA, contractB)
+	path := filepath.Join(t.TempDir(), "prestate.json")
+	assert.NoError(t, os.WriteFile(path, []byte(prestate), 0644))
+
+	stateReader, err := tracer.LoadStateFile(path)
+	assert.NoError(t, err)
+	account, err := stateReader.ReadAccountData(libcommon.HexToAddress("0x2222222222222222222222222222222222222222"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), account.Nonce)
+	assert.Equal(t, uint64(0x10), account.Balance.Uint64())
+
+	testRunner := NewTestRunnerWithConfig(nil, TestConfig{
+		CallData:    []byte{},
+		StateReader: stateReader,
+		Address:     &addrA,
+	})
+	assembly, evmSnapshot, err := testRunner.Execute()
+	assert.NoError(t, err)
+
+	riscvBytecode, err := assembly.ToBytecode()
+	assert.NoError(t, err)
+	execution, err := prover.NewRunner()
+	assert.NoError(t, err)
+	snapshot, err := execution.Execute(riscvBytecode)
+	assert.NoError(t, err)
+
+	snapShot := *snapshot.StackSnapshots
+	assert.Len(t, snapShot, len(evmSnapshot.Snapshots), "Snapshot length should match")
+
+	finalStack := snapShot[len(snapShot)-1]
+	assert.Len(t, finalStack, 3, "Final stack should have 3 elements")
+	assert.Equal(t, uint64(0x2a), finalStack[0].Uint64(), "First element should be the storage of A")
+	assert.Equal(t, uint64(1), finalStack[1].Uint64(), "Second element should be success flag (1)")
+	assert.Equal(t, uint64(0x07), finalStack[2].Uint64(), "Third element should be the storage of B")
+
+	for i := range evmSnapshot.Snapshots {
+		assertStackEqual(t, evmSnapshot.Snapshots[i], snapShot[i], fmt.Sprintf("Stack mismatch at instruction %d", i))
+	}
+}
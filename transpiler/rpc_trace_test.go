@@ -0,0 +1,154 @@
+package transpiler
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"erigon-transpiler-risc-v/tracer"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+)
+
+// toStructLogs renders a StateTracer trace the way a node's struct logger reports it.
+func toStructLogs(instructions []*tracer.EvmInstructionMetadata) []tracer.StructLog {
+	var logs []tracer.StructLog
+	var entered []bool
+	depth := 1
+	returnData := ""
+	for i, instruction := range instructions {
+		if instruction.IsStackRestore {
+			if entered[len(entered)-1] {
+				depth--
+			}
+			entered = entered[:len(entered)-1]
+			returnData = "0x" + hex.EncodeToString(instruction.ReturnData)
+			continue
+		}
+
+		stack := make([]string, len(instruction.StackSnapshot))
+		for j := range instruction.StackSnapshot {
+			stack[j] = instruction.StackSnapshot[j].Hex()
+		}
+		logs = append(logs, tracer.StructLog{
+			Pc:         instruction.Pc,
+			Op:         instruction.Opcode.String(),
+			Gas:        100000,
+			Depth:      depth,
+			Stack:      stack,
+			ReturnData: returnData,
+		})
+
+		switch instruction.Opcode {
+		case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+			enter := i+1 < len(instructions) && !instructions[i+1].IsStackRestore
+			entered = append(entered, enter)
+			if enter {
+				depth++
+			}
+		}
+	}
+	return logs
+}
+
+func TestRPCTracer(t *testing.T) {
+	callerAddr := libcommon.HexToAddress("0xabcd")
+	contractA := libcommon.HexToAddress("0x1000000000000000000000000000000000000000")
+	contractB := libcommon.HexToAddress("0x2000000000000000000000000000000000000000")
+	// PUSH1 42, PUSH1 0, MSTORE, PUSH1 32, PUSH1 0, RETURN
+	codeB := []byte{0x60, 0x2a, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+	// CALL(GAS, B, 0, 0, 0, 0, 0), POP, RETURNDATASIZE, POP, STOP
+	codeA := []byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x73}
+	codeA = append(codeA, contractB.Bytes()...)
+	codeA = append(codeA, 0x5a, 0xf1, 0x50, 0x3d, 0x50, 0x00)
+
+	simpleTracer := tracer.NewSimpleTracer()
+	assert.NoError(t, simpleTracer.DeployContract(contractA, codeA, uint256.NewInt(0)))
+	assert.NoError(t, simpleTracer.DeployContract(contractB, codeB, uint256.NewInt(0)))
+	expected, _, _, err := simpleTracer.ExecuteContract(contractA, []byte{}, 100000, uint256.NewInt(0))
+	assert.NoError(t, err)
+
+	codes := map[libcommon.Address][]byte{contractA: codeA, contractB: codeB}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		var result interface{}
+		switch request.Method {
+		case "eth_getTransactionByHash":
+			result = map[string]interface{}{
+				"from": callerAddr, "to": contractA, "input": "0x", "value": "0x0",
+				"blockNumber": "0x10", "transactionIndex": "0x2",
+			}
+		case "eth_getBlockByNumber":
+			result = map[string]interface{}{"number": "0x10", "timestamp": "0x5", "miner": libcommon.Address{}}
+		case "eth_chainId":
+			result = "0x539"
+		case "eth_getCode":
+			var address libcommon.Address
+			assert.NoError(t, json.Unmarshal(request.Params[0], &address))
+			result = "0x" + hex.EncodeToString(codes[address])
+		case "debug_traceTransaction":
+			result = map[string]interface{}{"gas": 0, "failed": false, "structLogs": toStructLogs(expected)}
+		default:
+			t.Errorf("unexpected method %s", request.Method)
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result}))
+	}))
+	defer server.Close()
+
+	client, err := tracer.DialRPC(context.Background(), server.URL)
+	assert.NoError(t, err)
+	instructions, state, err := tracer.NewRPCTracer(client).TraceTransaction(context.Background(), libcommon.HexToHash("0x01"))
+	assert.NoError(t, err)
+
+	assert.Len(t, instructions, len(expected))
+	for i := range min(len(instructions), len(expected)) {
+		want, got := expected[i], instructions[i]
+		assert.Equal(t, want.Opcode, got.Opcode, "instruction %d", i)
+		assert.Equal(t, want.Arguments, got.Arguments, "instruction %d", i)
+		assert.Equal(t, want.StackSnapshot, got.StackSnapshot, "instruction %d", i)
+		assert.Equal(t, want.Pc, got.Pc, "instruction %d", i)
+		assert.Equal(t, want.Address, got.Address, "instruction %d", i)
+		assert.Equal(t, want.IsStackRestore, got.IsStackRestore, "instruction %d", i)
+		assert.Equal(t, want.Result, got.Result, "instruction %d", i)
+		assert.Equal(t, want.ReturnData, got.ReturnData, "instruction %d", i)
+		assert.Equal(t, 2, got.TxIndex)
+	}
+	assert.Equal(t, contractA, state.Address)
+	assert.Equal(t, callerAddr, state.Caller)
+	assert.Equal(t, codeA, state.CodeData)
+	assert.Equal(t, uint64(1337), state.ChainId.Uint64())
+	assert.Equal(t, uint64(16), state.BlockNumber.Uint64())
+
+	// The rebuilt trace transpiles like the original
+	_, err = NewTestTranspiler().ProcessExecution(instructions, state)
+	assert.NoError(t, err)
+}
+
+func TestRPCTracerContextDeadline(t *testing.T) {
+	// A node that never answers
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, err := tracer.DialRPC(context.Background(), server.URL)
+	assert.NoError(t, err)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err = tracer.NewRPCTracer(client).TraceTransaction(ctx, libcommon.HexToHash("0x01"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,77 @@
+package transpiler
+
+import (
+	"testing"
+
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoundnessReport(t *testing.T) {
+	stack := func(values ...uint64) []uint256.Int {
+		result := make([]uint256.Int, len(values))
+		for i, value := range values {
+			result[i] = *uint256.NewInt(value)
+		}
+		return result
+	}
+	instructions := []*tracer.EvmInstructionMetadata{
+		{Opcode: vm.PUSH1, Arguments: []byte{0x05}, StackSnapshot: stack()},
+		{Opcode: vm.PUSH1, Arguments: []byte{0x03}, StackSnapshot: stack(5)},
+		{Opcode: vm.ADD, StackSnapshot: stack(5, 3)},
+		{Opcode: vm.PUSH1, Arguments: []byte{0x03}, StackSnapshot: stack(8)},
+		{Opcode: vm.MOD, StackSnapshot: stack(8, 3)},
+		{Opcode: vm.JUMPDEST, StackSnapshot: stack(2)},
+		{Opcode: vm.STOP, StackSnapshot: stack(2)},
+	}
+	state := &tracer.EvmExecutionState{CallValue: uint256.NewInt(0)}
+
+	transpiler := NewTestTranspiler()
+	_, err := transpiler.ProcessExecution(instructions, state)
+	assert.NoError(t, err)
+
+	report := transpiler.SoundnessReport()
+	assert.Equal(t, 3, report.Opcodes["PUSH1"][prover.OpcodeComputed])
+	assert.Equal(t, 1, report.Opcodes["ADD"][prover.OpcodeHostOptimized])
+	assert.Equal(t, 1, report.Opcodes["MOD"][prover.OpcodeTraceSupplied])
+	assert.Equal(t, 1, report.Opcodes["JUMPDEST"][prover.OpcodeNoOp])
+	assert.Equal(t, 1, report.Opcodes["STOP"][prover.OpcodeComputed])
+	assert.InDelta(t, 100*5.0/7.0, report.ConstrainedPercentage, 0.001)
+
+	// The default transpiler replaces the host-optimized opcodes with a zero
+	transpiler = NewTranspiler()
+	_, err = transpiler.ProcessExecution(instructions, state)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, transpiler.SoundnessReport().Opcodes["ADD"][prover.OpcodeDummyZero])
+}
+
+func TestSoundnessReportTraceSupplied(t *testing.T) {
+	state := &tracer.EvmExecutionState{
+		CallValue: uint256.NewInt(0),
+		Gas:       uint256.NewInt(21000),
+		CallData:  []byte{0x01, 0x02},
+		CodeData:  []byte{0x00},
+	}
+	transpiler := NewTestTranspiler()
+	assert.NoError(t, transpiler.AddInstruction(&tracer.EvmInstructionMetadata{Opcode: vm.CALLER}, state))
+	assert.NoError(t, transpiler.AddInstruction(&tracer.EvmInstructionMetadata{Opcode: vm.GAS}, state))
+	assert.NoError(t, transpiler.AddInstruction(&tracer.EvmInstructionMetadata{Opcode: vm.CALLDATASIZE}, state))
+	assert.NoError(t, transpiler.AddInstruction(&tracer.EvmInstructionMetadata{Opcode: vm.CODESIZE}, state))
+	assert.NoError(t, transpiler.AddInstruction(&tracer.EvmInstructionMetadata{
+		IsStackRestore: true,
+		Result:         uint256.NewInt(1),
+	}, state))
+
+	report := transpiler.SoundnessReport()
+	assert.Equal(t, 1, report.Opcodes["CALLER"][prover.OpcodeTraceSupplied])
+	assert.Equal(t, 1, report.Opcodes["GAS"][prover.OpcodeTraceSupplied])
+	assert.Equal(t, 1, report.Opcodes["CALLDATASIZE"][prover.OpcodeTraceSupplied])
+	assert.Equal(t, 1, report.Opcodes["CODESIZE"][prover.OpcodeTraceSupplied])
+	assert.Equal(t, 1, report.Opcodes["STACK_RESTORE"][prover.OpcodeTraceSupplied])
+	assert.Equal(t, 5, report.Totals[prover.OpcodeTraceSupplied])
+	assert.Zero(t, report.ConstrainedPercentage)
+}
@@ -0,0 +1,78 @@
+package transpiler
+
+import (
+	"strings"
+	"testing"
+
+	"erigon-transpiler-risc-v/prover"
+	"erigon-transpiler-risc-v/tracer"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceLocations(t *testing.T) {
+	address := libcommon.HexToAddress("0x1000000000000000000000000000000000000000")
+	instructions := []*tracer.EvmInstructionMetadata{
+		{Opcode: vm.PUSH1, Arguments: []byte{0x05}, StackSnapshot: []uint256.Int{}, Pc: 0, Address: address, TxIndex: 2},
+		{Opcode: vm.PUSH1, Arguments: []byte{0x03}, StackSnapshot: []uint256.Int{*uint256.NewInt(5)}, Pc: 2, Address: address, TxIndex: 2},
+		{Opcode: vm.ADD, StackSnapshot: []uint256.Int{*uint256.NewInt(5), *uint256.NewInt(3)}, Pc: 4, Address: address, TxIndex: 2},
+		{Opcode: vm.STOP, StackSnapshot: []uint256.Int{*uint256.NewInt(8)}, Pc: 5, Address: address, TxIndex: 2},
+	}
+	state := &tracer.EvmExecutionState{CallValue: uint256.NewInt(0)}
+
+	transpiler := NewTestTranspiler()
+	_, err := transpiler.ProcessExecution(instructions, state)
+	assert.NoError(t, err)
+
+	// Every RISC-V instruction maps back to the opcode that produced it
+	assembly := transpiler.ToAssembly()
+	for _, mapping := range transpiler.GetDebugMappings() {
+		for i := range mapping.RiscVInstructions {
+			section := assembly.SectionAt(mapping.RiscVStart + i)
+			assert.Equal(t, mapping.EvmOpcode, section.Name)
+			assert.Equal(t, mapping.Source, section.Source)
+		}
+	}
+	add := assembly.SectionAt(transpiler.GetDebugMappings()[2].RiscVStart)
+	assert.Equal(t, prover.SourceLocation{TxIndex: 2, Address: address.Hex(), Pc: 4}, *add.Source)
+	assert.Nil(t, assembly.SectionAt(len(assembly.Instructions)))
+
+	content, err := assembly.ToToolChainCompatibleAssembly()
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(content, "# tx 2 "+address.Hex()+" pc 4: ADD"))
+}
+
+func TestSoliditySourceMap(t *testing.T) {
+	source := "contract A {\n    function f() public {\n        uint x = 5 + 3;\n    }\n}\n"
+	sourceMap, err := prover.ParseSourceMap("0:60:0:-:0;;56:5;:::o;-1:0:1")
+	assert.NoError(t, err)
+	assert.Equal(t, []prover.SourceMapEntry{
+		{Start: 0, Length: 60, File: 0, Jump: "-"},
+		{Start: 0, Length: 60, File: 0, Jump: "-"},
+		{Start: 56, Length: 5, File: 0, Jump: "-"},
+		{Start: 56, Length: 5, File: 0, Jump: "o"},
+		{Start: -1, Length: 0, File: 1, Jump: "o"},
+	}, sourceMap)
+
+	// PUSH1 5, PUSH1 3, ADD, STOP, STOP
+	contract := &prover.SolidityContract{
+		Bytecode:  []byte{0x60, 0x05, 0x60, 0x03, 0x01, 0x00, 0x00},
+		SourceMap: sourceMap,
+		Sources:   map[int]prover.SoliditySource{0: {Name: "A.sol", Content: []byte(source)}},
+	}
+	location, ok := contract.Locate(4)
+	assert.True(t, ok)
+	assert.Equal(t, prover.SolidityLocation{File: "A.sol", Line: 3, Column: 18}, location)
+	// Push data and compiler generated code have no location
+	_, ok = contract.Locate(1)
+	assert.False(t, ok)
+	_, ok = contract.Locate(6)
+	assert.False(t, ok)
+
+	assembly, _, err := NewTestRunnerWithConfig(contract.Bytecode, TestConfig{Contract: contract}).Execute()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[int]int{"A.sol": {1: 2, 3: 2}}, assembly.SolidityCoverage())
+}
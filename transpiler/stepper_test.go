@@ -0,0 +1,29 @@
+package transpiler
+
+import (
+	"io"
+	"testing"
+
+	"erigon-transpiler-risc-v/prover"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepper(t *testing.T) {
+	// PUSH1 5, PUSH1 3, ADD, DUP1, STOP
+	assembly, evmSnapshot, err := NewTestRunner([]byte{0x60, 0x05, 0x60, 0x03, 0x01, 0x80, 0x00}).Execute()
+	assert.NoError(t, err)
+	bytecode, err := assembly.ToBytecode()
+	assert.NoError(t, err)
+
+	stepper, err := prover.NewStepper(bytecode)
+	assert.NoError(t, err)
+	for i, expected := range evmSnapshot.Snapshots {
+		stack, err := stepper.Next()
+		assert.NoError(t, err)
+		assert.Nil(t, FirstStackDivergence([][]uint256.Int{expected}, [][]uint256.Int{stack}), "snapshot %d", i)
+	}
+	_, err = stepper.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -5,6 +5,7 @@ import (
 	"erigon-transpiler-risc-v/tracer"
 
 	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/core/state"
 	"github.com/holiman/uint256"
 )
 
@@ -13,6 +14,14 @@ const CONTRACT_ADDRESS = "0x1234567890123456789012345678901234567890"
 type TestConfig struct {
 	CallValue *uint256.Int
 	CallData  []byte
+	// Optional, adds the Solidity locations to the assembly
+	Contract *prover.SolidityContract
+	// Optional, the pre-state of the accounts the execution reads
+	StateReader state.StateReader
+	// Optional, the address that is called, CONTRACT_ADDRESS by default
+	Address *libcommon.Address
+	// Optional, 100000 by default
+	GasLimit uint64
 }
 
 type TestRunner struct {
@@ -37,7 +46,9 @@ func NewTestRunnerWithConfig(program []byte, config TestConfig) *TestRunner {
 	if config.CallValue == nil {
 		config.CallValue = uint256.NewInt(0)
 	}
-	runner := tracer.NewSimpleTracer()
+	env := tracer.DefaultTracerEnv()
+	env.StateReader = config.StateReader
+	runner := tracer.NewSimpleTracerWithEnv(env)
 
 	return &TestRunner{
 		program: program,
@@ -50,24 +61,38 @@ type EvmStackSnapshot struct {
 	Snapshots [][]uint256.Int
 }
 
+// Execute deploys the program and calls it. Without a program the code of the called address comes from the
+// pre-state.
 func (t *TestRunner) Execute() (*prover.AssemblyFile, *EvmStackSnapshot, error) {
 	contractAddr := libcommon.HexToAddress(CONTRACT_ADDRESS)
+	if t.config.Address != nil {
+		contractAddr = *t.config.Address
+	}
 
-	err := t.runner.DeployContract(contractAddr, t.program, uint256.NewInt(1000))
-	if err != nil {
-		return nil, nil, err
+	if len(t.program) > 0 {
+		err := t.runner.DeployContract(contractAddr, t.program, uint256.NewInt(1000))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	gasLimit := t.config.GasLimit
+	if gasLimit == 0 {
+		gasLimit = 100000
 	}
 
 	callData := t.config.CallData
 	if callData == nil {
 		callData = []byte{}
 	}
-	instructions, executionState, _, err := t.runner.ExecuteContract(contractAddr, callData, 100000, t.config.CallValue)
+	instructions, executionState, _, err := t.runner.ExecuteContract(contractAddr, callData, gasLimit, t.config.CallValue)
 	if err != nil {
 		return nil, nil, err
 	}
 	transpiler := NewTestTranspiler()
 	transpiler.EnableSnapshots()
+	if t.config.Contract != nil {
+		transpiler.AddSoliditySource(contractAddr, t.config.Contract)
+	}
 	snapshot, err := transpiler.ProcessExecution(instructions, executionState)
 	if err != nil {
 		return nil, nil, err
@@ -0,0 +1,92 @@
+package transpiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"erigon-transpiler-risc-v/tracer"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceFileReplay(t *testing.T) {
+	contractAddr := libcommon.HexToAddress(CONTRACT_ADDRESS)
+	simpleTracer := tracer.NewSimpleTracer()
+	// PUSH1 5, PUSH1 3, ADD, PUSH1 0, SSTORE, STOP
+	err := simpleTracer.DeployContract(contractAddr, []byte{0x60, 0x05, 0x60, 0x03, 0x01, 0x60, 0x00, 0x55, 0x00}, uint256.NewInt(1000))
+	assert.NoError(t, err)
+	instructions, state, _, err := simpleTracer.ExecuteContract(contractAddr, []byte{0x01, 0x02}, 100000, uint256.NewInt(7))
+	assert.NoError(t, err)
+	transactions := []tracer.TracedTransaction{{Instructions: instructions, State: state}}
+
+	original := NewTranspiler()
+	_, err = original.ProcessExecution(instructions, state)
+	assert.NoError(t, err)
+	expected, err := original.ToAssembly().ToToolChainCompatibleAssembly()
+	assert.NoError(t, err)
+
+	for _, name := range []string{"trace.bin", "trace.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		assert.NoError(t, tracer.WriteTraceFile(path, transactions))
+		replayed, err := tracer.ReadTraceFile(path)
+		assert.NoError(t, err)
+		assert.Len(t, replayed, 1)
+		assert.Len(t, replayed[0].Instructions, len(instructions))
+
+		// The replay transpiles to the same program without a node
+		replay := NewTranspiler()
+		_, err = replay.ProcessExecution(replayed[0].Instructions, replayed[0].State)
+		assert.NoError(t, err)
+		content, err := replay.ToAssembly().ToToolChainCompatibleAssembly()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, content, name)
+	}
+}
+
+func TestTraceFileCorruptLengths(t *testing.T) {
+	// Header of a binary trace file followed by the gzip compressed uvarints and bytes
+	traceFile := func(parts ...[]byte) string {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		for _, part := range parts {
+			_, err := writer.Write(part)
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, writer.Close())
+
+		header := binary.BigEndian.AppendUint16([]byte("EVMTRACE"), tracer.TraceFileVersion)
+		path := filepath.Join(t.TempDir(), "trace.bin")
+		assert.NoError(t, os.WriteFile(path, append(header, compressed.Bytes()...), 0644))
+		return path
+	}
+	uvarint := func(value uint64) []byte {
+		return binary.AppendUvarint(nil, value)
+	}
+	// One transaction without a state, with one PUSH1 at pc 0 and no arguments
+	instruction := [][]byte{uvarint(1), uvarint(0), uvarint(1), {0x60, 0}, uvarint(0), make([]byte, 20), uvarint(0)}
+
+	// The counts are only trusted as far as the input goes
+	_, err := tracer.ReadTraceFile(traceFile(uvarint(1 << 30)))
+	assert.ErrorContains(t, err, "corrupt trace file")
+	_, err = tracer.ReadTraceFile(traceFile(uvarint(1), uvarint(0), uvarint(1<<30)))
+	assert.ErrorContains(t, err, "corrupt trace file")
+	_, err = tracer.ReadTraceFile(traceFile(append(instruction, uvarint(1<<30))...))
+	assert.ErrorContains(t, err, "corrupt trace file")
+
+	// No EVM stack is deeper than 1024 entries
+	_, err = tracer.ReadTraceFile(traceFile(append(instruction, uvarint(0), uvarint(1025))...))
+	assert.ErrorContains(t, err, "deeper than 1024")
+
+	path := traceFile(append(instruction, uvarint(0), uvarint(1), uvarint(1), []byte{7})...)
+	transactions, err := tracer.ReadTraceFile(path)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 1)
+	assert.Len(t, transactions[0].Instructions, 1)
+	assert.Equal(t, *uint256.NewInt(7), transactions[0].Instructions[0].StackSnapshot[0])
+}
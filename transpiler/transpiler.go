@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"strconv"
 
+	libcommon "github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon/core/vm"
 	"github.com/holiman/uint256"
 )
@@ -30,6 +31,11 @@ type Transpiler struct {
 	currentDepth    int
 	config          TranspilerConfig
 	outputWriter    func([]prover.Instruction) error // Optional streaming output
+	sections        []prover.InstructionSection      // Instructions generated for each opcode, for the cycle count
+	soundness       *prover.SoundnessReport
+	opcodeClass     prover.OpcodeClass // Class of the opcode being transpiled
+	dummyOnStack    bool               // A dummy zero was pushed, the stack no longer matches the EVM one
+	solidity        map[libcommon.Address]*prover.SolidityContract
 }
 
 type EvmToRiscVMapping struct {
@@ -37,6 +43,11 @@ type EvmToRiscVMapping struct {
 	RiscVInstructions []prover.Instruction  `json:"risc_v_instructions"`
 	DataVariables     []prover.DataVariable `json:"data_variables"`
 	CallDepth         int                   `json:"call_depth"`
+	// Stack after the opcode, null when it is unknown (e.g. the last opcode of the trace, or after a dummy zero)
+	EvmStack []uint256.Int          `json:"evm_stack"`
+	Source   *prover.SourceLocation `json:"source"`
+	// Index of the first RISC-V instruction of the opcode in the program
+	RiscVStart int `json:"risc_v_start"`
 }
 
 func NewTestTranspiler() *Transpiler {
@@ -45,7 +56,7 @@ func NewTestTranspiler() *Transpiler {
 
 func NewTranspiler() *Transpiler {
 	return NewTranspilerWithConfig(TranspilerConfig{
-		DisableCallContextSeparation: true,
+		DisableCallContextSeparation: false,
 		DisableHostOptimizedOpcodes:  true,
 		DisableMCopyOperations:       true,
 		DisableDebugMappings:         false,
@@ -62,6 +73,7 @@ func NewTranspilerWithConfig(config TranspilerConfig) *Transpiler {
 		currentDepth:    0,
 		config:          config,
 		outputWriter:    nil,
+		soundness:       prover.NewSoundnessReport(),
 	}
 }
 
@@ -75,6 +87,7 @@ func NewStreamingTranspilerWithConfig(config TranspilerConfig, outputWriter func
 		currentDepth:    0,
 		config:          config,
 		outputWriter:    outputWriter,
+		soundness:       prover.NewSoundnessReport(),
 	}
 }
 
@@ -82,6 +95,14 @@ func (tr *Transpiler) EnableSnapshots() {
 	tr.enableSnapshots = true
 }
 
+// AddSoliditySource adds the Solidity locations of the contract at the address to the source locations.
+func (tr *Transpiler) AddSoliditySource(address libcommon.Address, contract *prover.SolidityContract) {
+	if tr.solidity == nil {
+		tr.solidity = make(map[libcommon.Address]*prover.SolidityContract)
+	}
+	tr.solidity[address] = contract
+}
+
 func (tr *Transpiler) ProcessExecution(instructions []*tracer.EvmInstructionMetadata, executionState *tracer.EvmExecutionState) (EvmStackSnapshot, error) {
 	snapshot := EvmStackSnapshot{
 		Snapshots: make([][]uint256.Int, 0),
@@ -90,12 +111,7 @@ func (tr *Transpiler) ProcessExecution(instructions []*tracer.EvmInstructionMeta
 	for i := range instructions {
 		var resultStack *[]uint256.Int
 		if i+1 < len(instructions) {
-			// TODO: is this correct?
-			if instructions[i].Opcode == vm.CREATE2 || instructions[i].Opcode == vm.CREATE {
-				resultStack = &instructions[i+2].StackSnapshot
-			} else {
-				resultStack = &instructions[i+1].StackSnapshot
-			}
+			resultStack = &instructions[i+1].StackSnapshot
 		}
 
 		err := tr.AddInstructionWithResult(instructions[i], executionState, resultStack)
@@ -116,6 +132,22 @@ func (tr *Transpiler) AddInstruction(op *tracer.EvmInstructionMetadata, state *t
 func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata, state *tracer.EvmExecutionState, resultStack *[]uint256.Int) error {
 	startInstructionCount := len(tr.instructions)
 
+	source := &prover.SourceLocation{
+		TxIndex: op.TxIndex,
+		Address: op.Address.Hex(),
+		Pc:      op.Pc,
+	}
+	if contract, ok := tr.solidity[op.Address]; ok {
+		if location, ok := contract.Locate(op.Pc); ok {
+			source.Solidity = &location
+		}
+	}
+	if op.IsStackRestore {
+		tr.markSection("STACK_RESTORE", source)
+	} else {
+		tr.markSection(op.Opcode.String(), source)
+	}
+
 	if op.IsStackRestore {
 		// TODO: this logic should maybe not be here?
 		// Decrement call depth when returning from a call
@@ -126,8 +158,15 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		if !tr.config.DisableCallContextSeparation {
 			tr.instructions = append(tr.instructions, tr.restoreStackContext()...)
 		}
+		tr.instructions = append(tr.instructions, tr.setReturnData(op.ReturnData)...)
 		if op.Result != nil {
-			tr.instructions = append(tr.instructions, tr.pushOpcode(int32(op.Result.Uint64()))...)
+			if op.Result.IsUint64() && op.Result.Uint64() <= 1 {
+				tr.instructions = append(tr.instructions, tr.pushOpcode(int32(op.Result.Uint64()))...)
+			} else {
+				// Address of a newly created contract
+				varName := tr.dataSection.Add(op.Result)
+				tr.instructions = append(tr.instructions, tr.loadFromDataSection(varName)...)
+			}
 		}
 		tr.instructions = append(tr.instructions, prover.Instruction{
 			Name:     "EBREAK",
@@ -142,13 +181,25 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 				RiscVInstructions: make([]prover.Instruction, len(generatedInstructions)),
 				DataVariables:     dataVars,
 				CallDepth:         tr.currentDepth,
+				EvmStack:          tr.stackAfter(resultStack),
+				Source:            source,
+				RiscVStart:        startInstructionCount,
 			})
 			copy(tr.debugMappings[len(tr.debugMappings)-1].RiscVInstructions, generatedInstructions)
 		}
+		// The call result and return data are copied from the trace
+		tr.soundness.Add("STACK_RESTORE", prover.OpcodeTraceSupplied)
 
 		return nil
 	}
 
+	tr.opcodeClass = prover.OpcodeComputed
+	if noOpOpcodes[op.Opcode] {
+		tr.opcodeClass = prover.OpcodeNoOp
+	} else if traceSuppliedOpcodes[op.Opcode] {
+		tr.opcodeClass = prover.OpcodeTraceSupplied
+	}
+
 	switch op.Opcode {
 	case vm.ADD:
 		tr.instructions = append(tr.instructions, tr.hostOptimizedOpcode(tr.add256Call, 2)...)
@@ -475,17 +526,17 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		}
 		tr.instructions = append(tr.instructions, instructions...)
 	case vm.CREATE:
-		createInstructions, err := tr.resultFromTraceCall(resultStack, 3, "CREATE")
-		if err != nil {
-			return err
-		}
-		tr.instructions = append(tr.instructions, createInstructions...)
+		// The created address is pushed when the init code frame returns
+		tr.instructions = append(tr.instructions, tr.popStack()...)
+		tr.instructions = append(tr.instructions, tr.popStack()...)
+		tr.instructions = append(tr.instructions, tr.popStack()...)
+		tr.instructions = append(tr.instructions, tr.enterCallFrame()...)
 	case vm.CREATE2:
-		create2Instructions, err := tr.resultFromTraceCall(resultStack, 4, "CREATE2")
-		if err != nil {
-			return err
-		}
-		tr.instructions = append(tr.instructions, create2Instructions...)
+		tr.instructions = append(tr.instructions, tr.popStack()...)
+		tr.instructions = append(tr.instructions, tr.popStack()...)
+		tr.instructions = append(tr.instructions, tr.popStack()...)
+		tr.instructions = append(tr.instructions, tr.popStack()...)
+		tr.instructions = append(tr.instructions, tr.enterCallFrame()...)
 	case vm.SELFDESTRUCT:
 		// Pop recipient address (dummy implementation)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
@@ -530,11 +581,7 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		varName := tr.dataSection.Add(size)
 		tr.instructions = append(tr.instructions, tr.loadFromDataSection(varName)...)
 	case vm.RETURNDATASIZE:
-		instructions, err := tr.resultFromTraceCall(resultStack, 0, "RETURNDATASIZE")
-		if err != nil {
-			return err
-		}
-		tr.instructions = append(tr.instructions, instructions...)
+		tr.instructions = append(tr.instructions, tr.returnDataSizeCall()...)
 	case vm.CALLDATALOAD:
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		offset := op.StackSnapshot[0].Uint64()
@@ -568,15 +615,36 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		tr.storageSection.Store(tr.dataSection, key, value)
 	case vm.SLOAD:
 		key := tr.getStorageKey(op.StackSnapshot[0])
+		if !tr.storageSection.Has(key) {
+			tr.opcodeClass = prover.OpcodeDummyZero
+		}
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		varName := tr.storageSection.Load(tr.dataSection, key)
 		tr.instructions = append(tr.instructions, tr.loadFromDataSection(varName)...)
 	case vm.TLOAD:
 		key := tr.getStorageKey(op.StackSnapshot[0])
+		if !tr.storageSection.Has(key) {
+			tr.opcodeClass = prover.OpcodeDummyZero
+		}
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		varName := tr.storageSection.Load(tr.dataSection, key)
 		tr.instructions = append(tr.instructions, tr.loadFromDataSection(varName)...)
 	case vm.STOP:
+		// A callee that stops returns to its caller like RETURN, with the EBREAK of its snapshot
+		if tr.currentDepth > 0 {
+			if !tr.config.DisableCallContextSeparation {
+				tr.instructions = append(tr.instructions, prover.Instruction{
+					Name:     "addi",
+					Operands: []string{"sp", "s3", "0"},
+				})
+			}
+			tr.instructions = append(tr.instructions, prover.Instruction{
+				Name:     "EBREAK",
+				Operands: []string{},
+			})
+			tr.storeDebugInfo(startInstructionCount, op.Opcode, resultStack, source)
+		}
+		tr.soundness.Add(op.Opcode.String(), tr.opcodeClass)
 		return nil
 	case vm.RETURN:
 		tr.instructions = append(tr.instructions, tr.popStack()...)
@@ -596,7 +664,8 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 				Operands: []string{},
 			})
 		}
-		tr.storeDebugInfo(startInstructionCount, op.Opcode)
+		tr.storeDebugInfo(startInstructionCount, op.Opcode, resultStack, source)
+		tr.soundness.Add(op.Opcode.String(), tr.opcodeClass)
 		return nil
 	case vm.REVERT:
 		tr.instructions = append(tr.instructions, tr.popStack()...)
@@ -614,7 +683,8 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 				Operands: []string{},
 			})
 		}
-		tr.storeDebugInfo(startInstructionCount, op.Opcode)
+		tr.storeDebugInfo(startInstructionCount, op.Opcode, resultStack, source)
+		tr.soundness.Add(op.Opcode.String(), tr.opcodeClass)
 		return nil
 	case vm.INVALID:
 		if !tr.config.DisableCallContextSeparation {
@@ -630,7 +700,8 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 				Operands: []string{},
 			})
 		}
-		tr.storeDebugInfo(startInstructionCount, op.Opcode)
+		tr.storeDebugInfo(startInstructionCount, op.Opcode, resultStack, source)
+		tr.soundness.Add(op.Opcode.String(), tr.opcodeClass)
 		return nil
 	case vm.CALLER:
 		callerBytes := state.Caller.Bytes()
@@ -649,7 +720,9 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 
-		if !tr.config.DisableMCopyOperations {
+		if tr.config.DisableMCopyOperations {
+			tr.opcodeClass = prover.OpcodeNoOp
+		} else {
 			// TODO: implement proper mcopy operation
 			destOffset := op.StackSnapshot[2].Uint64()
 			srcOffset := op.StackSnapshot[1].Uint64()
@@ -665,11 +738,7 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 
-		if !tr.config.DisableCallContextSeparation {
-			tr.instructions = append(tr.instructions, tr.saveStackContext()...)
-			tr.instructions = append(tr.instructions, tr.createNewStackFrame()...)
-		}
-		tr.currentDepth++
+		tr.instructions = append(tr.instructions, tr.enterCallFrame()...)
 	case vm.DELEGATECALL:
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
@@ -678,11 +747,7 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 
-		if !tr.config.DisableCallContextSeparation {
-			tr.instructions = append(tr.instructions, tr.saveStackContext()...)
-			tr.instructions = append(tr.instructions, tr.createNewStackFrame()...)
-		}
-		tr.currentDepth++
+		tr.instructions = append(tr.instructions, tr.enterCallFrame()...)
 	case vm.STATICCALL:
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
@@ -691,11 +756,7 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 
-		if !tr.config.DisableCallContextSeparation {
-			tr.instructions = append(tr.instructions, tr.saveStackContext()...)
-			tr.instructions = append(tr.instructions, tr.createNewStackFrame()...)
-		}
-		tr.currentDepth++
+		tr.instructions = append(tr.instructions, tr.enterCallFrame()...)
 	case vm.CALLCODE:
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
@@ -705,11 +766,7 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 		tr.instructions = append(tr.instructions, tr.popStack()...)
 
-		if !tr.config.DisableCallContextSeparation {
-			tr.instructions = append(tr.instructions, tr.saveStackContext()...)
-			tr.instructions = append(tr.instructions, tr.createNewStackFrame()...)
-		}
-		tr.currentDepth++
+		tr.instructions = append(tr.instructions, tr.enterCallFrame()...)
 	default:
 		return fmt.Errorf("unimplemented opcode: 0x%02x", uint64(op.Opcode))
 	}
@@ -719,12 +776,16 @@ func (tr *Transpiler) AddInstructionWithResult(op *tracer.EvmInstructionMetadata
 		Operands: []string{},
 	})
 
-	tr.storeDebugInfo(startInstructionCount, op.Opcode)
+	tr.storeDebugInfo(startInstructionCount, op.Opcode, resultStack, source)
+	tr.soundness.Add(op.Opcode.String(), tr.opcodeClass)
 
 	return nil
 }
 
-func (tr *Transpiler) storeDebugInfo(startInstructionCount int, op vm.OpCode) {
+func (tr *Transpiler) storeDebugInfo(startInstructionCount int, op vm.OpCode, resultStack *[]uint256.Int, source *prover.SourceLocation) {
+	if tr.opcodeClass == prover.OpcodeDummyZero {
+		tr.dummyOnStack = true
+	}
 	// Record the mapping for this EVM opcode (only if debug mappings are enabled)
 	if !tr.config.DisableDebugMappings {
 		generatedInstructions := tr.instructions[startInstructionCount:]
@@ -734,11 +795,26 @@ func (tr *Transpiler) storeDebugInfo(startInstructionCount int, op vm.OpCode) {
 			RiscVInstructions: make([]prover.Instruction, len(generatedInstructions)),
 			DataVariables:     dataVars,
 			CallDepth:         tr.currentDepth,
+			EvmStack:          tr.stackAfter(resultStack),
+			Source:            source,
+			RiscVStart:        startInstructionCount,
 		})
 		copy(tr.debugMappings[len(tr.debugMappings)-1].RiscVInstructions, generatedInstructions)
 	}
 }
 
+// stackAfter is the EVM stack the RISC-V one has to match after the opcode, nil once a dummy zero could be on it.
+func (tr *Transpiler) stackAfter(resultStack *[]uint256.Int) []uint256.Int {
+	if resultStack == nil || tr.dummyOnStack {
+		return nil
+	}
+	// Keep an empty stack distinguishable from an unknown one in the JSON
+	if *resultStack == nil {
+		return []uint256.Int{}
+	}
+	return *resultStack
+}
+
 func (tr *Transpiler) pushOpcode(value int32) []prover.Instruction {
 	return []prover.Instruction{
 		{
@@ -1070,17 +1146,70 @@ func (tr *Transpiler) saveStackContext() []prover.Instruction {
 	}
 }
 
+func (tr *Transpiler) enterCallFrame() []prover.Instruction {
+	tr.currentDepth++
+
+	var instructions []prover.Instruction
+	if !tr.config.DisableCallContextSeparation {
+		instructions = append(instructions, tr.saveStackContext()...)
+		instructions = append(instructions, tr.createNewStackFrame()...)
+	}
+	// The return data buffer is empty when entering a new frame
+	instructions = append(instructions, prover.Instruction{
+		Name:     "li",
+		Operands: []string{"s4", "0"},
+	})
+	return instructions
+}
+
+// createNewStackFrame starts the callee stack right below the caller's, the stack grows down so the caller's
+// entries above s3 are never touched.
 func (tr *Transpiler) createNewStackFrame() []prover.Instruction {
 	return []prover.Instruction{
 		{
 			Name:     "addi",
-			Operands: []string{"sp", "sp", "-1024"},
+			Operands: []string{"s3", "sp", "0"},
+		},
+	}
+}
+
+// setReturnData makes the return data of the exited frame available to the caller,
+// s4 holds the size and s5 points to the data.
+func (tr *Transpiler) setReturnData(returnData []byte) []prover.Instruction {
+	instructions := []prover.Instruction{
+		{
+			Name:     "li",
+			Operands: []string{"s4", strconv.Itoa(len(returnData))},
 		},
+	}
+	if len(returnData) > 0 {
+		varName := tr.dataSection.AddBytes(returnData)
+		instructions = append(instructions, prover.Instruction{
+			Name:     "la",
+			Operands: []string{"s5", varName},
+		})
+	}
+	return instructions
+}
+
+func (tr *Transpiler) returnDataSizeCall() []prover.Instruction {
+	instructions := []prover.Instruction{
 		{
 			Name:     "addi",
-			Operands: []string{"s3", "sp", "0"},
+			Operands: []string{"sp", "sp", "-32"},
 		},
+		{
+			Name:     "sw",
+			Operands: []string{"s4", "0(sp)"},
+		},
+	}
+	for i := 1; i < 8; i++ {
+		instructions = append(instructions, prover.Instruction{
+			Name:     "sw",
+			Operands: []string{"zero", fmt.Sprintf("%d(sp)", i*4)},
+		})
 	}
+	return instructions
 }
 
 func (tr *Transpiler) restoreStackContext() []prover.Instruction {
@@ -1107,6 +1236,7 @@ func (tr *Transpiler) restoreStackContext() []prover.Instruction {
 }
 
 func (tr *Transpiler) AddTransactionBoundary() {
+	tr.markSection("TRANSACTION_BOUNDARY", nil)
 	tr.instructions = append(tr.instructions, prover.Instruction{
 		Name:     "mv",
 		Operands: []string{"sp", "s2"},
@@ -1115,6 +1245,10 @@ func (tr *Transpiler) AddTransactionBoundary() {
 		Name:     "mv",
 		Operands: []string{"s3", "s2"},
 	})
+	tr.instructions = append(tr.instructions, prover.Instruction{
+		Name:     "li",
+		Operands: []string{"s4", "0"},
+	})
 	tr.instructions = append(tr.instructions, prover.Instruction{
 		Name:     "EBREAK",
 		Operands: []string{},
@@ -1126,6 +1260,7 @@ func (tr *Transpiler) resetStateForNextTransaction() {
 	tr.currentDepth = 0
 	tr.storageSection = NewStorageSection() // Reset storage between transactions
 	tr.debugMappings = make([]EvmToRiscVMapping, 0)
+	tr.dummyOnStack = false
 	// Note: We keep dataSection and instructions as they accumulate across transactions in a block
 }
 
@@ -1133,6 +1268,22 @@ func (tr *Transpiler) resetStateForNextTransaction() {
 func (tr *Transpiler) ClearInstructionsAndDebugMappings() {
 	tr.instructions = make([]prover.Instruction, 0)
 	tr.debugMappings = make([]EvmToRiscVMapping, 0)
+	tr.sections = nil
+}
+
+// markSection starts a new section at the next instruction
+func (tr *Transpiler) markSection(name string, source *prover.SourceLocation) {
+	start := len(tr.instructions)
+	if last := len(tr.sections) - 1; last >= 0 && tr.sections[last].Start == start {
+		tr.sections[last].Name = name
+		tr.sections[last].Source = source
+		return
+	}
+	tr.sections = append(tr.sections, prover.InstructionSection{
+		Name:   name,
+		Start:  start,
+		Source: source,
+	})
 }
 
 func (tr *Transpiler) getStorageKey(arguments uint256.Int) string {
@@ -1184,10 +1335,12 @@ func (tr *Transpiler) ToAssembly() *prover.AssemblyFile {
 			Value: dataVar.Value,
 		})
 	}
+	dataSection = append(dataSection, tr.dataSection.Blobs()...)
 
 	return &prover.AssemblyFile{
 		Instructions: tr.instructions,
 		DataSection:  dataSection,
+		Sections:     tr.sections,
 	}
 }
 
@@ -1211,11 +1364,55 @@ func (tr *Transpiler) getDataSectionSnapshot() []prover.DataVariable {
 			Value: dataVar.Value,
 		})
 	}
+	dataVars = append(dataVars, tr.dataSection.Blobs()...)
 	return dataVars
 }
 
+// Opcodes that only pop their arguments, their side effects are not part of the proof
+var noOpOpcodes = map[vm.OpCode]bool{
+	vm.JUMP:           true,
+	vm.JUMPI:          true,
+	vm.JUMPDEST:       true,
+	vm.MSTORE:         true,
+	vm.MSTORE8:        true,
+	vm.SSTORE:         true,
+	vm.TSTORE:         true,
+	vm.CALLDATACOPY:   true,
+	vm.CODECOPY:       true,
+	vm.RETURNDATACOPY: true,
+	vm.EXTCODECOPY:    true,
+	vm.SELFDESTRUCT:   true,
+	vm.LOG0:           true,
+	vm.LOG1:           true,
+	vm.LOG2:           true,
+	vm.LOG3:           true,
+	vm.LOG4:           true,
+}
+
+// Opcodes pushing a value taken from the execution state of the trace, not computed by the transpiled code
+var traceSuppliedOpcodes = map[vm.OpCode]bool{
+	vm.CALLVALUE:    true,
+	vm.GAS:          true,
+	vm.CALLER:       true,
+	vm.ORIGIN:       true,
+	vm.ADDRESS:      true,
+	vm.TIMESTAMP:    true,
+	vm.CHAINID:      true,
+	vm.COINBASE:     true,
+	vm.NUMBER:       true,
+	vm.CALLDATALOAD: true,
+	vm.CALLDATASIZE: true,
+	vm.CODESIZE:     true,
+}
+
+// SoundnessReport classifies every transpiled opcode, across all transactions.
+func (tr *Transpiler) SoundnessReport() *prover.SoundnessReport {
+	return tr.soundness
+}
+
 func (tr *Transpiler) hostOptimizedOpcode(originalFunc func() []prover.Instruction, numStackArgs int) []prover.Instruction {
 	if tr.config.DisableHostOptimizedOpcodes {
+		tr.opcodeClass = prover.OpcodeDummyZero
 		var instructions []prover.Instruction
 		for i := 0; i < numStackArgs; i++ {
 			instructions = append(instructions, tr.popStack()...)
@@ -1224,6 +1421,7 @@ func (tr *Transpiler) hostOptimizedOpcode(originalFunc func() []prover.Instructi
 		instructions = append(instructions, tr.pushOpcode(0)...)
 		return instructions
 	}
+	tr.opcodeClass = prover.OpcodeHostOptimized
 	return originalFunc()
 }
 
@@ -1243,6 +1441,7 @@ func (tr *Transpiler) resultFromTraceCall(resultStack *[]uint256.Int, numArgs in
 		return nil, fmt.Errorf("%s requires result stack but it's empty", opName)
 	}
 	result := a[len(a)-1]
+	tr.opcodeClass = prover.OpcodeTraceSupplied
 	varName := tr.dataSection.Add(&result)
 	instructions = append(instructions, tr.loadFromDataSection(varName)...)
 
@@ -1253,6 +1452,8 @@ type DataSection struct {
 	values []*uint256.Int
 	// Map value hex to variable name for deduplication
 	valueToVar map[string]string
+	blobs      [][]byte
+	blobToVar  map[string]string
 }
 
 type StorageSection struct {
@@ -1263,6 +1464,8 @@ func NewDataSection() *DataSection {
 	return &DataSection{
 		values:     make([]*uint256.Int, 0),
 		valueToVar: make(map[string]string),
+		blobs:      make([][]byte, 0),
+		blobToVar:  make(map[string]string),
 	}
 }
 
@@ -1279,6 +1482,11 @@ func (ss *StorageSection) Store(dataSection *DataSection, key string, value *uin
 	return varName
 }
 
+func (ss *StorageSection) Has(key string) bool {
+	_, exists := ss.keyToVar[key]
+	return exists
+}
+
 func (ss *StorageSection) Load(dataSection *DataSection, key string) string {
 	if varName, exists := ss.keyToVar[key]; exists {
 		return varName
@@ -1303,6 +1511,29 @@ func (ds *DataSection) Add(value *uint256.Int) string {
 	return varName
 }
 
+func (ds *DataSection) AddBytes(data []byte) string {
+	key := string(data)
+	if varName, exists := ds.blobToVar[key]; exists {
+		return varName
+	}
+
+	ds.blobs = append(ds.blobs, data)
+	varName := fmt.Sprintf("data_blob_%d", len(ds.blobs)-1)
+	ds.blobToVar[key] = varName
+	return varName
+}
+
+func (ds *DataSection) Blobs() []prover.DataVariable {
+	result := make([]prover.DataVariable, len(ds.blobs))
+	for i, blob := range ds.blobs {
+		result[i] = prover.DataVariable{
+			Name:  fmt.Sprintf("data_blob_%d", i),
+			Bytes: blob,
+		}
+	}
+	return result
+}
+
 func (ds *DataSection) Iter() []struct {
 	Name  string
 	Value *uint256.Int
@@ -55,7 +55,7 @@ func TestBigIntConverter(t *testing.T) {
 	bytecode, err := file.ToBytecode()
 	assert.NoError(t, err)
 
-	VmRunner, err := prover.NewUnicornRunner()
+	VmRunner, err := prover.NewRunner()
 	assert.NoError(t, err)
 
 	snapshot, err := VmRunner.Execute(bytecode)
@@ -0,0 +1,198 @@
+package transpiler
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"erigon-transpiler-risc-v/tracer"
+
+	"github.com/erigontech/erigon-lib/chain"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/rlp"
+	"github.com/erigontech/erigon-lib/types"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/params"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+)
+
+// compactLeafPath is the hex-prefix encoding of a full 64 nibble leaf path.
+func compactLeafPath(key []byte) []byte {
+	return append([]byte{0x20}, key...)
+}
+
+// witnessHeader is a header with the given number and state root, every fork field set.
+func witnessHeader(number uint64, stateRoot []byte) *types.Header {
+	var zero uint64
+	return &types.Header{
+		Number:                new(big.Int).SetUint64(number),
+		Root:                  libcommon.BytesToHash(stateRoot),
+		Difficulty:            new(big.Int),
+		GasLimit:              30_000_000,
+		Time:                  1_700_000_000 + number*12,
+		BaseFee:               big.NewInt(params.GWei),
+		WithdrawalsHash:       &libcommon.Hash{},
+		BlobGasUsed:           &zero,
+		ExcessBlobGas:         &zero,
+		ParentBeaconBlockRoot: &libcommon.Hash{},
+		RequestsHash:          &libcommon.Hash{},
+	}
+}
+
+// writeWitness writes a debug_executionWitness response with the RLP encoded headers.
+func writeWitness(t *testing.T, nodes [][]byte, codes [][]byte, headers ...*types.Header) string {
+	hexes := func(blobs [][]byte) []string {
+		encoded := make([]string, 0, len(blobs))
+		for _, blob := range blobs {
+			encoded = append(encoded, "0x"+hex.EncodeToString(blob))
+		}
+		return encoded
+	}
+	var encodedHeaders [][]byte
+	for _, header := range headers {
+		encoded, err := rlp.EncodeToBytes(header)
+		assert.NoError(t, err)
+		encodedHeaders = append(encodedHeaders, encoded)
+	}
+
+	witness := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"result": map[string][]string{
+			"state":   hexes(nodes),
+			"codes":   hexes(codes),
+			"headers": hexes(encodedHeaders),
+		},
+	}
+	data, err := json.Marshal(witness)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "witness.json")
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestWitnessStateReader(t *testing.T) {
+	address := libcommon.HexToAddress("0x1000000000000000000000000000000000000000")
+	slot := libcommon.BytesToHash([]byte{1})
+	code := []byte{0x60, 0x01, 0x00}
+
+	// Tries with a single leaf each
+	encode := func(value interface{}) []byte {
+		encoded, err := rlp.EncodeToBytes(value)
+		assert.NoError(t, err)
+		return encoded
+	}
+	storageLeaf := encode([]interface{}{compactLeafPath(crypto.Keccak256(slot[:])), encode([]byte{0x2a})})
+	account := encode([]interface{}{uint64(5), uint64(1000), crypto.Keccak256(storageLeaf), crypto.Keccak256(code)})
+	stateLeaf := encode([]interface{}{compactLeafPath(crypto.Keccak256(address[:])), account})
+	stateRoot := crypto.Keccak256(stateLeaf)
+	path := writeWitness(t, [][]byte{storageLeaf, stateLeaf}, [][]byte{code}, witnessHeader(99, stateRoot))
+
+	loaded, err := tracer.LoadExecutionWitness(path)
+	assert.NoError(t, err)
+	parent, ok := loaded.Header(99)
+	assert.True(t, ok)
+	assert.Equal(t, libcommon.BytesToHash(stateRoot), parent.Root)
+
+	reader := tracer.NewWitnessStateReader(loaded, parent.Root)
+	accountData, err := reader.ReadAccountData(address)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), accountData.Nonce)
+	assert.Equal(t, uint64(1000), accountData.Balance.Uint64())
+	readCode, err := reader.ReadAccountCode(address)
+	assert.NoError(t, err)
+	assert.Equal(t, code, readCode)
+	value, found, err := reader.ReadAccountStorage(address, slot)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, *uint256.NewInt(42), value)
+
+	// Keys on another path are proven absent by the leaf
+	missing, err := reader.ReadAccountData(libcommon.HexToAddress("0x2000000000000000000000000000000000000000"))
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+	_, found, err = reader.ReadAccountStorage(address, libcommon.BytesToHash([]byte{2}))
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// A node the execution needs but the witness lacks is an error
+	delete(loaded.Nodes, libcommon.BytesToHash(crypto.Keccak256(storageLeaf)))
+	reader = tracer.NewWitnessStateReader(loaded, parent.Root)
+	_, _, err = reader.ReadAccountStorage(address, slot)
+	assert.ErrorContains(t, err, "witness has no trie node")
+}
+
+func TestTraceWitnessBlock(t *testing.T) {
+	chainConfig := chain.AllProtocolChanges
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	// The sender is the only account, every other one is proven absent by its leaf
+	encode := func(value interface{}) []byte {
+		encoded, err := rlp.EncodeToBytes(value)
+		assert.NoError(t, err)
+		return encoded
+	}
+	emptyRoot := libcommon.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	account := encode([]interface{}{uint64(0), uint64(params.Ether), emptyRoot[:], crypto.Keccak256(nil)})
+	stateLeaf := encode([]interface{}{compactLeafPath(crypto.Keccak256(sender[:])), account})
+	parent := witnessHeader(99, crypto.Keccak256(stateLeaf))
+	witnessPath := writeWitness(t, [][]byte{stateLeaf}, nil, parent)
+
+	// PUSH1 1, PUSH1 2, ADD, STOP
+	initCode := []byte{0x60, 0x01, 0x60, 0x02, 0x01, 0x00}
+	txn, err := types.SignTx(
+		types.NewContractCreation(0, uint256.NewInt(0), 100_000, uint256.NewInt(2*params.GWei), initCode),
+		*types.LatestSignerForChainID(chainConfig.ChainID), key)
+	assert.NoError(t, err)
+
+	header := witnessHeader(100, nil)
+	header.ParentHash = parent.Hash()
+	encodedHeader, err := json.Marshal(header)
+	assert.NoError(t, err)
+	var block map[string]interface{}
+	assert.NoError(t, json.Unmarshal(encodedHeader, &block))
+	block["transactions"] = []types.Transaction{txn}
+	data, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": block})
+	assert.NoError(t, err)
+	blockPath := filepath.Join(t.TempDir(), "block.json")
+	assert.NoError(t, os.WriteFile(blockPath, data, 0644))
+
+	loadedBlock, err := tracer.LoadWitnessBlock(blockPath)
+	assert.NoError(t, err)
+	assert.Equal(t, txn.Hash(), loadedBlock.Transactions[0].Hash())
+	witness, err := tracer.LoadExecutionWitness(witnessPath)
+	assert.NoError(t, err)
+
+	traced, err := tracer.TraceWitnessBlock(loadedBlock, witness, chainConfig, 0)
+	assert.NoError(t, err)
+	assert.Len(t, traced, 1)
+	var opcodes []vm.OpCode
+	for _, instruction := range traced[0].Instructions {
+		opcodes = append(opcodes, instruction.Opcode)
+	}
+	assert.Equal(t, []vm.OpCode{vm.PUSH1, vm.PUSH1, vm.ADD, vm.STOP}, opcodes)
+	assert.Equal(t, sender, traced[0].State.Origin)
+	assert.Equal(t, uint64(100), traced[0].State.BlockNumber.Uint64())
+
+	// The witness has to be of the parent the block was built on
+	otherHeader := *loadedBlock.Header
+	otherHeader.ParentHash = libcommon.HexToHash("0x01")
+	otherBlock := &tracer.WitnessBlock{Header: &otherHeader, Transactions: loadedBlock.Transactions}
+	_, err = tracer.TraceWitnessBlock(otherBlock, witness, chainConfig, 0)
+	assert.ErrorContains(t, err, "is not the parent")
+
+	// A state the witness lacks is an error, not an empty account
+	delete(witness.Nodes, libcommon.BytesToHash(crypto.Keccak256(stateLeaf)))
+	_, err = tracer.TraceWitnessBlock(loadedBlock, witness, chainConfig, 0)
+	assert.ErrorContains(t, err, "witness has no trie node")
+
+	_, err = tracer.ChainConfig(12345)
+	assert.ErrorContains(t, err, "unsupported chain id 12345")
+}